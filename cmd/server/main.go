@@ -2,60 +2,333 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Mehrbod2002/lcp/internal/adapter/eventbus"
 	"github.com/Mehrbod2002/lcp/internal/adapter/graphql"
+	"github.com/Mehrbod2002/lcp/internal/adapter/htpasswd"
+	"github.com/Mehrbod2002/lcp/internal/adapter/notify"
 	"github.com/Mehrbod2002/lcp/internal/adapter/repository/lcp"
+	"github.com/Mehrbod2002/lcp/internal/adapter/rest"
 	"github.com/Mehrbod2002/lcp/internal/config"
+	domain "github.com/Mehrbod2002/lcp/internal/domain/lcp"
 	lcpencrypt "github.com/Mehrbod2002/lcp/internal/lcp/encrypt"
 	lcplicense "github.com/Mehrbod2002/lcp/internal/lcp/license"
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
 	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/license"
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/outbox"
 	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/publication"
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/status"
 )
 
-// @title LCP License Server API
-// @version 1.0
-// @description API for managing LCP licenses and publications
-// @host localhost:8080
-// @BasePath /
-// @securityDefinitions.apikey BearerAuth
-// @in header
-// @name Authorization
+// shutdownTimeout bounds how long in-flight requests may take to complete
+// once the server is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		panic(err)
 	}
 
+	// Without SERVER_ROLES every role is served by one listener on
+	// SERVER_PORT. Otherwise each selected role gets its own listener, and
+	// links point at the server of the role serving them.
+	roles := cfg.Server.Roles
+	serves := func(role string) bool {
+		return len(roles) == 0 || slices.Contains(roles, role)
+	}
+	publicBaseURL := buildBaseURL(cfg)
+	licenseBaseURL, statusBaseURL, frontendBaseURL := publicBaseURL, publicBaseURL, publicBaseURL
+	if len(roles) > 0 {
+		licenseBaseURL = cfg.LCP.Server.BaseURL()
+		statusBaseURL = cfg.LSD.Server.BaseURL()
+		frontendBaseURL = cfg.Frontend.Server.BaseURL()
+	}
+
 	lcpEnc := lcpencrypt.NewFileCopyEncrypter(cfg.LCP.Storage.FS.Directory)
 	lcpSrv := lcplicense.NewService()
-	pubRepo := lcp.NewPublicationRepository()
-	licRepo := lcp.NewLicenseRepository()
-	pubUsecase := publication.NewPublicationUsecase(pubRepo, lcpEnc)
-	publicBaseURL := buildBaseURL(cfg)
-	licUsecase := license.NewLicenseUsecase(licRepo, lcpSrv, publicBaseURL)
+	store, err := lcp.OpenStore(lcp.StoreOptions{
+		Directory:        cfg.Memory.Directory,
+		SnapshotInterval: cfg.Memory.SnapshotInterval,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer store.Close()
 
-	mux := http.NewServeMux()
+	// The lcp and frontend roles share the catalog, so they share the store.
+	// The lsd role may keep its own when started by SERVER_ROLES, as the
+	// status server of the Readium architecture keeps its own database.
+	statusStore := store
+	if dir := cfg.LSD.StoreDirectory; dir != "" && dir != cfg.Memory.Directory && serves(config.RoleLSD) {
+		if len(roles) == 0 {
+			panic(errors.New("LSD_MEMORY_STORE_DIR needs SERVER_ROLES"))
+		}
+		statusStore, err = lcp.OpenStore(lcp.StoreOptions{
+			Directory:        dir,
+			SnapshotInterval: cfg.Memory.SnapshotInterval,
+		})
+		if err != nil {
+			panic(err)
+		}
+		defer statusStore.Close()
+	}
+
+	events := eventbus.NewMemoryBus()
+	pubUsecase := publication.NewPublicationUsecase(store.Publications, store.Licenses, store.LicenseEvents, store, lcpEnc, events)
+
+	// The license and status services notify each other in process, unless
+	// the other one is configured to run as a separate server. A URL pointing
+	// at a role of this process would only loop back into it.
+	if cfg.Notify.LSD.URL != "" && serves(config.RoleLSD) {
+		panic(errors.New("LSD_NOTIFY_URL must be empty when this process serves the lsd role"))
+	}
+	if cfg.Notify.LCP.URL != "" && serves(config.RoleLCP) {
+		panic(errors.New("LCP_UPDATE_URL must be empty when this process serves the lcp role"))
+	}
+	// Without the URL of a role served elsewhere, its notifications would be
+	// delivered in process to a service nobody reaches.
+	issuesLicenses := serves(config.RoleLCP) || serves(config.RoleFrontend)
+	if cfg.Notify.LSD.URL == "" && issuesLicenses && !serves(config.RoleLSD) {
+		panic(errors.New("LSD_NOTIFY_URL is required when this process issues licenses without serving the lsd role"))
+	}
+	if cfg.Notify.LCP.URL == "" && serves(config.RoleLSD) && !serves(config.RoleLCP) {
+		panic(errors.New("LCP_UPDATE_URL is required when this process serves the lsd role without the lcp role"))
+	}
+	lsdNotifyAuth := notify.Credentials{Username: cfg.Notify.LSD.Username, Password: cfg.Notify.LSD.Password}
+	lcpUpdateAuth := notify.Credentials{Username: cfg.Notify.LCP.Username, Password: cfg.Notify.LCP.Password}
+	// Separate processes notify each other in both directions, and endpoints
+	// without credentials turn every notification away.
+	if cfg.Notify.LSD.URL != "" || cfg.Notify.LCP.URL != "" {
+		if lsdNotifyAuth.Username == "" || lsdNotifyAuth.Password == "" {
+			panic(errors.New("LSD_NOTIFY_USERNAME and LSD_NOTIFY_PASSWORD are required when LSD_NOTIFY_URL or LCP_UPDATE_URL is set"))
+		}
+		if lcpUpdateAuth.Username == "" || lcpUpdateAuth.Password == "" {
+			panic(errors.New("LCP_UPDATE_USERNAME and LCP_UPDATE_PASSWORD are required when LSD_NOTIFY_URL or LCP_UPDATE_URL is set"))
+		}
+	}
+	notifyClient := &http.Client{Timeout: cfg.Notify.Timeout}
+	inProcess := notify.NewInProcess()
+	var statusNotifier domain.StatusNotifier = inProcess
+	if cfg.Notify.LSD.URL != "" {
+		statusNotifier = notify.NewHTTPStatusNotifier(cfg.Notify.LSD.URL, lsdNotifyAuth, notifyClient)
+	}
+	var licenseUpdater domain.LicenseUpdater = inProcess
+	if cfg.Notify.LCP.URL != "" {
+		licenseUpdater = notify.NewHTTPLicenseUpdater(cfg.Notify.LCP.URL, lcpUpdateAuth, notifyClient)
+	}
 
-	gqlHandler := graphql.NewHandler(&graphql.Resolver{
-		PublicationUsecase: pubUsecase,
-		LicenseUsecase:     licUsecase,
-		PublicBaseURL:      publicBaseURL,
+	// Notifications are queued with the change they report and delivered
+	// once it commits. Each store has its own outbox.
+	outboxOptions := outbox.Options{
+		RetryInterval:    cfg.Notify.RetryInterval,
+		MaxRetryInterval: cfg.Notify.MaxRetryInterval,
+	}
+	notifications := outbox.NewDispatcher(store.Notifications, store.Licenses, statusNotifier, licenseUpdater, outboxOptions)
+	statusNotifications := notifications
+	if statusStore != store {
+		statusNotifications = outbox.NewDispatcher(statusStore.Notifications, statusStore.Licenses, statusNotifier, licenseUpdater, outboxOptions)
+	}
+	licUsecase := license.NewLicenseUsecase(store.Licenses, notifications, store.Publications, store, lcpSrv, events, licenseBaseURL, domain.RenewalPolicy{
+		MaxRenewals:      cfg.LSD.Renewal.MaxRenewals,
+		MaxLoanLength:    cfg.LSD.Renewal.MaxLoanLength,
+		DefaultExtension: cfg.LSD.Renewal.DefaultExtension,
 	})
-	mux.Handle("/graphql", gqlHandler)
-	mux.Handle("/publications/", publicationDownloadHandler(pubUsecase))
+	statusUsecase := status.NewStatusUsecase(statusStore.Licenses, statusStore.LicenseEvents, statusStore, events, statusNotifications, status.Options{
+		MaxDevices: cfg.LSD.MaxDevices,
+	})
+	inProcess.Bind(statusUsecase, licUsecase)
+	notifications.Start()
+	defer notifications.Stop()
+	if statusNotifications != notifications {
+		statusNotifications.Start()
+		defer statusNotifications.Stop()
+	}
 
-	port := cfg.Server.Port
-	if port == "" {
-		port = ":8080"
+	// Licenses are expired by the status server.
+	var expiry *status.Sweeper
+	if serves(config.RoleLSD) {
+		expiry = status.NewSweeper(statusUsecase, status.SweeperOptions{
+			Interval:  cfg.LSD.ExpiryInterval,
+			BatchSize: cfg.LSD.ExpiryBatchSize,
+		})
+		expiry.Start()
+		defer expiry.Stop()
 	}
 
-	if err := http.ListenAndServe(port, mux); err != nil {
+	var gqlHandler http.Handler
+	if serves(config.RoleFrontend) {
+		gqlOptions := graphql.Options{
+			PersistedQueries: graphql.PersistedQueryOptions{CacheSize: cfg.GraphQL.PersistedQueryCacheSize},
+			MaxBatchSize:     cfg.GraphQL.MaxBatchSize,
+			Limits: gql.Limits{
+				MaxDepth: cfg.GraphQL.MaxDepth,
+				MaxCost:  cfg.GraphQL.MaxCost,
+				ListSize: cfg.GraphQL.DefaultListSize,
+			},
+			Timeout:        cfg.GraphQL.Timeout,
+			Explorer:       cfg.GraphQL.Explorer,
+			AllowedOrigins: cfg.GraphQL.AllowedOrigins,
+			MaxBodySize:    int64(cfg.GraphQL.MaxBodySize),
+		}
+		if cfg.GraphQL.PersistedQueriesFile != "" {
+			if gqlOptions.PersistedQueries.AllowList, err = graphql.LoadPersistedQueryManifest(cfg.GraphQL.PersistedQueriesFile); err != nil {
+				panic(err)
+			}
+		}
+		gqlHandler, err = graphql.NewHandler(&graphql.Resolver{
+			PublicationUsecase: pubUsecase,
+			LicenseUsecase:     licUsecase,
+			Events:             events,
+			PublicBaseURL:      frontendBaseURL,
+			LicenseBaseURL:     licenseBaseURL,
+		}, gqlOptions)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	services := rest.Services{
+		Publications:  pubUsecase,
+		Licenses:      licUsecase,
+		Statuses:      statusUsecase,
+		Expiry:        expiry,
+		Notifications: notifications,
+	}
+	restOptions := rest.Options{
+		PublicBaseURL:     publicBaseURL,
+		LicenseBaseURL:    licenseBaseURL,
+		StatusBaseURL:     statusBaseURL,
+		StatusNotifyAuth:  lsdNotifyAuth,
+		LicenseUpdateAuth: lcpUpdateAuth,
+	}
+
+	if len(roles) == 0 {
+		restHandler, err := rest.NewHandler(services, restOptions)
+		if err != nil {
+			panic(err)
+		}
+		auth, err := managementAuth(cfg.Server.AuthFile, "SERVER_AUTH_FILE")
+		if err != nil {
+			panic(err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/graphql", auth(gqlHandler))
+		restHandler.Register(mux, auth)
+
+		port := cfg.Server.Port
+		if port == "" {
+			port = ":8080"
+		}
+		if err := serve([]*http.Server{{Addr: port, Handler: mux}}); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	var servers []*http.Server
+	for _, role := range roles {
+		server := roleServer(cfg, role)
+		roleServices := services
+		if role != config.RoleLSD {
+			roleServices.Expiry = nil
+		} else {
+			roleServices.Notifications = statusNotifications
+		}
+		roleOptions := restOptions
+		roleOptions.Roles = []rest.Role{rest.Role(role)}
+		roleOptions.PublicBaseURL = server.BaseURL()
+		restHandler, err := rest.NewHandler(roleServices, roleOptions)
+		if err != nil {
+			panic(err)
+		}
+
+		// The auth file of the role protects its management routes, and the
+		// GraphQL endpoint of the frontend.
+		mux := http.NewServeMux()
+		if restHandler.Protected() || role == config.RoleFrontend {
+			auth, err := managementAuth(server.AuthFile, strings.ToUpper(role)+"_AUTH_FILE")
+			if err != nil {
+				panic(err)
+			}
+			if role == config.RoleFrontend {
+				mux.Handle("/graphql", auth(gqlHandler))
+			}
+			restHandler.Register(mux, auth)
+		} else {
+			restHandler.Register(mux)
+		}
+
+		servers = append(servers, &http.Server{Addr: server.Address(), Handler: mux})
+	}
+	if err := serve(servers); err != nil {
 		panic(err)
 	}
 }
 
+// serve runs servers until one of them fails or the process receives SIGINT
+// or SIGTERM, then shuts them all down so that the deferred snapshot of the
+// store and the stop of the sweeper run before exiting.
+func serve(servers []*http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}(server)
+	}
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		err = errors.Join(err, server.Shutdown(shutdownCtx))
+	}
+	return err
+}
+
+// managementAuth returns basic auth middleware admitting the users of the
+// htpasswd file at authFile. Without a file the management routes would be
+// open to anyone, so variable, the setting naming it, is reported missing.
+func managementAuth(authFile, variable string) (func(http.Handler) http.Handler, error) {
+	if authFile == "" {
+		return nil, fmt.Errorf("%s must name an htpasswd file protecting the management routes", variable)
+	}
+	users, err := htpasswd.Load(authFile)
+	if err != nil {
+		return nil, err
+	}
+	return rest.BasicAuth(users), nil
+}
+
+func roleServer(cfg *config.Config, role string) config.RoleServer {
+	switch role {
+	case config.RoleLCP:
+		return cfg.LCP.Server
+	case config.RoleLSD:
+		return cfg.LSD.Server
+	default:
+		return cfg.Frontend.Server
+	}
+}
+
 func buildBaseURL(cfg *config.Config) string {
 	baseURL := strings.TrimSpace(cfg.Server.PublicBaseURL)
 	if baseURL != "" {
@@ -69,31 +342,3 @@ func buildBaseURL(cfg *config.Config) string {
 
 	return "http://localhost" + port
 }
-
-func publicationDownloadHandler(pubUsecase publication.PublicationUsecase) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			return
-		}
-
-		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-		if len(parts) < 3 || parts[0] != "publications" || parts[2] != "content" {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-
-		pubID := parts[1]
-		pub, err := pubUsecase.GetByID(context.Background(), pubID)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		if pub == nil || pub.EncryptedPath == "" {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-
-		http.ServeFile(w, r, pub.EncryptedPath)
-	})
-}
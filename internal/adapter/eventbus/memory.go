@@ -0,0 +1,84 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+)
+
+// DefaultBuffer is the number of events a subscriber may fall behind before
+// it is dropped.
+const DefaultBuffer = 64
+
+// MemoryBus is an in-process lcp.EventBus. Events are delivered to the
+// subscribers of the replica that published them only.
+type MemoryBus struct {
+	mu          sync.Mutex
+	buffer      int
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	types  map[lcp.EventType]bool
+	events chan lcp.Event
+	once   sync.Once
+}
+
+// NewMemoryBus returns an empty bus whose subscribers buffer DefaultBuffer
+// events.
+func NewMemoryBus() *MemoryBus {
+	return NewMemoryBusWithBuffer(DefaultBuffer)
+}
+
+// NewMemoryBusWithBuffer returns an empty bus whose subscribers buffer the
+// given number of events.
+func NewMemoryBusWithBuffer(buffer int) *MemoryBus {
+	return &MemoryBus{buffer: buffer, subscribers: make(map[*subscriber]struct{})}
+}
+
+// Publish hands event to every interested subscriber. A subscriber whose
+// buffer is full is dropped and its channel closed, so that it notices the
+// gap instead of silently missing events.
+func (b *MemoryBus) Publish(_ context.Context, event lcp.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers {
+		if len(sub.types) > 0 && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.removeLocked(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber until ctx is done.
+func (b *MemoryBus) Subscribe(ctx context.Context, types ...lcp.EventType) <-chan lcp.Event {
+	sub := &subscriber{events: make(chan lcp.Event, b.buffer)}
+	if len(types) > 0 {
+		sub.types = make(map[lcp.EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.removeLocked(sub)
+	}()
+	return sub.events
+}
+
+func (b *MemoryBus) removeLocked(sub *subscriber) {
+	delete(b.subscribers, sub)
+	sub.once.Do(func() { close(sub.events) })
+}
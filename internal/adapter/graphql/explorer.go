@@ -0,0 +1,70 @@
+package graphql
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+//go:generate sh vendor-graphiql.sh
+
+// embeddedExplorer holds the GraphiQL page, its bootstrap script and the
+// vendored GraphiQL and React bundles with their licences. They are compiled
+// into the binary, so the explorer loads nothing from the network.
+//
+//go:embed graphiql
+var embeddedExplorer embed.FS
+
+// explorerFiles is the file system the explorer is served from, rooted above
+// the graphiql directory.
+var explorerFiles fs.FS = embeddedExplorer
+
+// explorerAsset is the query parameter the page loads its files with, which
+// keeps them on the path of the endpoint wherever it is mounted.
+const explorerAsset = "graphiql"
+
+// explorerPolicy only allows scripts, styles and requests from the server
+// the page came from.
+const explorerPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; img-src 'self' data:; font-src 'self' data:; " +
+	"connect-src 'self'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// errExplorerNotVendored is returned by NewHandler when the explorer is
+// enabled in a build without the GraphiQL bundle.
+var errExplorerNotVendored = errors.New("graphql: the GraphiQL bundle is not vendored; run go generate ./internal/adapter/graphql or disable the explorer")
+
+// explorerVendored reports whether the GraphiQL bundle was embedded.
+func explorerVendored() bool {
+	_, err := fs.Stat(explorerFiles, "graphiql/graphiql.min.js")
+	return err == nil
+}
+
+// wantsExplorer reports whether r was sent by a browser navigating to the
+// endpoint rather than by a GraphQL client.
+func wantsExplorer(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == "text/html" {
+			return true
+		}
+	}
+	return false
+}
+
+// serveExplorer writes the GraphiQL page, or the file of the page named by
+// asset.
+func serveExplorer(w http.ResponseWriter, r *http.Request, asset string) {
+	name := "index.html"
+	if asset != "" {
+		name = asset
+	}
+	if !fs.ValidPath(name) || path.Dir(name) != "." {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Security-Policy", explorerPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFileFS(w, r, explorerFiles, "graphiql/"+name)
+}
@@ -0,0 +1,108 @@
+package graphql
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// withExplorerFiles serves the explorer from files for the duration of the
+// test.
+func withExplorerFiles(t *testing.T, files fs.FS) {
+	t.Helper()
+	previous := explorerFiles
+	explorerFiles = files
+	t.Cleanup(func() { explorerFiles = previous })
+}
+
+// stubExplorer is the page of the repository with stand-ins for the
+// vendored bundles.
+func stubExplorer(t *testing.T) fstest.MapFS {
+	t.Helper()
+	files := fstest.MapFS{}
+	for _, name := range []string{"index.html", "init.js", "page.css"} {
+		data, err := fs.ReadFile(embeddedExplorer, "graphiql/"+name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		files["graphiql/"+name] = &fstest.MapFile{Data: data}
+	}
+	for _, name := range []string{"react.production.min.js", "react-dom.production.min.js", "graphiql.min.js", "graphiql.min.css"} {
+		files["graphiql/"+name] = &fstest.MapFile{Data: []byte("/* " + name + " */")}
+	}
+	return files
+}
+
+func TestExplorerNeedsBundle(t *testing.T) {
+	files := stubExplorer(t)
+	delete(files, "graphiql/graphiql.min.js")
+	withExplorerFiles(t, files)
+
+	if _, err := NewHandler(&Resolver{}, Options{Explorer: true}); !errors.Is(err, errExplorerNotVendored) {
+		t.Fatalf("NewHandler = %v, want errExplorerNotVendored", err)
+	}
+}
+
+func TestExplorer(t *testing.T) {
+	withExplorerFiles(t, stubExplorer(t))
+	h, _ := newTestHandler(t, Options{Explorer: true})
+
+	get := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	page := get("/graphql", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	if page.Code != http.StatusOK || !strings.Contains(page.Body.String(), `<script src="?graphiql=graphiql.min.js">`) {
+		t.Fatalf("page = %d %q, want the GraphiQL page", page.Code, page.Body.String())
+	}
+	if policy := page.Header().Get("Content-Security-Policy"); policy != explorerPolicy {
+		t.Fatalf("Content-Security-Policy = %q", policy)
+	}
+	if ct := page.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("page Content-Type = %q, want HTML", ct)
+	}
+
+	// Every file the page loads is served, whatever the client accepts.
+	for _, asset := range []string{"graphiql.min.css", "page.css", "react.production.min.js", "react-dom.production.min.js", "graphiql.min.js", "init.js"} {
+		if !strings.Contains(page.Body.String(), "?graphiql="+asset) {
+			t.Fatalf("page does not load %s", asset)
+		}
+		rec := get("/graphql?graphiql="+asset, "*/*")
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Fatalf("%s answered %d with headers %v", asset, rec.Code, rec.Header())
+		}
+	}
+
+	for _, asset := range []string{"missing.js", "../explorer.go", "graphiql/init.js", "/etc/passwd"} {
+		if rec := get("/graphql?graphiql="+asset, "*/*"); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s answered %d, want 404", asset, rec.Code)
+		}
+	}
+
+	// GraphQL clients still reach the API.
+	rec := get(`/graphql?query={publications{title}}`, "application/json")
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"data\":{\"publications\":[]}}\n" {
+		t.Fatalf("query answered %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestExplorerDisabled(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	for _, target := range []string{"/graphql", "/graphql?graphiql=init.js"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", "text/html")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("%s answered %q, want the JSON greeting", target, ct)
+		}
+	}
+}
@@ -2,228 +2,242 @@ package graphql
 
 import (
 	"bytes"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
+	"github.com/Mehrbod2002/lcp/internal/pkg/websocket"
 )
 
-// NewHandler wires a lightweight GraphQL-compatible endpoint without external dependencies.
-// The handler supports the repository's defined operations and expects uploads as base64 strings.
-func NewHandler(resolver *Resolver) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			w.Header().Set("Content-Type", "application/json")
-			_ = json.NewEncoder(w).Encode(map[string]string{
-				"message": "Send a POST {query, variables} payload to interact with the LCP GraphQL API.",
-			})
-			return
-		}
-
-		payload, err := DecodePayload(r)
-		if err != nil {
-			writeGraphQLError(w, err)
-			return
-		}
-
-		query := strings.ToLower(payload.Query)
-		switch {
-		case strings.Contains(query, "uploadpublication"):
-			handleUploadPublication(w, r, resolver, payload)
-		case strings.Contains(query, "createlicense"):
-			handleCreateLicense(w, r, resolver, payload)
-		case strings.Contains(query, "revokelicense"):
-			handleRevokeLicense(w, r, resolver, payload)
-		case strings.Contains(query, "licenses"):
-			handleListLicenses(w, r, resolver, payload)
-		case strings.Contains(query, "publications"):
-			handleListPublications(w, r, resolver)
-		default:
-			writeGraphQLError(w, ErrUnsupportedOperation)
-		}
-	})
+// DefaultMaxBodySize applies when Options.MaxBodySize is zero.
+const DefaultMaxBodySize = 10 << 20
+
+// Options tunes the GraphQL endpoint.
+type Options struct {
+	// PersistedQueries configures Automatic Persisted Queries.
+	PersistedQueries PersistedQueryOptions
+	// MaxBatchSize bounds the number of operations sent as one JSON array.
+	// Zero disables batching.
+	MaxBatchSize int
+	// Limits bound the depth and estimated cost of operations; the costs are
+	// annotated in schema.graphql.
+	Limits gql.Limits
+	// Timeout bounds the execution of each operation. The deadline reaches
+	// the use cases through the resolver context. Zero disables it.
+	Timeout time.Duration
+	// Explorer serves GraphiQL to browsers that GET the endpoint. It needs
+	// the bundle vendored by go generate.
+	Explorer bool
+	// AllowedOrigins lists the browser origins, besides the host of the
+	// endpoint, allowed to open subscription WebSockets. "*" allows any.
+	AllowedOrigins []string
+	// MaxBodySize bounds JSON request bodies, and the operations and map
+	// parts of multipart requests, in bytes. The files of multipart requests
+	// are streamed and not bounded. Zero means DefaultMaxBodySize.
+	MaxBodySize int64
 }
 
-func handleUploadPublication(w http.ResponseWriter, r *http.Request, resolver *Resolver, payload *GraphQLPayload) {
-	title := stringValue(payload.Variables["title"])
-	rawFile := payload.Variables["file"]
-	if title == "" || rawFile == nil {
-		writeGraphQLError(w, ErrMissingFields)
-		return
+// NewHandler wires the GraphQL endpoint. Requests are parsed and validated
+// against schema.graphql and executed by the in-repo engine; uploads are
+// sent as multipart requests, or as base64 strings in the JSON body.
+// Subscriptions are served over WebSocket with the graphql-transport-ws
+// protocol. It fails when a field of schema.graphql has no resolver.
+// Introspection queries are answered from the same schema, which the
+// optional GraphiQL explorer uses to document it. It also fails when the
+// explorer is enabled without the vendored GraphiQL bundle.
+func NewHandler(resolver *Resolver, opts Options) (http.Handler, error) {
+	if opts.Explorer && !explorerVendored() {
+		return nil, errExplorerNotVendored
 	}
-
-	data, err := readUploadBytes(rawFile)
+	schema, err := LoadSchema()
 	if err != nil {
-		writeGraphQLError(w, err)
-		return
+		return nil, err
 	}
-
-	pub, err := resolver.PublicationUsecase.UploadAndEncrypt(r.Context(), title, bytes.NewReader(data))
+	executor, err := gql.NewExecutor(gql.Config{
+		Schema:           schema,
+		Resolvers:        resolver.fieldResolvers(),
+		TypeResolvers:    resolver.typeResolvers(),
+		Streams:          resolver.streams(),
+		ErrorExtensions:  errorExtensions,
+		Context:          resolver.withLoaders,
+		Limits:           opts.Limits,
+		RequireResolvers: true,
+	})
 	if err != nil {
-		writeGraphQLError(w, err)
-		return
+		return nil, err
 	}
 
-	writeGraphQLData(w, map[string]interface{}{
-		"uploadPublication": encodePublication(pub, resolver.PublicBaseURL),
-	})
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	return &handler{
+		executor:       executor,
+		documents:      newDocumentCache(executor, opts.PersistedQueries),
+		maxBatch:       opts.MaxBatchSize,
+		timeout:        opts.Timeout,
+		explorer:       opts.Explorer,
+		allowedOrigins: opts.AllowedOrigins,
+		maxBody:        opts.MaxBodySize,
+	}, nil
 }
 
-func handleCreateLicense(w http.ResponseWriter, r *http.Request, resolver *Resolver, payload *GraphQLPayload) {
-	startDate, err := parseTimePtr(stringPtr(payload.Variables["startDate"]))
-	if err != nil {
-		writeGraphQLError(w, err)
+type handler struct {
+	executor       *gql.Executor
+	documents      *documentCache
+	maxBatch       int
+	timeout        time.Duration
+	explorer       bool
+	allowedOrigins []string
+	maxBody        int64
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsUpgrade(r) {
+		serveWebSocket(w, r, h)
 		return
 	}
-	endDate, err := parseTimePtr(stringPtr(payload.Variables["endDate"]))
-	if err != nil {
-		writeGraphQLError(w, err)
+	if r.Method == http.MethodGet {
+		if asset := r.URL.Query().Get(explorerAsset); h.explorer && (asset != "" || wantsExplorer(r)) {
+			serveExplorer(w, r, asset)
+			return
+		}
+		payload, err := decodeQueryString(r)
+		if err != nil {
+			writeGraphQLError(w, apperrors.Wrap(apperrors.KindValidation, err, "invalid query string"))
+			return
+		}
+		if payload == nil {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"message": "Send a POST {query, variables} payload to interact with the LCP GraphQL API.",
+			})
+			return
+		}
+		writeGraphQLResponse(w, h.execute(r.Context(), payload, true))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
 		return
 	}
 
-	license, err := resolver.LicenseUsecase.Create(r.Context(), &lcp.LicenseInput{
-		PublicationID: stringValue(payload.Variables["publicationID"]),
-		UserID:        stringValue(payload.Variables["userID"]),
-		Passphrase:    stringValue(payload.Variables["passphrase"]),
-		Hint:          stringValue(payload.Variables["hint"]),
-		RightPrint:    intPtr(payload.Variables["rightPrint"]),
-		RightCopy:     intPtr(payload.Variables["rightCopy"]),
-		StartDate:     startDate,
-		EndDate:       endDate,
-	})
+	payloads, batch, uploads, err := decodeRequest(w, r, h.maxBody)
 	if err != nil {
-		writeGraphQLError(w, err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeGraphQLError(w, apperrors.Validation("request body must not exceed %d bytes", tooLarge.Limit))
+			return
+		}
+		writeGraphQLError(w, apperrors.Wrap(apperrors.KindValidation, err, "invalid request body"))
 		return
 	}
-
-	writeGraphQLData(w, map[string]interface{}{
-		"createLicense": encodeLicense(license),
-	})
-}
-
-func handleRevokeLicense(w http.ResponseWriter, r *http.Request, resolver *Resolver, payload *GraphQLPayload) {
-	id := stringValue(payload.Variables["id"])
-	if id == "" {
-		writeGraphQLError(w, ErrMissingFields)
+	if uploads != nil {
+		defer uploads.Close()
+	}
+	if !batch {
+		writeGraphQLResponse(w, h.execute(r.Context(), payloads[0], false))
 		return
 	}
-
-	if err := resolver.LicenseUsecase.Revoke(r.Context(), id); err != nil {
-		writeGraphQLError(w, err)
+	if len(payloads) == 0 || len(payloads) > h.maxBatch {
+		writeGraphQLError(w, apperrors.Validation("a batch must hold between 1 and %d operations", h.maxBatch))
 		return
 	}
 
-	writeGraphQLData(w, map[string]interface{}{
-		"revokeLicense": true,
-	})
+	// Operations of a batch run one after the other, so that later ones
+	// observe the mutations of earlier ones.
+	responses := make([]*gql.Response, len(payloads))
+	for i, payload := range payloads {
+		responses[i] = h.execute(r.Context(), payload, false)
+	}
+	writeGraphQLResponse(w, responses)
 }
 
-func handleListLicenses(w http.ResponseWriter, r *http.Request, resolver *Resolver, payload *GraphQLPayload) {
-	var publicationID *string
-	if val := stringPtr(payload.Variables["publicationID"]); val != nil {
-		publicationID = val
+// execute runs one payload, tagging request errors with the code clients use
+// to tell them apart from resolver failures. Read-only requests may only run
+// queries.
+func (h *handler) execute(ctx context.Context, payload *GraphQLPayload, readOnly bool) *gql.Response {
+	doc, errs := h.documents.resolve(payload)
+	if errs != nil {
+		return gql.ErrorResponse(errs...)
 	}
-
-	licenses, err := resolver.LicenseUsecase.GetByPublication(r.Context(), publicationID)
-	if err != nil {
-		writeGraphQLError(w, err)
-		return
+	if readOnly {
+		op, err := gql.SelectOperation(doc, payload.OperationName)
+		if err == nil && op.Type != gql.Query {
+			err = &gql.Error{Message: fmt.Sprintf("Only queries can be sent with GET, not %ss.", op.Type)}
+		}
+		if err != nil {
+			return gql.ErrorResponse(withCode(gql.Errors{err}, string(apperrors.KindValidation))...)
+		}
 	}
-
-	encoded := make([]map[string]interface{}, 0, len(licenses))
-	for _, lic := range licenses {
-		encoded = append(encoded, encodeLicense(lic))
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
 	}
-
-	writeGraphQLData(w, map[string]interface{}{
-		"licenses": encoded,
-	})
+	resp := h.executor.ExecuteDocument(ctx, doc, payload.OperationName, payload.Variables)
+	resp.Errors = withCode(resp.Errors, string(apperrors.KindValidation))
+	return resp
 }
 
-func handleListPublications(w http.ResponseWriter, r *http.Request, resolver *Resolver) {
-	pubs, err := resolver.PublicationUsecase.GetAll(r.Context())
-	if err != nil {
-		writeGraphQLError(w, err)
-		return
+// parse parses and validates a query, tagging the errors with the code of
+// the step that failed.
+func parse(executor *gql.Executor, query string) (*gql.Document, gql.Errors) {
+	if strings.TrimSpace(query) == "" {
+		return nil, gql.Errors{{
+			Message:    "Must provide query string.",
+			Extensions: map[string]any{"code": apperrors.KindValidation},
+		}}
+	}
+	doc, errs := executor.Parse(query)
+	if errs != nil {
+		code := "GRAPHQL_VALIDATION_FAILED"
+		if strings.HasPrefix(errs[0].Message, "Syntax Error") {
+			code = "GRAPHQL_PARSE_FAILED"
+		}
+		return nil, withCode(errs, code)
 	}
+	return doc, nil
+}
 
-	encoded := make([]map[string]interface{}, 0, len(pubs))
-	for _, pub := range pubs {
-		encoded = append(encoded, encodePublication(pub, resolver.PublicBaseURL))
+// withCode sets extensions.code on the errors that do not carry one yet.
+func withCode(errs gql.Errors, code string) gql.Errors {
+	for _, err := range errs {
+		if err.Extensions == nil {
+			err.Extensions = map[string]any{"code": code}
+		}
 	}
+	return errs
+}
 
-	writeGraphQLData(w, map[string]interface{}{
-		"publications": encoded,
-	})
+func errorExtensions(err error) map[string]any {
+	return map[string]any{"code": apperrors.KindOf(err)}
 }
 
-func writeGraphQLData(w http.ResponseWriter, data map[string]interface{}) {
+func writeGraphQLResponse(w http.ResponseWriter, resp any) {
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(resp)
 }
 
 func writeGraphQLError(w http.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"errors": []map[string]interface{}{{"message": err.Error()}},
+		"errors": []map[string]interface{}{{
+			"message":    err.Error(),
+			"extensions": map[string]interface{}{"code": apperrors.KindOf(err)},
+		}},
 	})
 }
 
-func encodePublication(pub *lcp.Publication, baseURL string) map[string]interface{} {
-	return map[string]interface{}{
-		"id":            pub.ID,
-		"title":         pub.Title,
-		"filePath":      pub.FilePath,
-		"encryptedPath": pub.EncryptedPath,
-		"createdAt":     pub.CreatedAt.Format(time.RFC3339),
-		"downloadURL":   strings.TrimRight(baseURL, "/") + "/publications/" + pub.ID + "/content",
-	}
-}
-
-func encodeLicense(license *lcp.License) map[string]interface{} {
-	var startDate, endDate *string
-	if license.StartDate != nil {
-		formatted := license.StartDate.Format(time.RFC3339)
-		startDate = &formatted
-	}
-	if license.EndDate != nil {
-		formatted := license.EndDate.Format(time.RFC3339)
-		endDate = &formatted
-	}
-
-	return map[string]interface{}{
-		"id":             license.ID,
-		"publicationID":  license.PublicationID,
-		"userID":         license.UserID,
-		"passphrase":     license.Passphrase,
-		"hint":           license.Hint,
-		"publicationURL": license.PublicationURL,
-		"rightPrint":     license.RightPrint,
-		"rightCopy":      license.RightCopy,
-		"startDate":      startDate,
-		"endDate":        endDate,
-		"createdAt":      license.CreatedAt.Format(time.RFC3339),
-	}
-}
-
-func readUploadBytes(value interface{}) ([]byte, error) {
-	switch v := value.(type) {
-	case string:
-		if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
-			return decoded, nil
-		}
-		return []byte(v), nil
-	case []byte:
-		return v, nil
-	default:
-		return nil, ErrUnsupportedFile
-	}
-}
-
 func stringValue(value interface{}) string {
 	if v, ok := value.(string); ok {
 		return v
@@ -253,36 +267,75 @@ func intPtr(value interface{}) *int {
 	}
 }
 
-func parseTimePtr(value *string) (*time.Time, error) {
-	if value == nil {
-		return nil, nil
-	}
-	parsed, err := time.Parse(time.RFC3339, *value)
-	if err != nil {
-		return nil, err
-	}
-	return &parsed, nil
-}
-
 // GraphQLPayload describes the request body accepted by the handler.
 type GraphQLPayload struct {
 	Query         string                 `json:"query"`
 	OperationName string                 `json:"operationName"`
 	Variables     map[string]interface{} `json:"variables"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+// decodeRequest reads a JSON payload or a JSON array of payloads, or a
+// multipart request carrying files for Upload variables, reading at most
+// maxBody bytes of JSON. batch reports whether the operations were sent as an
+// array.
+func decodeRequest(w http.ResponseWriter, r *http.Request, maxBody int64) (payloads []*GraphQLPayload, batch bool, uploads *uploadSet, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		return decodeMultipart(r, maxBody)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody)).Decode(&raw); err != nil {
+		return nil, false, nil, err
+	}
+	payloads, batch, err = decodeOperations(raw)
+	return payloads, batch, nil, err
 }
 
-// DecodePayload parses the HTTP request body into a GraphQLPayload.
-func DecodePayload(r *http.Request) (*GraphQLPayload, error) {
+// decodeOperations decodes one payload or an array of payloads.
+func decodeOperations(raw json.RawMessage) ([]*GraphQLPayload, bool, error) {
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		var payloads []*GraphQLPayload
+		if err := json.Unmarshal(raw, &payloads); err != nil {
+			return nil, false, err
+		}
+		for _, payload := range payloads {
+			if payload == nil {
+				return nil, false, errors.New("batched operations must be objects")
+			}
+		}
+		return payloads, true, nil
+	}
 	var payload GraphQLPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		return nil, err
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, false, err
+	}
+	return []*GraphQLPayload{&payload}, false, nil
+}
+
+// decodeQueryString reads a payload from the query string of a GET request,
+// or returns nil when the request carries none.
+func decodeQueryString(r *http.Request) (*GraphQLPayload, error) {
+	values := r.URL.Query()
+	if !values.Has("query") && !values.Has("extensions") {
+		return nil, nil
+	}
+	payload := &GraphQLPayload{Query: values.Get("query"), OperationName: values.Get("operationName")}
+	if raw := values.Get("variables"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &payload.Variables); err != nil {
+			return nil, fmt.Errorf("variables: %w", err)
+		}
+	}
+	if raw := values.Get("extensions"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &payload.Extensions); err != nil {
+			return nil, fmt.Errorf("extensions: %w", err)
+		}
 	}
-	return &payload, nil
+	return payload, nil
 }
 
 // GraphQL handler errors.
 var (
-	ErrUnsupportedOperation = errors.New("operation not supported by this handler")
-	ErrMissingFields        = errors.New("required fields are missing in variables")
-	ErrUnsupportedFile      = errors.New("file must be provided as a base64 string")
+	ErrUnsupportedFile = apperrors.Validation("file must be sent as a multipart part or a base64 string")
+	ErrInvalidTime     = apperrors.Validation("dates must be RFC 3339 timestamps")
 )
@@ -0,0 +1,99 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Mehrbod2002/lcp/internal/adapter/eventbus"
+	"github.com/Mehrbod2002/lcp/internal/adapter/repository/lcp"
+	domain "github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/lcp/encrypt"
+	lcplicense "github.com/Mehrbod2002/lcp/internal/lcp/license"
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/license"
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/publication"
+)
+
+// discardOutbox drops the notifications of the license use case.
+type discardOutbox struct{}
+
+func (discardOutbox) Queue(context.Context, domain.NotificationKind, string) error { return nil }
+func (discardOutbox) Wake()                                                        {}
+
+// newTestHandler serves the GraphQL endpoint over a volatile store, and
+// returns the store to seed it.
+func newTestHandler(t *testing.T, opts Options) (http.Handler, *lcp.Store) {
+	t.Helper()
+	store := lcp.NewStore()
+	events := eventbus.NewMemoryBus()
+	resolver := &Resolver{
+		PublicationUsecase: publication.NewPublicationUsecase(store.Publications, store.Licenses, store.LicenseEvents, store, encrypt.NewFileCopyEncrypter(t.TempDir()), events),
+		LicenseUsecase:     license.NewLicenseUsecase(store.Licenses, discardOutbox{}, store.Publications, store, lcplicense.NewService(), events, "http://localhost:8080", domain.RenewalPolicy{}),
+		Events:             events,
+		PublicBaseURL:      "http://localhost:8080",
+	}
+	h, err := NewHandler(resolver, opts)
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	return h, store
+}
+
+// graphQLResponse is the decoded body of a GraphQL response.
+type graphQLResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// post sends body as a JSON request and decodes the response.
+func post(t *testing.T, h http.Handler, body string) graphQLResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return decodeResponse(t, rec)
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder) graphQLResponse {
+	t.Helper()
+	var resp graphQLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestHandlerRejectsOversizedBodies(t *testing.T) {
+	h, _ := newTestHandler(t, Options{MaxBodySize: 64})
+
+	resp := post(t, h, `{"query": "{ publications { id } }"}`)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("small request failed: %+v", resp.Errors)
+	}
+
+	resp = post(t, h, `{"query": "{ publications { id title filePath encryptedPath createdAt } }"}`)
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != "request body must not exceed 64 bytes" {
+		t.Fatalf("oversized request answered %+v, want the size error", resp.Errors)
+	}
+}
+
+func TestHandlerRejectsOtherMethods(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/graphql", bytes.NewReader(nil)))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, POST" {
+		t.Fatalf("Allow = %q, want GET, POST", allow)
+	}
+}
@@ -0,0 +1,117 @@
+// Starts GraphiQL on the endpoint serving this page. Queries and mutations
+// are POSTed to it; subscriptions run over graphql-transport-ws on the same
+// path, since the bundled fetcher needs the graphql-ws client for them.
+(function () {
+  "use strict";
+
+  var endpoint = window.location.pathname;
+
+  function isSubscription(params, opts) {
+    var doc = opts && opts.documentAST;
+    if (!doc) {
+      return false;
+    }
+    var operation = doc.definitions.find(function (definition) {
+      return definition.kind === "OperationDefinition" &&
+        (!params.operationName || (definition.name && definition.name.value === params.operationName));
+    });
+    return Boolean(operation) && operation.operation === "subscription";
+  }
+
+  function post(params, opts) {
+    var headers = Object.assign({ "Content-Type": "application/json", Accept: "application/json" }, opts && opts.headers);
+    return fetch(endpoint, {
+      method: "POST",
+      headers: headers,
+      body: JSON.stringify(params),
+      credentials: "same-origin"
+    }).then(function (response) {
+      return response.json();
+    });
+  }
+
+  // subscribe returns an async iterator over the results of a subscription,
+  // which ends when the server completes it or the connection closes.
+  function subscribe(params) {
+    var scheme = window.location.protocol === "https:" ? "wss://" : "ws://";
+    var socket = new WebSocket(scheme + window.location.host + endpoint, "graphql-transport-ws");
+    var results = [];
+    var waiting = null;
+    var done = false;
+
+    function push(result) {
+      if (done) {
+        return;
+      }
+      if (result.done) {
+        done = true;
+      }
+      if (waiting) {
+        var resolve = waiting;
+        waiting = null;
+        resolve(result);
+      } else {
+        results.push(result);
+      }
+    }
+
+    socket.onopen = function () {
+      socket.send(JSON.stringify({ type: "connection_init" }));
+    };
+    socket.onmessage = function (event) {
+      var message = JSON.parse(event.data);
+      switch (message.type) {
+        case "connection_ack":
+          socket.send(JSON.stringify({ id: "1", type: "subscribe", payload: params }));
+          break;
+        case "ping":
+          socket.send(JSON.stringify({ type: "pong" }));
+          break;
+        case "next":
+          push({ value: message.payload, done: false });
+          break;
+        case "error":
+          push({ value: { errors: message.payload }, done: false });
+          push({ value: undefined, done: true });
+          break;
+        case "complete":
+          push({ value: undefined, done: true });
+          break;
+      }
+    };
+    socket.onclose = function () {
+      push({ value: undefined, done: true });
+    };
+
+    var iterator = {
+      next: function () {
+        if (results.length > 0) {
+          return Promise.resolve(results.shift());
+        }
+        if (done) {
+          return Promise.resolve({ value: undefined, done: true });
+        }
+        return new Promise(function (resolve) {
+          waiting = resolve;
+        });
+      },
+      return: function () {
+        push({ value: undefined, done: true });
+        socket.close();
+        return Promise.resolve({ value: undefined, done: true });
+      }
+    };
+    iterator[Symbol.asyncIterator] = function () {
+      return iterator;
+    };
+    return iterator;
+  }
+
+  function fetcher(params, opts) {
+    return isSubscription(params, opts) ? subscribe(params) : post(params, opts);
+  }
+
+  ReactDOM.createRoot(document.getElementById("graphiql")).render(
+    React.createElement(GraphiQL, { fetcher: fetcher })
+  );
+})();
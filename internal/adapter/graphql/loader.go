@@ -0,0 +1,103 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
+)
+
+// loader collects the keys requested while one level of a query resolves and
+// fetches them with a single call when the first value is needed, so nested
+// fields cost one lookup per level instead of one per parent. A loader lives
+// for one request; the executor resolves fields of a request sequentially.
+type loader[V any] struct {
+	fetch   func(ctx context.Context, keys []string) (map[string]V, error)
+	pending []string
+	queued  map[string]bool
+	results map[string]V
+	errs    map[string]error
+}
+
+func newLoader[V any](fetch func(ctx context.Context, keys []string) (map[string]V, error)) *loader[V] {
+	return &loader[V]{
+		fetch:   fetch,
+		queued:  make(map[string]bool),
+		results: make(map[string]V),
+		errs:    make(map[string]error),
+	}
+}
+
+// load queues key and returns a thunk that yields its value.
+func (l *loader[V]) load(ctx context.Context, key string) gql.Thunk {
+	_, done := l.results[key]
+	if !done && l.errs[key] == nil && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	return func() (any, error) {
+		if l.queued[key] {
+			l.dispatch(ctx)
+		}
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		return l.results[key], nil
+	}
+}
+
+func (l *loader[V]) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+	values, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		delete(l.queued, key)
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		l.results[key] = values[key]
+	}
+}
+
+// loaders are the per-request loaders behind nested fields.
+type loaders struct {
+	publications          *loader[*lcp.Publication]
+	licensesByPublication *loader[[]*lcp.License]
+}
+
+type loadersKey struct{}
+
+func (r *Resolver) withLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		publications: newLoader(func(ctx context.Context, ids []string) (map[string]*lcp.Publication, error) {
+			pubs, err := r.PublicationUsecase.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[string]*lcp.Publication, len(pubs))
+			for _, pub := range pubs {
+				byID[pub.ID] = pub
+			}
+			return byID, nil
+		}),
+		licensesByPublication: newLoader(func(ctx context.Context, publicationIDs []string) (map[string][]*lcp.License, error) {
+			licenses, err := r.LicenseUsecase.GetByPublications(ctx, publicationIDs)
+			if err != nil {
+				return nil, err
+			}
+			byPublication := make(map[string][]*lcp.License, len(publicationIDs))
+			for _, id := range publicationIDs {
+				byPublication[id] = []*lcp.License{}
+			}
+			for _, license := range licenses {
+				byPublication[license.PublicationID] = append(byPublication[license.PublicationID], license)
+			}
+			return byPublication, nil
+		}),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,119 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
+)
+
+// Global IDs identify any Node across types. They encode "<Type>:<id>" so
+// that node(id) can route the lookup; clients must treat them as opaque.
+const (
+	publicationNode = "Publication"
+	licenseNode     = "License"
+)
+
+func globalID(typeName, id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(typeName + ":" + id))
+}
+
+// parseGlobalID splits a global ID into its type name and record ID.
+func parseGlobalID(value string) (typeName, id string, ok bool) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", "", false
+	}
+	typeName, id, ok = strings.Cut(string(decoded), ":")
+	if !ok || id == "" || (typeName != publicationNode && typeName != licenseNode) {
+		return "", "", false
+	}
+	return typeName, id, true
+}
+
+// localID returns the record ID behind an ID argument. Arguments accept
+// either the global ID of a typeName node or the record ID itself, so that
+// clients written before global IDs keep working.
+func localID(typeName string, value interface{}) (string, error) {
+	raw := stringValue(value)
+	if nodeType, id, ok := parseGlobalID(raw); ok {
+		if nodeType != typeName {
+			return "", apperrors.Validation("id %s is not a %s", raw, typeName)
+		}
+		return id, nil
+	}
+	return raw, nil
+}
+
+// localIDPtr is localID for optional arguments.
+func localIDPtr(typeName string, value interface{}) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	id, err := localID(typeName, value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// resolveNodeType names the object type of a value returned for Node.
+func resolveNodeType(value any) string {
+	switch value.(type) {
+	case *lcp.Publication:
+		return publicationNode
+	case *lcp.License:
+		return licenseNode
+	}
+	return ""
+}
+
+func (r *Resolver) typeResolvers() map[string]gql.TypeResolver {
+	return map[string]gql.TypeResolver{
+		"Node": resolveNodeType,
+	}
+}
+
+func (r *Resolver) node(p gql.ResolveParams) (any, error) {
+	typeName, id, ok := parseGlobalID(stringValue(p.Args["id"]))
+	if !ok {
+		return nil, nil
+	}
+	switch typeName {
+	case publicationNode:
+		return nullIfNotFound(r.PublicationUsecase.GetByID(p.Context, id))
+	default:
+		return nullIfNotFound(r.LicenseUsecase.GetByID(p.Context, id))
+	}
+}
+
+func (r *Resolver) publication(p gql.ResolveParams) (any, error) {
+	id, err := localID(publicationNode, p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	return nullIfNotFound(r.PublicationUsecase.GetByID(p.Context, id))
+}
+
+func (r *Resolver) license(p gql.ResolveParams) (any, error) {
+	id, err := localID(licenseNode, p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	return nullIfNotFound(r.LicenseUsecase.GetByID(p.Context, id))
+}
+
+// nullIfNotFound turns a missing record into a null field, as single-entity
+// queries report absent records without an error.
+func nullIfNotFound[T any](value *T, err error) (any, error) {
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,144 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
+	"github.com/Mehrbod2002/lcp/internal/pkg/lru"
+)
+
+// Error codes of the Automatic Persisted Queries protocol.
+const (
+	codePersistedQueryNotFound     = "PERSISTED_QUERY_NOT_FOUND"
+	codePersistedQueryNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+	codePersistedQueryNotInList    = "PERSISTED_QUERY_NOT_IN_LIST"
+)
+
+// PersistedQueryOptions configures Automatic Persisted Queries, which let
+// clients send the SHA-256 hash of a query instead of its text.
+type PersistedQueryOptions struct {
+	// CacheSize is the number of parsed documents kept in memory, keyed by
+	// the hash of their query.
+	CacheSize int
+	// AllowList maps query hashes to queries. When set, only these queries
+	// are executed and clients cannot register new ones.
+	AllowList map[string]string
+}
+
+// LoadPersistedQueryManifest reads an allow-list of queries from a JSON file.
+// Both an Apollo persisted query manifest and a plain object mapping hashes
+// to queries are accepted; hashes are recomputed from the query text.
+func LoadPersistedQueryManifest(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Operations []struct {
+			Body string `json:"body"`
+		} `json:"operations"`
+	}
+	var queries []string
+	if err := json.Unmarshal(data, &manifest); err == nil && manifest.Operations != nil {
+		for _, op := range manifest.Operations {
+			queries = append(queries, op.Body)
+		}
+	} else {
+		var byHash map[string]string
+		if err := json.Unmarshal(data, &byHash); err != nil {
+			return nil, errors.New("persisted query manifest must be an Apollo manifest or an object of hashes to queries")
+		}
+		for _, query := range byHash {
+			queries = append(queries, query)
+		}
+	}
+
+	allowList := make(map[string]string, len(queries))
+	for _, query := range queries {
+		allowList[queryHash(query)] = query
+	}
+	return allowList, nil
+}
+
+// documentCache resolves the document of a payload from its query text or
+// persisted query hash, caching parsed and validated documents.
+type documentCache struct {
+	executor  *gql.Executor
+	documents *lru.Cache[string, *gql.Document]
+	allowList map[string]string
+}
+
+func newDocumentCache(executor *gql.Executor, opts PersistedQueryOptions) *documentCache {
+	return &documentCache{
+		executor:  executor,
+		documents: lru.New[string, *gql.Document](opts.CacheSize),
+		allowList: opts.AllowList,
+	}
+}
+
+func (c *documentCache) resolve(payload *GraphQLPayload) (*gql.Document, gql.Errors) {
+	hash, persisted, errs := persistedQuery(payload.Extensions)
+	if errs != nil {
+		return nil, errs
+	}
+
+	query := payload.Query
+	if query == "" && persisted {
+		if doc, ok := c.documents.Get(hash); ok {
+			return doc, nil
+		}
+		if query = c.allowList[hash]; query == "" {
+			return nil, persistedQueryError("PersistedQueryNotFound", codePersistedQueryNotFound)
+		}
+	} else if query != "" {
+		computed := queryHash(query)
+		if persisted && computed != hash {
+			return nil, persistedQueryError("provided sha does not match query", string(apperrors.KindValidation))
+		}
+		if c.allowList != nil && c.allowList[computed] == "" {
+			return nil, persistedQueryError("Query is not in the persisted query list.", codePersistedQueryNotInList)
+		}
+		hash = computed
+		if doc, ok := c.documents.Get(hash); ok {
+			return doc, nil
+		}
+	}
+
+	doc, errs := parse(c.executor, query)
+	if errs != nil {
+		return nil, errs
+	}
+	c.documents.Add(hash, doc)
+	return doc, nil
+}
+
+// persistedQuery reads the persistedQuery request extension.
+func persistedQuery(extensions map[string]interface{}) (hash string, ok bool, errs gql.Errors) {
+	raw, present := extensions["persistedQuery"]
+	if !present {
+		return "", false, nil
+	}
+	pq, _ := raw.(map[string]interface{})
+	if version, _ := pq["version"].(float64); version != 1 {
+		return "", false, persistedQueryError("Unsupported persisted query version.", codePersistedQueryNotSupported)
+	}
+	hash, _ = pq["sha256Hash"].(string)
+	if hash == "" {
+		return "", false, persistedQueryError("persistedQuery.sha256Hash is required.", string(apperrors.KindValidation))
+	}
+	return hash, true, nil
+}
+
+func persistedQueryError(message, code string) gql.Errors {
+	return gql.Errors{{Message: message, Extensions: map[string]any{"code": code}}}
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package graphql
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const persistedTestQuery = "{ publications { title } }"
+
+// persistedRequest is the body of a request carrying the hash of a query,
+// and the query itself unless it is empty.
+func persistedRequest(query, hash string) string {
+	if query == "" {
+		return fmt.Sprintf(`{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": %q}}}`, hash)
+	}
+	return fmt.Sprintf(`{"query": %q, "extensions": {"persistedQuery": {"version": 1, "sha256Hash": %q}}}`, query, hash)
+}
+
+// expectCode checks resp failed with one error carrying code, or succeeded
+// when code is empty.
+func expectCode(t *testing.T, resp graphQLResponse, code string) {
+	t.Helper()
+	if code == "" {
+		if len(resp.Errors) != 0 || resp.Data["publications"] == nil {
+			t.Fatalf("response = %+v, want the publications", resp)
+		}
+		return
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != code {
+		t.Fatalf("errors = %+v, want %s", resp.Errors, code)
+	}
+}
+
+func TestAutomaticPersistedQueries(t *testing.T) {
+	h, _ := newTestHandler(t, Options{PersistedQueries: PersistedQueryOptions{CacheSize: 10}})
+	hash := queryHash(persistedTestQuery)
+
+	// The client sends the hash alone, and the query once it is unknown.
+	expectCode(t, post(t, h, persistedRequest("", hash)), codePersistedQueryNotFound)
+	expectCode(t, post(t, h, persistedRequest(persistedTestQuery, hash)), "")
+	expectCode(t, post(t, h, persistedRequest("", hash)), "")
+
+	expectCode(t, post(t, h, persistedRequest(persistedTestQuery, queryHash("{ licenses { id } }"))), "BAD_USER_INPUT")
+	expectCode(t, post(t, h, `{"extensions": {"persistedQuery": {"version": 2, "sha256Hash": "`+hash+`"}}}`), codePersistedQueryNotSupported)
+	expectCode(t, post(t, h, `{"extensions": {"persistedQuery": {"version": 1}}}`), "BAD_USER_INPUT")
+}
+
+func TestPersistedQueriesWithoutCache(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	hash := queryHash(persistedTestQuery)
+
+	expectCode(t, post(t, h, persistedRequest(persistedTestQuery, hash)), "")
+	expectCode(t, post(t, h, persistedRequest("", hash)), codePersistedQueryNotFound)
+}
+
+func TestPersistedQueryAllowList(t *testing.T) {
+	hash := queryHash(persistedTestQuery)
+	h, _ := newTestHandler(t, Options{PersistedQueries: PersistedQueryOptions{
+		CacheSize: 10,
+		AllowList: map[string]string{hash: persistedTestQuery},
+	}})
+
+	expectCode(t, post(t, h, persistedRequest("", hash)), "")
+	expectCode(t, post(t, h, `{"query": "`+persistedTestQuery+`"}`), "")
+
+	// Other queries can neither run nor be registered.
+	other := "{ licenses { id } }"
+	expectCode(t, post(t, h, `{"query": "`+other+`"}`), codePersistedQueryNotInList)
+	expectCode(t, post(t, h, persistedRequest(other, queryHash(other))), codePersistedQueryNotInList)
+	expectCode(t, post(t, h, persistedRequest("", queryHash(other))), codePersistedQueryNotFound)
+}
+
+func TestLoadPersistedQueryManifest(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+	want := map[string]string{queryHash(persistedTestQuery): persistedTestQuery}
+
+	apollo := write("apollo.json", `{"format": "apollo-persisted-query-manifest", "version": 1, "operations": [{"id": "ignored", "name": "Publications", "type": "query", "body": "`+persistedTestQuery+`"}]}`)
+	if got, err := LoadPersistedQueryManifest(apollo); err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Apollo manifest = %v, %v, want %v", got, err, want)
+	}
+
+	// Hashes are recomputed rather than trusted.
+	byHash := write("hashes.json", `{"not-the-hash": "`+persistedTestQuery+`"}`)
+	if got, err := LoadPersistedQueryManifest(byHash); err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("hash manifest = %v, %v, want %v", got, err, want)
+	}
+
+	if _, err := LoadPersistedQueryManifest(write("list.json", `["`+persistedTestQuery+`"]`)); err == nil {
+		t.Fatal("a list of queries was accepted")
+	}
+	if _, err := LoadPersistedQueryManifest(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatal("a missing manifest was accepted")
+	}
+}
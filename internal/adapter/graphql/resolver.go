@@ -1,6 +1,13 @@
 package graphql
 
 import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
 	usecaseLicense "github.com/Mehrbod2002/lcp/internal/usecase/lcp/license"
 	usecasePublication "github.com/Mehrbod2002/lcp/internal/usecase/lcp/publication"
 )
@@ -9,5 +16,208 @@ import (
 type Resolver struct {
 	PublicationUsecase usecasePublication.PublicationUsecase
 	LicenseUsecase     usecaseLicense.LicenseUsecase
+	Events             lcp.EventSubscriber
 	PublicBaseURL      string
+	// LicenseBaseURL prefixes the publication links of licenses, served by
+	// the license server. It defaults to PublicBaseURL.
+	LicenseBaseURL string
+}
+
+// fieldResolvers maps every field of schema.graphql to a resolver. Root
+// fields call the use cases; Publication and License fields read the domain
+// entities they return, and their nested fields go through the request's
+// loaders. NewHandler refuses to start when a field is missing.
+func (r *Resolver) fieldResolvers() map[string]map[string]gql.FieldResolver {
+	return map[string]map[string]gql.FieldResolver{
+		"Publication": {
+			"id":            publicationField(func(pub *lcp.Publication) any { return globalID(publicationNode, pub.ID) }),
+			"databaseId":    publicationField(func(pub *lcp.Publication) any { return pub.ID }),
+			"title":         publicationField(func(pub *lcp.Publication) any { return pub.Title }),
+			"filePath":      publicationField(func(pub *lcp.Publication) any { return pub.FilePath }),
+			"encryptedPath": publicationField(func(pub *lcp.Publication) any { return pub.EncryptedPath }),
+			"createdAt":     publicationField(func(pub *lcp.Publication) any { return pub.CreatedAt.Format(time.RFC3339) }),
+			"downloadURL": publicationField(func(pub *lcp.Publication) any {
+				return strings.TrimRight(r.PublicBaseURL, "/") + "/publications/" + pub.ID + "/content"
+			}),
+			"status": publicationField(func(pub *lcp.Publication) any { return strings.ToUpper(string(pub.CurrentStatus())) }),
+			"licenses": func(p gql.ResolveParams) (any, error) {
+				pub := p.Source.(*lcp.Publication)
+				return loadersFrom(p.Context).licensesByPublication.load(p.Context, pub.ID), nil
+			},
+		},
+		"License": {
+			"id":                    licenseField(func(lic *lcp.License) any { return globalID(licenseNode, lic.ID) }),
+			"databaseId":            licenseField(func(lic *lcp.License) any { return lic.ID }),
+			"publicationID":         licenseField(func(lic *lcp.License) any { return globalID(publicationNode, lic.PublicationID) }),
+			"publicationDatabaseId": licenseField(func(lic *lcp.License) any { return lic.PublicationID }),
+			"userID":                licenseField(func(lic *lcp.License) any { return lic.UserID }),
+			"passphrase":            licenseField(func(lic *lcp.License) any { return lic.Passphrase }),
+			"hint":                  licenseField(func(lic *lcp.License) any { return lic.Hint }),
+			"publicationURL":        licenseField(r.licensedPublicationURL),
+			"rightPrint":            licenseField(func(lic *lcp.License) any { return lic.RightPrint }),
+			"rightCopy":             licenseField(func(lic *lcp.License) any { return lic.RightCopy }),
+			"startDate":             licenseField(func(lic *lcp.License) any { return formatTimePtr(lic.StartDate) }),
+			"endDate":               licenseField(func(lic *lcp.License) any { return formatTimePtr(lic.EndDate) }),
+			"createdAt":             licenseField(func(lic *lcp.License) any { return lic.CreatedAt.Format(time.RFC3339) }),
+			"status":                licenseField(func(lic *lcp.License) any { return strings.ToUpper(string(lic.StatusAt(time.Now()))) }),
+			"revokedAt":             licenseField(func(lic *lcp.License) any { return formatTimePtr(lic.RevokedAt) }),
+			"revocationReason": licenseField(func(lic *lcp.License) any {
+				if lic.RevocationReason == "" {
+					return nil
+				}
+				return lic.RevocationReason
+			}),
+			"publication": func(p gql.ResolveParams) (any, error) {
+				lic := p.Source.(*lcp.License)
+				return loadersFrom(p.Context).publications.load(p.Context, lic.PublicationID), nil
+			},
+		},
+		"PublicationEvent": publicationEventResolvers(),
+		"LicenseEvent":     licenseEventResolvers(),
+		"Query": {
+			"node":         r.node,
+			"publication":  r.publication,
+			"license":      r.license,
+			"publications": r.publications,
+			"licenses":     r.licenses,
+		},
+		"Mutation": {
+			"uploadPublication":     r.uploadPublication,
+			"updatePublication":     r.updatePublication,
+			"activatePublication":   r.changePublication(r.PublicationUsecase.Activate),
+			"deactivatePublication": r.changePublication(r.PublicationUsecase.Deactivate),
+			"deletePublication":     r.deletePublication,
+			"createLicense":         r.createLicense,
+			"revokeLicense":         r.revokeLicense,
+		},
+	}
+}
+
+// licensedPublicationURL is the publication link of a license, built from
+// its ID like the links of the license documents.
+func (r *Resolver) licensedPublicationURL(lic *lcp.License) any {
+	baseURL := r.LicenseBaseURL
+	if baseURL == "" {
+		baseURL = r.PublicBaseURL
+	}
+	return strings.TrimRight(baseURL, "/") + "/licenses/" + lic.ID + "/publication"
+}
+
+func (r *Resolver) publications(p gql.ResolveParams) (any, error) {
+	return r.PublicationUsecase.GetAll(p.Context)
+}
+
+func (r *Resolver) licenses(p gql.ResolveParams) (any, error) {
+	publicationID, err := localIDPtr(publicationNode, p.Args["publicationID"])
+	if err != nil {
+		return nil, err
+	}
+	return r.LicenseUsecase.GetByPublication(p.Context, publicationID)
+}
+
+func (r *Resolver) uploadPublication(p gql.ResolveParams) (any, error) {
+	file, ok := p.Args["file"].(io.Reader)
+	if !ok {
+		return nil, ErrUnsupportedFile
+	}
+	return r.PublicationUsecase.UploadAndEncrypt(p.Context, stringValue(p.Args["title"]), file)
+}
+
+func (r *Resolver) updatePublication(p gql.ResolveParams) (any, error) {
+	id, err := localID(publicationNode, p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	return r.PublicationUsecase.Update(p.Context, id, &lcp.PublicationUpdate{Title: stringPtr(p.Args["title"])})
+}
+
+// changePublication resolves the mutations that take a publication ID and
+// return the changed publication.
+func (r *Resolver) changePublication(change func(ctx context.Context, id string) (*lcp.Publication, error)) gql.FieldResolver {
+	return func(p gql.ResolveParams) (any, error) {
+		id, err := localID(publicationNode, p.Args["id"])
+		if err != nil {
+			return nil, err
+		}
+		return change(p.Context, id)
+	}
+}
+
+func (r *Resolver) deletePublication(p gql.ResolveParams) (any, error) {
+	id, err := localID(publicationNode, p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	if err := r.PublicationUsecase.Delete(p.Context, id); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+func (r *Resolver) createLicense(p gql.ResolveParams) (any, error) {
+	startDate, err := parseTimePtr(stringPtr(p.Args["startDate"]))
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := parseTimePtr(stringPtr(p.Args["endDate"]))
+	if err != nil {
+		return nil, err
+	}
+	publicationID, err := localID(publicationNode, p.Args["publicationID"])
+	if err != nil {
+		return nil, err
+	}
+
+	return r.LicenseUsecase.Create(p.Context, &lcp.LicenseInput{
+		PublicationID: publicationID,
+		UserID:        stringValue(p.Args["userID"]),
+		Passphrase:    stringValue(p.Args["passphrase"]),
+		Hint:          stringValue(p.Args["hint"]),
+		RightPrint:    intPtr(p.Args["rightPrint"]),
+		RightCopy:     intPtr(p.Args["rightCopy"]),
+		StartDate:     startDate,
+		EndDate:       endDate,
+	})
+}
+
+func (r *Resolver) revokeLicense(p gql.ResolveParams) (any, error) {
+	id, err := localID(licenseNode, p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	if err := r.LicenseUsecase.Revoke(p.Context, id, stringValue(p.Args["reason"])); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+func publicationField(get func(pub *lcp.Publication) any) gql.FieldResolver {
+	return func(p gql.ResolveParams) (any, error) {
+		return get(p.Source.(*lcp.Publication)), nil
+	}
+}
+
+func licenseField(get func(lic *lcp.License) any) gql.FieldResolver {
+	return func(p gql.ResolveParams) (any, error) {
+		return get(p.Source.(*lcp.License)), nil
+	}
+}
+
+func formatTimePtr(value *time.Time) *string {
+	if value == nil {
+		return nil
+	}
+	formatted := value.Format(time.RFC3339)
+	return &formatted
+}
+
+func parseTimePtr(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, ErrInvalidTime
+	}
+	return &parsed, nil
 }
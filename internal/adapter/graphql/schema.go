@@ -0,0 +1,48 @@
+package graphql
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+// LoadSchema parses schema.graphql and binds the custom scalars it declares.
+func LoadSchema() (*gql.Schema, error) {
+	schema, err := gql.ParseSchema(schemaSource)
+	if err != nil {
+		return nil, err
+	}
+	schema.Type("Upload").Scalar = &gql.Scalar{
+		ParseValue: readUpload,
+		ParseLiteral: func(value *gql.Value) (any, error) {
+			if value.Kind != gql.StringValue {
+				return nil, ErrUnsupportedFile
+			}
+			return readUpload(value.Raw)
+		},
+	}
+	return schema, nil
+}
+
+// readUpload accepts the reader of a multipart file part, or a base64 string
+// for clients that send files inline in the JSON body.
+func readUpload(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case io.Reader:
+		return v, nil
+	case string:
+		if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
+			return bytes.NewReader(decoded), nil
+		}
+		return strings.NewReader(v), nil
+	default:
+		return nil, ErrUnsupportedFile
+	}
+}
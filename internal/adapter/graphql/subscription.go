@@ -0,0 +1,109 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
+)
+
+// Event type enum values of schema.graphql, by domain event type.
+var (
+	publicationEventTypes = map[lcp.EventType]string{
+		lcp.EventPublicationEncrypted: "ENCRYPTED",
+		lcp.EventPublicationFailed:    "FAILED",
+	}
+	licenseEventTypes = map[lcp.EventType]string{
+		lcp.EventLicenseCreated:       "CREATED",
+		lcp.EventLicenseRevoked:       "REVOKED",
+		lcp.EventLicenseStatusChanged: "STATUS_CHANGED",
+	}
+)
+
+// streams maps the subscription fields of schema.graphql to the event bus.
+func (r *Resolver) streams() map[string]gql.StreamResolver {
+	return map[string]gql.StreamResolver{
+		"publicationEvents": func(p gql.ResolveParams) (<-chan any, error) {
+			types := eventTypes(publicationEventTypes, p.Args["types"])
+			return r.subscribe(p.Context, types, nil), nil
+		},
+		"licenseEvents": func(p gql.ResolveParams) (<-chan any, error) {
+			publicationID, err := localIDPtr(publicationNode, p.Args["publicationID"])
+			if err != nil {
+				return nil, err
+			}
+			types := eventTypes(licenseEventTypes, p.Args["types"])
+			return r.subscribe(p.Context, types, func(event lcp.Event) bool {
+				return publicationID == nil || event.License.PublicationID == *publicationID
+			}), nil
+		},
+	}
+}
+
+// subscribe forwards the bus events accepted by match until ctx is done.
+func (r *Resolver) subscribe(ctx context.Context, types []lcp.EventType, match func(lcp.Event) bool) <-chan any {
+	events := r.Events.Subscribe(ctx, types...)
+	out := make(chan any)
+	go func() {
+		defer close(out)
+		for event := range events {
+			if match != nil && !match(event) {
+				continue
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// eventTypes returns the domain event types selected by a list of enum
+// values, or every type of the enum when the list is absent or empty.
+func eventTypes(enum map[lcp.EventType]string, selected any) []lcp.EventType {
+	values, _ := selected.([]any)
+	var types []lcp.EventType
+	for eventType, name := range enum {
+		if len(values) == 0 {
+			types = append(types, eventType)
+			continue
+		}
+		for _, value := range values {
+			if value == name {
+				types = append(types, eventType)
+			}
+		}
+	}
+	return types
+}
+
+func publicationEventResolvers() map[string]gql.FieldResolver {
+	return map[string]gql.FieldResolver{
+		"type":        eventField(func(event lcp.Event) any { return publicationEventTypes[event.Type] }),
+		"publication": eventField(func(event lcp.Event) any { return event.Publication }),
+		"error": eventField(func(event lcp.Event) any {
+			if event.Error == "" {
+				return nil
+			}
+			return event.Error
+		}),
+		"occurredAt": eventField(func(event lcp.Event) any { return event.OccurredAt.Format(time.RFC3339) }),
+	}
+}
+
+func licenseEventResolvers() map[string]gql.FieldResolver {
+	return map[string]gql.FieldResolver{
+		"type":       eventField(func(event lcp.Event) any { return licenseEventTypes[event.Type] }),
+		"license":    eventField(func(event lcp.Event) any { return event.License }),
+		"occurredAt": eventField(func(event lcp.Event) any { return event.OccurredAt.Format(time.RFC3339) }),
+	}
+}
+
+func eventField(get func(event lcp.Event) any) gql.FieldResolver {
+	return func(p gql.ResolveParams) (any, error) {
+		return get(p.Source.(lcp.Event)), nil
+	}
+}
@@ -0,0 +1,233 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// decodeMultipart reads a request following the GraphQL multipart request
+// specification: an "operations" part holding the JSON payload or a batch of
+// payloads, a "map" part naming the variables each file fills, then one part
+// per file. Only the first two parts are read here, up to maxPart bytes each;
+// the returned uploads stream the file parts to the resolvers as they read
+// them. The caller must close the uploads once the request has been executed.
+func decodeMultipart(r *http.Request, maxPart int64) ([]*GraphQLPayload, bool, *uploadSet, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	var operations interface{}
+	if err := decodePart(reader, "operations", maxPart, &operations); err != nil {
+		return nil, false, nil, err
+	}
+	var fileMap map[string][]string
+	if err := decodePart(reader, "map", maxPart, &fileMap); err != nil {
+		return nil, false, nil, err
+	}
+
+	_, batch := operations.([]interface{})
+	uploads := &uploadSet{
+		reader:   reader,
+		expected: make(map[string]bool, len(fileMap)),
+		spooled:  make(map[string]*os.File),
+	}
+	for key, paths := range fileMap {
+		uploads.expected[key] = true
+		for _, path := range paths {
+			if err := setPath(operations, path, batch, &uploadReader{set: uploads, key: key}); err != nil {
+				return nil, false, nil, err
+			}
+		}
+	}
+
+	var objects []interface{}
+	if batch {
+		objects = operations.([]interface{})
+	} else {
+		objects = []interface{}{operations}
+	}
+	payloads := make([]*GraphQLPayload, 0, len(objects))
+	for _, object := range objects {
+		operation, ok := object.(map[string]interface{})
+		if !ok {
+			return nil, false, nil, apperrors.Validation("operations must be objects")
+		}
+		payload := &GraphQLPayload{
+			Query:         stringValue(operation["query"]),
+			OperationName: stringValue(operation["operationName"]),
+		}
+		payload.Variables, _ = operation["variables"].(map[string]interface{})
+		payload.Extensions, _ = operation["extensions"].(map[string]interface{})
+		payloads = append(payloads, payload)
+	}
+	return payloads, batch, uploads, nil
+}
+
+func decodePart(reader *multipart.Reader, name string, maxSize int64, target interface{}) error {
+	part, err := reader.NextPart()
+	if err != nil {
+		return apperrors.Validation("multipart request is missing the %q part", name)
+	}
+	defer part.Close()
+	if part.FormName() != name {
+		return apperrors.Validation("multipart request must send %q before %q", name, part.FormName())
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(nil, part, maxSize)).Decode(target); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return apperrors.Validation("the %q part must not exceed %d bytes", name, tooLarge.Limit)
+		}
+		return apperrors.Validation("invalid %q part: %v", name, err)
+	}
+	return nil
+}
+
+// setPath replaces the value at an object path such as "variables.file" or
+// "variables.files.1" in the operations document. Paths into a batch start
+// with the index of the operation, as in "0.variables.file".
+func setPath(operations interface{}, path string, batch bool, value interface{}) error {
+	segments := strings.Split(path, ".")
+	variables := 0
+	if batch {
+		variables = 1
+	}
+	if len(segments) < variables+2 || segments[variables] != "variables" {
+		return apperrors.Validation("invalid file map path %q", path)
+	}
+	current := operations
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch node := current.(type) {
+		case map[string]interface{}:
+			if last {
+				node[segment] = value
+				return nil
+			}
+			next, ok := node[segment]
+			if !ok || next == nil {
+				next = make(map[string]interface{})
+				node[segment] = next
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return apperrors.Validation("invalid file map path %q", path)
+			}
+			if last {
+				node[index] = value
+				return nil
+			}
+			current = node[index]
+		default:
+			return apperrors.Validation("invalid file map path %q", path)
+		}
+	}
+	return nil
+}
+
+// uploadSet hands out the file parts of one multipart request. Parts are
+// read straight from the request body; a part that is skipped to reach a
+// later one, or left unfinished when another is opened, is spooled to a
+// temporary file so it can still be read afterwards.
+type uploadSet struct {
+	mu       sync.Mutex
+	reader   *multipart.Reader
+	expected map[string]bool
+	live     string
+	part     *multipart.Part
+	spooled  map[string]*os.File
+	closed   bool
+}
+
+func (s *uploadSet) read(key string, p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+	if file, ok := s.spooled[key]; ok {
+		return file.Read(p)
+	}
+	if s.live != key {
+		if err := s.advance(key); err != nil {
+			return 0, err
+		}
+	}
+	return s.part.Read(p)
+}
+
+// advance moves the multipart reader to the part named key.
+func (s *uploadSet) advance(key string) error {
+	for {
+		if s.live != "" {
+			if err := s.spool(); err != nil {
+				return err
+			}
+		}
+		part, err := s.reader.NextPart()
+		if err == io.EOF {
+			return apperrors.Validation("multipart request is missing file %q", key)
+		}
+		if err != nil {
+			return err
+		}
+		name := part.FormName()
+		if !s.expected[name] || s.spooled[name] != nil {
+			part.Close()
+			continue
+		}
+		s.live, s.part = name, part
+		if name == key {
+			return nil
+		}
+	}
+}
+
+// spool copies what is left of the live part to a temporary file.
+func (s *uploadSet) spool() error {
+	file, err := os.CreateTemp("", "lcp-upload-*")
+	if err != nil {
+		return err
+	}
+	part := s.part
+	s.spooled[s.live] = file
+	s.live, s.part = "", nil
+	if _, err := io.Copy(file, part); err != nil {
+		return err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close removes the spooled files.
+func (s *uploadSet) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, file := range s.spooled {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	return nil
+}
+
+// uploadReader is the Upload value of a file sent as a multipart part.
+type uploadReader struct {
+	set *uploadSet
+	key string
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	return u.set.read(u.key, p)
+}
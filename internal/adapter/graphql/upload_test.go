@@ -0,0 +1,211 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// formPart is one part of a multipart request. Parts other than operations
+// and map are sent as files.
+type formPart struct {
+	name, body string
+}
+
+// postMultipart sends parts as a GraphQL multipart request and returns the
+// response body.
+func postMultipart(t *testing.T, h http.Handler, parts ...formPart) []byte {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		var w io.Writer
+		var err error
+		if part.name == "operations" || part.name == "map" {
+			w, err = writer.CreateFormField(part.name)
+		} else {
+			w, err = writer.CreateFormFile(part.name, part.name+".epub")
+		}
+		if err != nil {
+			t.Fatalf("create part %q: %v", part.name, err)
+		}
+		io.WriteString(w, part.body)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close multipart body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/graphql", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Body.Bytes()
+}
+
+// uploadOperation is the operations part uploading a publication, whose
+// file is mapped to variables.file.
+func uploadOperation(title string) string {
+	return fmt.Sprintf(`{"query": "mutation ($title: String!, $file: Upload!) { uploadPublication(title: $title, file: $file) { title } }", "variables": {"title": %q, "file": null}}`, title)
+}
+
+func TestMultipartUpload(t *testing.T) {
+	tmp := t.TempDir()
+	h, store := newTestHandler(t, Options{MaxBatchSize: 2})
+	t.Setenv("TMPDIR", tmp)
+
+	var resp graphQLResponse
+	body := postMultipart(t, h,
+		formPart{"operations", uploadOperation("Dune")},
+		formPart{"map", `{"0": ["variables.file"]}`},
+		formPart{"0", "dune content"},
+	)
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Errors) != 0 {
+		t.Fatalf("upload answered %s (%v)", body, err)
+	}
+
+	// The first operation reads the second file part, so the first one is
+	// spooled to a temporary file until the second operation reads it.
+	var batch []graphQLResponse
+	body = postMultipart(t, h,
+		formPart{"operations", "[" + uploadOperation("Emma") + "," + uploadOperation("Ivanhoe") + "]"},
+		formPart{"map", `{"a": ["1.variables.file"], "b": ["0.variables.file"]}`},
+		formPart{"a", "ivanhoe content"},
+		formPart{"b", "emma content"},
+	)
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) != 2 {
+		t.Fatalf("batch answered %s (%v), want two responses", body, err)
+	}
+	for i, resp := range batch {
+		if len(resp.Errors) != 0 {
+			t.Fatalf("operation %d failed: %+v", i, resp.Errors)
+		}
+	}
+
+	pubs, err := store.Publications.FindAll(context.Background())
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	contents := make(map[string]string)
+	for _, pub := range pubs {
+		content, err := os.ReadFile(pub.EncryptedPath)
+		if err != nil {
+			t.Fatalf("read the encrypted file of %s: %v", pub.Title, err)
+		}
+		contents[pub.Title] = string(content)
+	}
+	want := map[string]string{"Dune": "dune content", "Emma": "emma content", "Ivanhoe": "ivanhoe content"}
+	if !reflect.DeepEqual(contents, want) {
+		t.Fatalf("encrypted contents = %q, want %q", contents, want)
+	}
+
+	// Neither the spooled part nor the uploaded files are left behind.
+	if entries, err := os.ReadDir(tmp); err != nil || len(entries) != 0 {
+		t.Fatalf("temporary directory holds %v (%v), want nothing", entries, err)
+	}
+}
+
+func TestMultipartUploadErrors(t *testing.T) {
+	operations := uploadOperation("Dune")
+	tests := []struct {
+		name  string
+		parts []formPart
+		want  string
+	}{
+		{
+			name:  "missing map",
+			parts: []formPart{{"operations", operations}},
+			want:  `invalid request body: multipart request is missing the "map" part`,
+		},
+		{
+			name:  "map first",
+			parts: []formPart{{"map", `{}`}, {"operations", operations}},
+			want:  `invalid request body: multipart request must send "operations" before "map"`,
+		},
+		{
+			name:  "invalid operations",
+			parts: []formPart{{"operations", `{"query": `}, {"map", `{}`}},
+			want:  `invalid request body: invalid "operations" part: unexpected EOF`,
+		},
+		{
+			name:  "oversized operations",
+			parts: []formPart{{"operations", `{"query": "` + strings.Repeat(" ", 512) + `"}`}, {"map", `{}`}},
+			want:  `invalid request body: the "operations" part must not exceed 256 bytes`,
+		},
+		{
+			name:  "bad map path",
+			parts: []formPart{{"operations", operations}, {"map", `{"0": ["file"]}`}, {"0", "content"}},
+			want:  `invalid request body: invalid file map path "file"`,
+		},
+		{
+			name:  "missing file",
+			parts: []formPart{{"operations", operations}, {"map", `{"0": ["variables.file"]}`}},
+			want:  `multipart request is missing file "0"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandler(t, Options{MaxBodySize: 256})
+			var resp graphQLResponse
+			body := postMultipart(t, h, tt.parts...)
+			if err := json.Unmarshal(body, &resp); err != nil {
+				t.Fatalf("decode response %s: %v", body, err)
+			}
+			if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, tt.want) {
+				t.Fatalf("errors = %+v, want %q", resp.Errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		path       string
+		batch      bool
+		want       string
+		wantErr    bool
+	}{
+		{name: "variable", operations: `{"variables": {"file": null}}`, path: "variables.file", want: `{"variables":{"file":"x"}}`},
+		{name: "missing variables", operations: `{}`, path: "variables.file", want: `{"variables":{"file":"x"}}`},
+		{name: "list item", operations: `{"variables": {"files": [null, null]}}`, path: "variables.files.1", want: `{"variables":{"files":[null,"x"]}}`},
+		{name: "batch", operations: `[{}, {"variables": {}}]`, path: "1.variables.file", batch: true, want: `[{},{"variables":{"file":"x"}}]`},
+		{name: "outside variables", operations: `{"query": ""}`, path: "query", wantErr: true},
+		{name: "variables itself", operations: `{"variables": {}}`, path: "variables", wantErr: true},
+		{name: "batch without index", operations: `[{}]`, path: "variables.file", batch: true, wantErr: true},
+		{name: "operation out of range", operations: `[{}]`, path: "1.variables.file", batch: true, wantErr: true},
+		{name: "item out of range", operations: `{"variables": {"files": [null]}}`, path: "variables.files.1", wantErr: true},
+		{name: "item not a number", operations: `{"variables": {"files": [null]}}`, path: "variables.files.first", wantErr: true},
+		{name: "through a scalar", operations: `{"variables": {"file": "name"}}`, path: "variables.file.name", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var operations interface{}
+			if err := json.Unmarshal([]byte(tt.operations), &operations); err != nil {
+				t.Fatalf("decode operations: %v", err)
+			}
+			err := setPath(operations, tt.path, tt.batch, "x")
+			if tt.wantErr {
+				if err == nil || err.Error() != `invalid file map path "`+tt.path+`"` {
+					t.Fatalf("setPath(%q) = %v, want an invalid path error", tt.path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setPath(%q): %v", tt.path, err)
+			}
+			got, _ := json.Marshal(operations)
+			if string(got) != tt.want {
+				t.Fatalf("operations = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+#!/bin/sh
+# Downloads the GraphiQL bundle served by the explorer, and the licences it is
+# distributed under, into graphiql/, where they are embedded into the binary.
+# Run it through go generate and commit the files; bump the versions here to
+# upgrade.
+set -eu
+
+GRAPHIQL_VERSION=3.7.1
+REACT_VERSION=18.3.1
+CDN=https://unpkg.com
+
+cd "$(dirname "$0")/graphiql"
+fetch() {
+	curl -fsSL -o "$1" "$2"
+}
+fetch react.production.min.js "$CDN/react@$REACT_VERSION/umd/react.production.min.js"
+fetch react-dom.production.min.js "$CDN/react-dom@$REACT_VERSION/umd/react-dom.production.min.js"
+fetch graphiql.min.js "$CDN/graphiql@$GRAPHIQL_VERSION/graphiql.min.js"
+fetch graphiql.min.css "$CDN/graphiql@$GRAPHIQL_VERSION/graphiql.min.css"
+fetch LICENSE.react "$CDN/react@$REACT_VERSION/LICENSE"
+fetch LICENSE.react-dom "$CDN/react-dom@$REACT_VERSION/LICENSE"
+fetch LICENSE.graphiql "$CDN/graphiql@$GRAPHIQL_VERSION/LICENSE"
@@ -0,0 +1,248 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+	"github.com/Mehrbod2002/lcp/internal/pkg/gql"
+	"github.com/Mehrbod2002/lcp/internal/pkg/websocket"
+)
+
+// graphql-transport-ws, see
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const (
+	transportWSProtocol = "graphql-transport-ws"
+
+	wsConnectionInit = "connection_init"
+	wsConnectionAck  = "connection_ack"
+	wsPing           = "ping"
+	wsPong           = "pong"
+	wsSubscribe      = "subscribe"
+	wsNext           = "next"
+	wsError          = "error"
+	wsComplete       = "complete"
+)
+
+// connectionInitWait bounds the time between the opening of a connection and
+// its connection_init message.
+var connectionInitWait = 10 * time.Second
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	wsCloseBadRequest       = 4400
+	wsCloseUnauthorized     = 4401
+	wsCloseBadProtocol      = 4406
+	wsCloseInitTimeout      = 4408
+	wsCloseDuplicateID      = 4409
+	wsCloseTooManyInitCalls = 4429
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsSession is one graphql-transport-ws connection and its operations.
+type wsSession struct {
+	conn    *websocket.Conn
+	handler *handler
+	ctx     context.Context
+
+	mu         sync.Mutex
+	initCalled bool
+	acked      bool
+	operations map[string]*wsOperation
+	wg         sync.WaitGroup
+}
+
+// wsOperation is an operation started by a subscribe message.
+type wsOperation struct {
+	cancel context.CancelFunc
+}
+
+// serveWebSocket upgrades the request and runs the graphql-transport-ws
+// protocol until the client disconnects.
+func serveWebSocket(w http.ResponseWriter, r *http.Request, h *handler) {
+	conn, err := websocket.Upgrade(w, r, websocket.Options{
+		Protocols:      []string{transportWSProtocol},
+		AllowedOrigins: h.allowedOrigins,
+	})
+	if err != nil {
+		return
+	}
+	if conn.Subprotocol() != transportWSProtocol {
+		_ = conn.CloseWith(wsCloseBadProtocol, "Subprotocol not acceptable")
+		return
+	}
+
+	// The request context is cancelled once the handler returns, which it
+	// does only when the connection is done.
+	ctx, cancel := context.WithCancel(r.Context())
+	s := &wsSession{conn: conn, handler: h, ctx: ctx, operations: make(map[string]*wsOperation)}
+	defer func() {
+		cancel()
+		s.wg.Wait()
+		conn.Close()
+	}()
+
+	initTimer := time.AfterFunc(connectionInitWait, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.acked {
+			_ = conn.CloseWith(wsCloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg wsMessage
+		if messageType != websocket.TextMessage || json.Unmarshal(data, &msg) != nil || msg.Type == "" {
+			_ = conn.CloseWith(wsCloseBadRequest, "Invalid message received")
+			return
+		}
+		if !s.handle(msg) {
+			return
+		}
+	}
+}
+
+// handle processes one client message and reports whether the connection
+// stays open.
+func (s *wsSession) handle(msg wsMessage) bool {
+	switch msg.Type {
+	case wsConnectionInit:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.initCalled {
+			_ = s.conn.CloseWith(wsCloseTooManyInitCalls, "Too many initialisation requests")
+			return false
+		}
+		s.initCalled = true
+		s.acked = true
+		return s.send(wsMessage{Type: wsConnectionAck}) == nil
+	case wsPing:
+		return s.send(wsMessage{Type: wsPong}) == nil
+	case wsPong:
+		return true
+	case wsSubscribe:
+		return s.subscribe(msg)
+	case wsComplete:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if op, ok := s.operations[msg.ID]; ok {
+			op.cancel()
+			delete(s.operations, msg.ID)
+		}
+		return true
+	default:
+		_ = s.conn.CloseWith(wsCloseBadRequest, "Invalid message received")
+		return false
+	}
+}
+
+func (s *wsSession) subscribe(msg wsMessage) bool {
+	var payload GraphQLPayload
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil {
+		_ = s.conn.CloseWith(wsCloseBadRequest, "Invalid message received")
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.acked {
+		_ = s.conn.CloseWith(wsCloseUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, exists := s.operations[msg.ID]; exists {
+		_ = s.conn.CloseWith(wsCloseDuplicateID, "Subscriber for "+msg.ID+" already exists")
+		return false
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	op := &wsOperation{cancel: cancel}
+	s.operations[msg.ID] = op
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.finish(msg.ID, op)
+		s.run(ctx, msg.ID, &payload)
+	}()
+	return true
+}
+
+// run executes one operation. Queries and mutations produce a single result;
+// subscriptions one result per event until the stream ends or the client
+// completes the operation.
+func (s *wsSession) run(ctx context.Context, id string, payload *GraphQLPayload) {
+	doc, errs := s.handler.documents.resolve(payload)
+	if errs != nil {
+		s.sendPayload(id, wsError, errs)
+		return
+	}
+	op, opErr := gql.SelectOperation(doc, payload.OperationName)
+	if opErr != nil {
+		s.sendPayload(id, wsError, withCode(gql.Errors{opErr}, string(apperrors.KindValidation)))
+		return
+	}
+
+	if op.Type != gql.Subscription {
+		s.sendPayload(id, wsNext, s.handler.execute(ctx, payload, false))
+		s.complete(ctx, id)
+		return
+	}
+
+	results, errResp := s.handler.executor.Subscribe(ctx, doc, payload.OperationName, payload.Variables)
+	if errResp != nil {
+		s.sendPayload(id, wsError, withCode(errResp.Errors, string(apperrors.KindValidation)))
+		return
+	}
+	for resp := range results {
+		resp.Errors = withCode(resp.Errors, string(apperrors.KindValidation))
+		if s.sendPayload(id, wsNext, resp) != nil {
+			return
+		}
+	}
+	s.complete(ctx, id)
+}
+
+// complete tells the client an operation is done, unless the client ended it.
+func (s *wsSession) complete(ctx context.Context, id string) {
+	if ctx.Err() == nil {
+		_ = s.send(wsMessage{ID: id, Type: wsComplete})
+	}
+}
+
+// finish releases a finished operation, unless the client already reused
+// its id for a new one.
+func (s *wsSession) finish(id string, op *wsOperation) {
+	op.cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.operations[id] == op {
+		delete(s.operations, id)
+	}
+}
+
+func (s *wsSession) sendPayload(id, messageType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.send(wsMessage{ID: id, Type: messageType, Payload: data})
+}
+
+func (s *wsSession) send(msg wsMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
@@ -0,0 +1,300 @@
+package graphql
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// wsClient speaks graphql-transport-ws to a test server. Frames are read in
+// the background and sent to frames.
+type wsClient struct {
+	t      *testing.T
+	conn   net.Conn
+	frames chan wsFrame
+}
+
+// wsFrame is a text or close frame received from the server.
+type wsFrame struct {
+	message wsMessage
+	// closeCode is set on close frames.
+	closeCode int
+}
+
+// dialWS opens a WebSocket to the handler requesting protocol, which may be
+// empty.
+func dialWS(t *testing.T, h http.Handler, protocol string) *wsClient {
+	t.Helper()
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	request := "GET /graphql HTTP/1.1\r\nHost: " + server.Listener.Addr().String() +
+		"\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+	if protocol != "" {
+		request += "Sec-WebSocket-Protocol: " + protocol + "\r\n"
+	}
+	if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake answered %v (%v), want 101", resp, err)
+	}
+
+	c := &wsClient{t: t, conn: conn, frames: make(chan wsFrame, 16)}
+	go c.readFrames(br)
+	return c
+}
+
+func (c *wsClient) readFrames(br *bufio.Reader) {
+	defer close(c.frames)
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			return
+		}
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(br, ext[:]); err != nil {
+				return
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(br, ext[:]); err != nil {
+				return
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return
+		}
+		var frame wsFrame
+		if header[0]&0x0f == 8 {
+			frame.closeCode = int(binary.BigEndian.Uint16(payload))
+		} else if err := json.Unmarshal(payload, &frame.message); err != nil {
+			return
+		}
+		c.frames <- frame
+	}
+}
+
+// send writes a masked text frame holding msg.
+func (c *wsClient) send(msg string) {
+	c.t.Helper()
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x81, 0x80}
+	if len(msg) <= 125 {
+		frame[1] |= byte(len(msg))
+	} else {
+		frame[1] |= 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(msg)))
+	}
+	frame = append(frame, mask...)
+	for i := 0; i < len(msg); i++ {
+		frame = append(frame, msg[i]^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("write frame: %v", err)
+	}
+}
+
+// next returns the next frame, failing when none arrives within a second.
+func (c *wsClient) next() wsFrame {
+	c.t.Helper()
+	select {
+	case frame, ok := <-c.frames:
+		if !ok {
+			c.t.Fatal("connection closed without a close frame")
+		}
+		return frame
+	case <-time.After(time.Second):
+		c.t.Fatal("no message received")
+		return wsFrame{}
+	}
+}
+
+// expect returns the next message, which must be of type messageType for the
+// operation id.
+func (c *wsClient) expect(messageType, id string) wsMessage {
+	c.t.Helper()
+	frame := c.next()
+	if frame.closeCode != 0 || frame.message.Type != messageType || frame.message.ID != id {
+		c.t.Fatalf("received %+v, want %s for %q", frame, messageType, id)
+	}
+	return frame.message
+}
+
+// init opens the session.
+func (c *wsClient) init() {
+	c.t.Helper()
+	c.send(`{"type": "connection_init"}`)
+	c.expect(wsConnectionAck, "")
+}
+
+func TestWebSocketQuery(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	c := dialWS(t, h, "graphql-ws, graphql-transport-ws")
+	c.init()
+
+	c.send(`{"type": "ping"}`)
+	c.expect(wsPong, "")
+
+	c.send(`{"id": "1", "type": "subscribe", "payload": {"query": "mutation ($file: Upload!) { uploadPublication(title: \"Dune\", file: $file) { title } }", "variables": {"file": "ZHVuZQ=="}}}`)
+	next := c.expect(wsNext, "1")
+	if string(next.Payload) != `{"data":{"uploadPublication":{"title":"Dune"}}}` {
+		t.Fatalf("next payload = %s", next.Payload)
+	}
+	c.expect(wsComplete, "1")
+
+	// The id is free again once the operation completed.
+	c.send(`{"id": "1", "type": "subscribe", "payload": {"query": "{ publications { title } }"}}`)
+	if next := c.expect(wsNext, "1"); string(next.Payload) != `{"data":{"publications":[{"title":"Dune"}]}}` {
+		t.Fatalf("next payload = %s", next.Payload)
+	}
+	c.expect(wsComplete, "1")
+}
+
+func TestWebSocketSubscription(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	c := dialWS(t, h, "graphql-transport-ws")
+	c.init()
+	c.send(`{"id": "events", "type": "subscribe", "payload": {"query": "subscription { publicationEvents(types: [ENCRYPTED]) { type publication { title } } }"}}`)
+
+	// The subscription starts in the background, so publications are uploaded
+	// until one of them is reported.
+	upload := func(i int) {
+		t.Helper()
+		file := base64.StdEncoding.EncodeToString([]byte("content"))
+		resp := post(t, h, fmt.Sprintf(`{"query": "mutation { uploadPublication(title: \"Book %d\", file: \"%s\") { title } }"}`, i, file))
+		if len(resp.Errors) != 0 {
+			t.Fatalf("upload failed: %+v", resp.Errors)
+		}
+	}
+	var event wsFrame
+	for i := 0; ; i++ {
+		upload(i)
+		select {
+		case event = <-c.frames:
+		case <-time.After(20 * time.Millisecond):
+			if i == 50 {
+				t.Fatal("no event received")
+			}
+			continue
+		}
+		break
+	}
+	var payload struct {
+		Data struct {
+			PublicationEvents struct {
+				Type        string
+				Publication struct{ Title string }
+			}
+		}
+	}
+	if event.message.Type != wsNext || event.message.ID != "events" || json.Unmarshal(event.message.Payload, &payload) != nil {
+		t.Fatalf("received %+v, want the next event", event)
+	}
+	if got := payload.Data.PublicationEvents; got.Type != "ENCRYPTED" || got.Publication.Title == "" {
+		t.Fatalf("event = %+v, want an encrypted publication", got)
+	}
+
+	// Operations the client completes are not completed by the server.
+	c.send(`{"id": "events", "type": "complete"}`)
+	c.send(`{"type": "ping"}`)
+	for frame := c.next(); frame.message.Type != wsPong; frame = c.next() {
+		if frame.message.Type != wsNext {
+			t.Fatalf("received %+v after complete, want the pong", frame)
+		}
+	}
+}
+
+func TestWebSocketOperationErrors(t *testing.T) {
+	h, _ := newTestHandler(t, Options{})
+	c := dialWS(t, h, "graphql-transport-ws")
+	c.init()
+
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{`{"query": "{ publications { title "}`, "GRAPHQL_PARSE_FAILED"},
+		{`{"query": "{ books { title } }"}`, "GRAPHQL_VALIDATION_FAILED"},
+		{`{"query": "query A { publications { title } }", "operationName": "B"}`, "BAD_USER_INPUT"},
+		{`{"query": "subscription { publicationEvents(types: [LOST]) { type } }"}`, "GRAPHQL_VALIDATION_FAILED"},
+	}
+	for i, tt := range tests {
+		id := fmt.Sprint(i)
+		c.send(`{"id": "` + id + `", "type": "subscribe", "payload": ` + tt.payload + `}`)
+		msg := c.expect(wsError, id)
+		var errs []struct {
+			Message    string
+			Extensions map[string]any
+		}
+		if err := json.Unmarshal(msg.Payload, &errs); err != nil || len(errs) == 0 {
+			t.Fatalf("error payload %s (%v), want a list of errors", msg.Payload, err)
+		}
+		if errs[0].Extensions["code"] != tt.want {
+			t.Fatalf("error %+v, want code %s", errs[0], tt.want)
+		}
+	}
+
+	// Errors end the operation without closing the connection.
+	c.send(`{"type": "ping"}`)
+	c.expect(wsPong, "")
+}
+
+func TestWebSocketCloses(t *testing.T) {
+	defer func(wait time.Duration) { connectionInitWait = wait }(connectionInitWait)
+	connectionInitWait = 50 * time.Millisecond
+
+	subscription := `{"id": "1", "type": "subscribe", "payload": {"query": "subscription { publicationEvents { type } }"}}`
+	tests := []struct {
+		name     string
+		protocol string
+		messages []string
+		code     int
+	}{
+		{"no subprotocol", "graphql-ws", nil, wsCloseBadProtocol},
+		{"init timeout", "graphql-transport-ws", nil, wsCloseInitTimeout},
+		{"subscribe before init", "graphql-transport-ws", []string{subscription}, wsCloseUnauthorized},
+		{"second init", "graphql-transport-ws", []string{`{"type": "connection_init"}`, `{"type": "connection_init"}`}, wsCloseTooManyInitCalls},
+		{"invalid JSON", "graphql-transport-ws", []string{`{"type": `}, wsCloseBadRequest},
+		{"unknown type", "graphql-transport-ws", []string{`{"type": "start"}`}, wsCloseBadRequest},
+		{"subscribe without id", "graphql-transport-ws", []string{`{"type": "connection_init"}`, `{"type": "subscribe", "payload": {}}`}, wsCloseBadRequest},
+		{"duplicate id", "graphql-transport-ws", []string{`{"type": "connection_init"}`, subscription, subscription}, wsCloseDuplicateID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandler(t, Options{})
+			c := dialWS(t, h, tt.protocol)
+			for _, msg := range tt.messages {
+				c.send(msg)
+			}
+			frame := c.next()
+			if frame.message.Type == wsConnectionAck {
+				frame = c.next()
+			}
+			if frame.closeCode != tt.code {
+				t.Fatalf("received %+v, want close %d", frame, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Package htpasswd authenticates users against an Apache htpasswd file, as
+// the auth_file setting of the Readium servers does. It verifies the MD5
+// ("$apr1$", the htpasswd default) and SHA-1 ("{SHA}") hashes.
+package htpasswd
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	apr1Prefix = "$apr1$"
+	shaPrefix  = "{SHA}"
+)
+
+// File holds the users of an htpasswd file.
+type File struct {
+	hashes map[string]string
+}
+
+// Load reads the htpasswd file at path. It fails on hashes it cannot
+// verify, such as bcrypt, rather than locking their users out silently.
+func Load(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file := &File{hashes: make(map[string]string)}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(entry, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("htpasswd: %s:%d: expected user:hash", path, line)
+		}
+		if !strings.HasPrefix(hash, apr1Prefix) && !strings.HasPrefix(hash, shaPrefix) {
+			return nil, fmt.Errorf("htpasswd: %s:%d: unsupported hash for user %q, use htpasswd -m or -s", path, line, user)
+		}
+		file.hashes[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Match reports whether password is the password of username.
+func (f *File) Match(username, password string) bool {
+	hash, ok := f.hashes[username]
+	if !ok {
+		return false
+	}
+	var computed string
+	switch {
+	case strings.HasPrefix(hash, apr1Prefix):
+		salt, _, _ := strings.Cut(hash[len(apr1Prefix):], "$")
+		computed = apr1(password, salt)
+	default:
+		sum := sha1.Sum([]byte(password))
+		computed = shaPrefix + base64.StdEncoding.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1
+}
+
+// apr1 is the Apache variant of the MD5-based crypt algorithm.
+func apr1(password, salt string) string {
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw, s := []byte(password), []byte(salt)
+
+	alternate := md5.New()
+	alternate.Write(pw)
+	alternate.Write(s)
+	alternate.Write(pw)
+	alt := alternate.Sum(nil)
+
+	h := md5.New()
+	h.Write(pw)
+	h.Write([]byte(apr1Prefix))
+	h.Write(s)
+	for i := len(pw); i > 0; i -= 16 {
+		h.Write(alt[:min(i, 16)])
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(pw[:1])
+		}
+	}
+	sum := h.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if i&1 != 0 {
+			round.Write(pw)
+		} else {
+			round.Write(sum)
+		}
+		if i%3 != 0 {
+			round.Write(s)
+		}
+		if i%7 != 0 {
+			round.Write(pw)
+		}
+		if i&1 != 0 {
+			round.Write(sum)
+		} else {
+			round.Write(pw)
+		}
+		sum = round.Sum(nil)
+	}
+
+	var out strings.Builder
+	out.WriteString(apr1Prefix + salt + "$")
+	for _, group := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode64(&out, uint(sum[group[0]])<<16|uint(sum[group[1]])<<8|uint(sum[group[2]]), 4)
+	}
+	encode64(&out, uint(sum[11]), 2)
+	return out.String()
+}
+
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func encode64(out *strings.Builder, value uint, chars int) {
+	for ; chars > 0; chars-- {
+		out.WriteByte(cryptAlphabet[value&0x3f])
+		value >>= 6
+	}
+}
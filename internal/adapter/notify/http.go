@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// DefaultTimeout bounds a notification sent without an explicit client.
+const DefaultTimeout = 10 * time.Second
+
+// maxErrorSize bounds the error body read from a failed notification.
+const maxErrorSize = 64 << 10
+
+// Credentials authenticate notifications with HTTP basic auth, like the
+// lcp_update_auth and lsd_notify_auth settings of the Readium servers.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Match reports whether username and password are these credentials. Empty
+// credentials match nothing, which keeps notification endpoints closed until
+// they are configured.
+func (c Credentials) Match(username, password string) bool {
+	if c.Username == "" {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(c.Username))
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(c.Password))
+	return userOK&passOK == 1
+}
+
+// HTTPStatusNotifier is an lcp.StatusNotifier reaching a status server: it
+// sends issued licenses to PUT /licenses and revoked ones to
+// PATCH /licenses/{id}/status.
+type HTTPStatusNotifier struct {
+	client client
+}
+
+// NewHTTPStatusNotifier returns a notifier for the status server at baseURL.
+// A nil httpClient uses one with DefaultTimeout.
+func NewHTTPStatusNotifier(baseURL string, auth Credentials, httpClient *http.Client) *HTTPStatusNotifier {
+	return &HTTPStatusNotifier{client: newClient(baseURL, auth, httpClient)}
+}
+
+func (n *HTTPStatusNotifier) LicenseIssued(ctx context.Context, license *lcp.License) error {
+	return n.client.send(ctx, http.MethodPut, "/licenses", license)
+}
+
+func (n *HTTPStatusNotifier) LicenseRevoked(ctx context.Context, license *lcp.License) error {
+	return n.client.send(ctx, http.MethodPatch, "/licenses/"+url.PathEscape(license.ID)+"/status", license)
+}
+
+// HTTPLicenseUpdater is an lcp.LicenseUpdater reaching a license server
+// through PATCH /licenses/{id}.
+type HTTPLicenseUpdater struct {
+	client client
+}
+
+// NewHTTPLicenseUpdater returns an updater for the license server at
+// baseURL. A nil httpClient uses one with DefaultTimeout.
+func NewHTTPLicenseUpdater(baseURL string, auth Credentials, httpClient *http.Client) *HTTPLicenseUpdater {
+	return &HTTPLicenseUpdater{client: newClient(baseURL, auth, httpClient)}
+}
+
+func (u *HTTPLicenseUpdater) UpdateLicense(ctx context.Context, update *lcp.LicenseUpdate) error {
+	return u.client.send(ctx, http.MethodPatch, "/licenses/"+url.PathEscape(update.LicenseID), update)
+}
+
+type client struct {
+	baseURL string
+	auth    Credentials
+	http    *http.Client
+}
+
+func newClient(baseURL string, auth Credentials, httpClient *http.Client) client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	return client{baseURL: strings.TrimRight(baseURL, "/"), auth: auth, http: httpClient}
+}
+
+// send delivers body as JSON. Rejections by the other service keep their
+// kind when it is one the caller can act on: an unknown license, a conflict
+// or invalid input.
+func (c client) send(ctx context.Context, method, path string, body any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(c.auth.Username, c.auth.Password)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusMultipleChoices {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var envelope struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, maxErrorSize)).Decode(&envelope)
+	message := envelope.Error
+	if message == "" {
+		message = resp.Status
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return apperrors.NotFound("%s", message)
+	case http.StatusConflict:
+		return apperrors.Conflict("%s", message)
+	case http.StatusBadRequest:
+		return apperrors.Validation("%s", message)
+	default:
+		return fmt.Errorf("notify: %s %s: %s", method, path, message)
+	}
+}
@@ -0,0 +1,55 @@
+// Package notify carries the notifications exchanged by the license and
+// status services: the license service hands issued and revoked licenses to
+// the status service, which hands back the dates changed by renewals and
+// returns. InProcess serves deployments running both roles in one process;
+// the HTTP implementations reach a service running as a separate process.
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+)
+
+var errUnbound = errors.New("notify: in-process notifier is not bound to the services")
+
+// InProcess is an lcp.StatusNotifier and lcp.LicenseUpdater calling the use
+// cases of the other service directly. Each use case depends on the other
+// through it, so it is created first and bound once both exist.
+type InProcess struct {
+	statuses lcp.StatusNotifier
+	licenses lcp.LicenseUpdater
+}
+
+func NewInProcess() *InProcess {
+	return &InProcess{}
+}
+
+// Bind sets the services notifications are delivered to. It must be called
+// before the use cases serve requests.
+func (n *InProcess) Bind(statuses lcp.StatusNotifier, licenses lcp.LicenseUpdater) {
+	n.statuses = statuses
+	n.licenses = licenses
+}
+
+func (n *InProcess) LicenseIssued(ctx context.Context, license *lcp.License) error {
+	if n.statuses == nil {
+		return errUnbound
+	}
+	return n.statuses.LicenseIssued(ctx, license)
+}
+
+func (n *InProcess) LicenseRevoked(ctx context.Context, license *lcp.License) error {
+	if n.statuses == nil {
+		return errUnbound
+	}
+	return n.statuses.LicenseRevoked(ctx, license)
+}
+
+func (n *InProcess) UpdateLicense(ctx context.Context, update *lcp.LicenseUpdate) error {
+	if n.licenses == nil {
+		return errUnbound
+	}
+	return n.licenses.UpdateLicense(ctx, update)
+}
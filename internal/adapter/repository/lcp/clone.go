@@ -0,0 +1,48 @@
+package lcp
+
+import (
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+)
+
+func clonePublication(pub *lcp.Publication) *lcp.Publication {
+	copied := *pub
+	return &copied
+}
+
+func cloneLicense(license *lcp.License) *lcp.License {
+	copied := *license
+	copied.RightPrint = cloneInt(license.RightPrint)
+	copied.RightCopy = cloneInt(license.RightCopy)
+	copied.StartDate = cloneTime(license.StartDate)
+	copied.EndDate = cloneTime(license.EndDate)
+	copied.RevokedAt = cloneTime(license.RevokedAt)
+	return &copied
+}
+
+func cloneLicenseEvent(event *lcp.LicenseEvent) *lcp.LicenseEvent {
+	copied := *event
+	return &copied
+}
+
+func cloneNotification(notification *lcp.Notification) *lcp.Notification {
+	copied := *notification
+	return &copied
+}
+
+func cloneInt(value *int) *int {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+func cloneTime(value *time.Time) *time.Time {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
@@ -0,0 +1,213 @@
+package lcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	journalFile  = "journal.wal"
+	snapshotFile = "snapshot.json"
+	frameHeader  = 8
+)
+
+// journalOp is a single mutation of one table. A nil Row deletes the record.
+type journalOp struct {
+	Table string          `json:"table"`
+	ID    string          `json:"id"`
+	Row   json.RawMessage `json:"row,omitempty"`
+}
+
+// journalEntry groups the operations that must be replayed together.
+type journalEntry struct {
+	Ops []journalOp `json:"ops"`
+}
+
+type snapshotDocument struct {
+	Tables map[string][]json.RawMessage `json:"tables"`
+}
+
+// journal is an append-only write-ahead log paired with a periodic snapshot.
+// Every entry is framed with its length and a CRC32 checksum, so a write torn
+// by a crash is detected on replay and discarded instead of corrupting the
+// store.
+type journal struct {
+	mu   sync.Mutex
+	dir  string
+	lock *os.File
+	file *os.File
+}
+
+func openJournal(dir string) (*journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	lock, err := lockDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	// A leftover temporary snapshot belongs to a snapshot that never
+	// completed; the previous snapshot and the log are still authoritative.
+	if err := os.Remove(filepath.Join(dir, snapshotFile+".tmp")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		lock.Close()
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return &journal{dir: dir, lock: lock, file: file}, nil
+}
+
+// load returns the last snapshot and every complete log entry written after
+// it. A torn tail is truncated so later appends start from a clean frame.
+func (j *journal) load() (*snapshotDocument, []journalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	snapshot := &snapshotDocument{}
+	data, err := os.ReadFile(filepath.Join(j.dir, snapshotFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, nil, err
+	default:
+		if err := json.Unmarshal(data, snapshot); err != nil {
+			return nil, nil, fmt.Errorf("read snapshot: %w", err)
+		}
+	}
+
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	var (
+		entries []journalEntry
+		offset  int64
+		reader  = bufio.NewReader(j.file)
+		header  = make([]byte, frameHeader)
+	)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[:4]))
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			break
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			break
+		}
+		entries = append(entries, entry)
+		offset += int64(frameHeader + len(payload))
+	}
+
+	if err := j.file.Truncate(offset); err != nil {
+		return nil, nil, err
+	}
+	if _, err := j.file.Seek(offset, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	return snapshot, entries, nil
+}
+
+// append durably writes entry to the log before the caller applies it.
+func (j *journal) append(entry journalEntry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, frameHeader+len(payload))
+	binary.BigEndian.PutUint32(frame[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:frameHeader], crc32.ChecksumIEEE(payload))
+	copy(frame[frameHeader:], payload)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return os.ErrClosed
+	}
+	if _, err := j.file.Write(frame); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// snapshot atomically replaces the snapshot file and then empties the log.
+// Entries are idempotent upserts and deletes, so a crash between the rename
+// and the truncation only causes them to be replayed twice.
+func (j *journal) snapshot(doc *snapshotDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return os.ErrClosed
+	}
+
+	tmpPath := filepath.Join(j.dir, snapshotFile+".tmp")
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(j.dir, snapshotFile)); err != nil {
+		return err
+	}
+	if err := syncDir(j.dir); err != nil {
+		return err
+	}
+
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := errors.Join(j.file.Close(), j.lock.Close())
+	j.file = nil
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
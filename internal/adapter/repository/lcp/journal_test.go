@@ -0,0 +1,264 @@
+package lcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+)
+
+// crash releases the journal of store without the final snapshot of Close,
+// leaving its directory as a killed process would.
+func crash(t *testing.T, store *Store) {
+	t.Helper()
+	if err := store.journal.close(); err != nil {
+		t.Fatalf("close journal: %v", err)
+	}
+}
+
+func openTestStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	store, err := OpenStore(StoreOptions{Directory: dir})
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	return store
+}
+
+func savePublications(t *testing.T, store *Store, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		pub := &lcp.Publication{ID: fmt.Sprintf("pub-%d", i), Title: fmt.Sprintf("Title %d", i), CreatedAt: time.Now()}
+		if err := store.Publications.Save(context.Background(), pub); err != nil {
+			t.Fatalf("Save %s: %v", pub.ID, err)
+		}
+	}
+}
+
+// checkPublications fails unless store holds exactly the publications
+// pub-0 to pub-(want-1).
+func checkPublications(t *testing.T, store *Store, want int) {
+	t.Helper()
+	pubs, err := store.Publications.FindAll(context.Background())
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(pubs) != want {
+		t.Fatalf("restored %d publications, want %d", len(pubs), want)
+	}
+	for i, pub := range pubs {
+		if id := fmt.Sprintf("pub-%d", i); pub.ID != id {
+			t.Fatalf("publication %d is %s, want %s", i, pub.ID, id)
+		}
+	}
+}
+
+func TestOpenStoreLocksDirectory(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestStore(t, dir)
+	if _, err := OpenStore(StoreOptions{Directory: dir}); err == nil {
+		t.Fatal("a second OpenStore on the same directory succeeded")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	reopened := openTestStore(t, dir)
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestReplayTruncatesTornTail(t *testing.T) {
+	frame := func(length uint32, payload string) []byte {
+		header := make([]byte, frameHeader)
+		binary.BigEndian.PutUint32(header[:4], length)
+		binary.BigEndian.PutUint32(header[4:], 0xdeadbeef)
+		return append(header, payload...)
+	}
+	tails := map[string][]byte{
+		"short header":  {0, 0, 1},
+		"short payload": frame(512, `{"ops":[{"table":`),
+		"bad checksum":  frame(10, `{"ops":[]}`),
+	}
+	for name, tail := range tails {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			walPath := filepath.Join(dir, journalFile)
+			store := openTestStore(t, dir)
+			savePublications(t, store, 0, 3)
+			crash(t, store)
+			info, err := os.Stat(walPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wal, err := os.OpenFile(walPath, os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := wal.Write(tail); err != nil {
+				t.Fatal(err)
+			}
+			wal.Close()
+
+			store = openTestStore(t, dir)
+			checkPublications(t, store, 3)
+			if truncated, err := os.Stat(walPath); err != nil || truncated.Size() != info.Size() {
+				t.Fatalf("log not truncated to its last complete frame: %v, %v", truncated, err)
+			}
+			// Appends after the truncation must replay too.
+			savePublications(t, store, 3, 5)
+			crash(t, store)
+			store = openTestStore(t, dir)
+			checkPublications(t, store, 5)
+			store.Close()
+		})
+	}
+}
+
+// TestReplayAfterSnapshotRename covers a crash after a snapshot replaced the
+// previous one but before the log was truncated: the log then replays on top
+// of a snapshot that already holds its writes.
+func TestReplayAfterSnapshotRename(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, journalFile)
+	store := openTestStore(t, dir)
+	savePublications(t, store, 0, 3)
+	if err := store.Publications.Delete(ctx, "pub-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	renamed, err := store.Publications.FindByID(ctx, "pub-2")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	renamed.Title = "Renamed"
+	if err := store.Publications.Save(ctx, renamed); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	wal, err := os.ReadFile(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	crash(t, store)
+	if err := os.WriteFile(walPath, wal, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A snapshot that never got renamed is ignored.
+	if err := os.WriteFile(filepath.Join(dir, snapshotFile+".tmp"), []byte(`{"tables":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store = openTestStore(t, dir)
+	defer store.Close()
+	pubs, err := store.Publications.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	var got []string
+	for _, pub := range pubs {
+		got = append(got, pub.ID+"="+pub.Title)
+	}
+	if want := "pub-0=Title 0,pub-2=Renamed"; strings.Join(got, ",") != want {
+		t.Fatalf("restored %v, want %s", got, want)
+	}
+}
+
+func TestCrashDuringAppend(t *testing.T) {
+	testCrash(t, "append", 200)
+}
+
+func TestCrashDuringSnapshot(t *testing.T) {
+	testCrash(t, "snapshot", 40)
+}
+
+// testCrash runs TestCrashHelper in a child process writing to a store, kills
+// it with SIGKILL once it acknowledged acked writes, and checks that every
+// acknowledged write survives without a gap.
+func testCrash(t *testing.T, mode string, acked int) {
+	if testing.Short() {
+		t.Skip("re-executes the test binary")
+	}
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCrashHelper$")
+	cmd.Env = append(os.Environ(), "LCP_CRASH_HELPER="+mode, "LCP_CRASH_DIR="+dir)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	last := -1
+	lines := bufio.NewScanner(stdout)
+	for last+1 < acked && lines.Scan() {
+		if i, err := strconv.Atoi(lines.Text()); err == nil {
+			last = i
+		}
+	}
+	if err := cmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, stdout)
+	_ = cmd.Wait()
+	if last+1 < acked {
+		t.Fatalf("helper stopped after %d writes", last+1)
+	}
+
+	store := openTestStore(t, dir)
+	defer store.Close()
+	pubs, err := store.Publications.FindAll(context.Background())
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(pubs) <= last {
+		t.Fatalf("restored %d publications, the helper acknowledged %d", len(pubs), last+1)
+	}
+	checkPublications(t, store, len(pubs))
+}
+
+// TestCrashHelper is the child process of testCrash. It writes publications
+// until it is killed, printing the index of each acknowledged write, and in
+// snapshot mode compacts the log after every write.
+func TestCrashHelper(t *testing.T) {
+	mode := os.Getenv("LCP_CRASH_HELPER")
+	if mode == "" {
+		return
+	}
+	store, err := OpenStore(StoreOptions{Directory: os.Getenv("LCP_CRASH_DIR")})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// Large records widen the window in which the kill lands mid-write.
+	padding := strings.Repeat("x", 16<<10)
+	for i := 0; ; i++ {
+		pub := &lcp.Publication{ID: fmt.Sprintf("pub-%d", i), Title: padding, CreatedAt: time.Now()}
+		if err := store.Publications.Save(context.Background(), pub); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(i)
+		if mode == "snapshot" {
+			if err := store.Snapshot(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+	}
+}
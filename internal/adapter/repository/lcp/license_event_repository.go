@@ -0,0 +1,45 @@
+package lcp
+
+import (
+	"context"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+)
+
+type LicenseEventRepository interface {
+	Save(ctx context.Context, event *lcp.LicenseEvent) error
+	FindByLicense(ctx context.Context, licenseID string) ([]*lcp.LicenseEvent, error)
+	DeleteByLicense(ctx context.Context, licenseID string) error
+}
+
+const licenseEventsByLicense = "license"
+
+type licenseEventRepository struct {
+	events *table[lcp.LicenseEvent]
+}
+
+func NewLicenseEventRepository() LicenseEventRepository {
+	return &licenseEventRepository{events: newLicenseEventTable()}
+}
+
+func newLicenseEventTable() *table[lcp.LicenseEvent] {
+	return newTable("license_events", func(event *lcp.LicenseEvent) string { return event.ID }, cloneLicenseEvent).
+		withIndex(licenseEventsByLicense, func(event *lcp.LicenseEvent) string { return event.LicenseID })
+}
+
+func (r *licenseEventRepository) Save(ctx context.Context, event *lcp.LicenseEvent) error {
+	return r.events.put(ctx, event)
+}
+
+func (r *licenseEventRepository) FindByLicense(ctx context.Context, licenseID string) ([]*lcp.LicenseEvent, error) {
+	return r.events.lookup(ctx, licenseEventsByLicense, licenseID), nil
+}
+
+func (r *licenseEventRepository) DeleteByLicense(ctx context.Context, licenseID string) error {
+	for _, event := range r.events.lookup(ctx, licenseEventsByLicense, licenseID) {
+		if _, err := r.events.delete(ctx, event.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,41 +2,106 @@ package lcp
 
 import (
 	"context"
-	"sync"
+	"time"
 
 	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
 )
 
 type LicenseRepository interface {
 	Save(ctx context.Context, license *lcp.License) error
+	List(ctx context.Context, page lcp.Page) ([]*lcp.License, error)
+	FindByID(ctx context.Context, id string) (*lcp.License, error)
 	FindByPublication(ctx context.Context, publicationID *string) ([]*lcp.License, error)
+	FindByPublications(ctx context.Context, publicationIDs []string) ([]*lcp.License, error)
+	FindByUser(ctx context.Context, userID string) ([]*lcp.License, error)
+	FindExpired(ctx context.Context, now time.Time, limit int) ([]*lcp.License, error)
+	Delete(ctx context.Context, id string) error
 }
 
+const (
+	licensesByPublication = "publication"
+	licensesByUser        = "user"
+	licensesByEndDate     = "end_date"
+)
+
+// endDateKey sorts as the end dates of the licenses that still grant access,
+// the only ones FindExpired can return.
+func endDateKey(license *lcp.License) string {
+	switch license.CurrentStatus() {
+	case status.StatusReady, status.StatusActive:
+	default:
+		return ""
+	}
+	if license.EndDate == nil {
+		return ""
+	}
+	return license.EndDate.UTC().Format(endDateLayout)
+}
+
+// endDateLayout is fixed width, so that keys sort as the dates they encode.
+const endDateLayout = "2006-01-02T15:04:05.000000000"
+
 type licenseRepository struct {
-	mu       sync.RWMutex
-	licenses []*lcp.License
+	licenses *table[lcp.License]
 }
 
 func NewLicenseRepository() LicenseRepository {
-	return &licenseRepository{}
+	return &licenseRepository{licenses: newLicenseTable()}
+}
+
+func newLicenseTable() *table[lcp.License] {
+	return newTable("licenses", func(license *lcp.License) string { return license.ID }, cloneLicense).
+		withIndex(licensesByPublication, func(license *lcp.License) string { return license.PublicationID }).
+		withIndex(licensesByUser, func(license *lcp.License) string { return license.UserID }).
+		withSortedIndex(licensesByEndDate, endDateKey)
 }
 
 func (r *licenseRepository) Save(ctx context.Context, license *lcp.License) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.licenses = append(r.licenses, license)
-	return nil
+	return r.licenses.put(ctx, license)
+}
+
+func (r *licenseRepository) List(ctx context.Context, page lcp.Page) ([]*lcp.License, error) {
+	return r.licenses.page(ctx, page), nil
+}
+
+func (r *licenseRepository) FindByID(ctx context.Context, id string) (*lcp.License, error) {
+	license, ok := r.licenses.get(ctx, id)
+	if !ok {
+		return nil, apperrors.NotFound("license %s not found", id)
+	}
+	return license, nil
 }
 
 func (r *licenseRepository) FindByPublication(ctx context.Context, publicationID *string) ([]*lcp.License, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	var result []*lcp.License
-	for _, lic := range r.licenses {
-		if publicationID == nil || lic.PublicationID == *publicationID {
-			result = append(result, lic)
-		}
+	if publicationID == nil {
+		return r.licenses.all(ctx), nil
 	}
-	return result, nil
+	return r.licenses.lookup(ctx, licensesByPublication, *publicationID), nil
+}
+
+func (r *licenseRepository) FindByPublications(ctx context.Context, publicationIDs []string) ([]*lcp.License, error) {
+	return r.licenses.lookupMany(ctx, licensesByPublication, publicationIDs), nil
+}
+
+func (r *licenseRepository) FindByUser(ctx context.Context, userID string) ([]*lcp.License, error) {
+	return r.licenses.lookup(ctx, licensesByUser, userID), nil
+}
+
+func (r *licenseRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*lcp.License, error) {
+	return r.licenses.scan(ctx, licensesByEndDate, now.UTC().Format(endDateLayout), func(license *lcp.License) bool {
+		return license.CurrentStatus() != status.StatusExpired && license.StatusAt(now) == status.StatusExpired
+	}, limit), nil
+}
+
+func (r *licenseRepository) Delete(ctx context.Context, id string) error {
+	deleted, err := r.licenses.delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return apperrors.NotFound("license %s not found", id)
+	}
+	return nil
 }
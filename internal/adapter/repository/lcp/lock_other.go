@@ -0,0 +1,11 @@
+//go:build !unix
+
+package lcp
+
+import "os"
+
+// lockDirectory opens dir without locking it: there is no flock outside
+// unix, so the store directory must not be shared by several processes.
+func lockDirectory(dir string) (*os.File, error) {
+	return os.Open(dir)
+}
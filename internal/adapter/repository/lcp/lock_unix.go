@@ -0,0 +1,28 @@
+//go:build unix
+
+package lcp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockDirectory takes an exclusive lock on dir, so that a second process
+// opening the same store fails instead of interleaving its writes with ours.
+// The lock is released when the returned file is closed.
+func lockDirectory(dir string) (*os.File, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(d.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		d.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("store directory %s is in use by another process", dir)
+		}
+		return nil, fmt.Errorf("lock store directory %s: %w", dir, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,45 @@
+package lcp
+
+import (
+	"context"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+type NotificationRepository interface {
+	Save(ctx context.Context, notification *lcp.Notification) error
+	FindPending(ctx context.Context) ([]*lcp.Notification, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type notificationRepository struct {
+	notifications *table[lcp.Notification]
+}
+
+func NewNotificationRepository() NotificationRepository {
+	return &notificationRepository{notifications: newNotificationTable()}
+}
+
+func newNotificationTable() *table[lcp.Notification] {
+	return newTable("notifications", func(notification *lcp.Notification) string { return notification.ID }, cloneNotification)
+}
+
+func (r *notificationRepository) Save(ctx context.Context, notification *lcp.Notification) error {
+	return r.notifications.put(ctx, notification)
+}
+
+func (r *notificationRepository) FindPending(ctx context.Context) ([]*lcp.Notification, error) {
+	return r.notifications.all(ctx), nil
+}
+
+func (r *notificationRepository) Delete(ctx context.Context, id string) error {
+	deleted, err := r.notifications.delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return apperrors.NotFound("notification %s not found", id)
+	}
+	return nil
+}
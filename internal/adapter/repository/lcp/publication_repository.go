@@ -2,50 +2,63 @@ package lcp
 
 import (
 	"context"
-	"sync"
 
 	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
 )
 
 type PublicationRepository interface {
 	Save(ctx context.Context, pub *lcp.Publication) error
 	FindAll(ctx context.Context) ([]*lcp.Publication, error)
+	List(ctx context.Context, page lcp.Page) ([]*lcp.Publication, error)
 	FindByID(ctx context.Context, id string) (*lcp.Publication, error)
+	FindByIDs(ctx context.Context, ids []string) ([]*lcp.Publication, error)
+	Delete(ctx context.Context, id string) error
 }
 
 type publicationRepository struct {
-	mu           sync.RWMutex
-	publications []*lcp.Publication
+	publications *table[lcp.Publication]
 }
 
 func NewPublicationRepository() PublicationRepository {
-	return &publicationRepository{}
+	return &publicationRepository{publications: newPublicationTable()}
+}
+
+func newPublicationTable() *table[lcp.Publication] {
+	return newTable("publications", func(pub *lcp.Publication) string { return pub.ID }, clonePublication)
 }
 
 func (r *publicationRepository) Save(ctx context.Context, pub *lcp.Publication) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.publications = append(r.publications, pub)
-	return nil
+	return r.publications.put(ctx, pub)
 }
 
 func (r *publicationRepository) FindAll(ctx context.Context) ([]*lcp.Publication, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	pubs := make([]*lcp.Publication, len(r.publications))
-	copy(pubs, r.publications)
-	return pubs, nil
+	return r.publications.all(ctx), nil
 }
 
-func (r *publicationRepository) FindByID(ctx context.Context, id string) (*lcp.Publication, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+func (r *publicationRepository) List(ctx context.Context, page lcp.Page) ([]*lcp.Publication, error) {
+	return r.publications.page(ctx, page), nil
+}
 
-	for _, pub := range r.publications {
-		if pub.ID == id {
-			return pub, nil
-		}
+func (r *publicationRepository) FindByID(ctx context.Context, id string) (*lcp.Publication, error) {
+	pub, ok := r.publications.get(ctx, id)
+	if !ok {
+		return nil, apperrors.NotFound("publication %s not found", id)
 	}
+	return pub, nil
+}
+
+func (r *publicationRepository) FindByIDs(ctx context.Context, ids []string) ([]*lcp.Publication, error) {
+	return r.publications.getMany(ctx, ids), nil
+}
 
-	return nil, nil
+func (r *publicationRepository) Delete(ctx context.Context, id string) error {
+	deleted, err := r.publications.delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return apperrors.NotFound("publication %s not found", id)
+	}
+	return nil
 }
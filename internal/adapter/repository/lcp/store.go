@@ -0,0 +1,184 @@
+package lcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StoreOptions configures the durability of a Store.
+type StoreOptions struct {
+	// Directory holds the write-ahead log and snapshot. An empty directory
+	// keeps the store purely in memory.
+	Directory string
+	// SnapshotInterval controls how often the log is compacted into a
+	// snapshot. Zero disables periodic snapshots; one is still taken on Close.
+	SnapshotInterval time.Duration
+}
+
+// storeTable is the part of a table the store needs to journal and snapshot
+// it without knowing its record type.
+type storeTable interface {
+	tableName() string
+	attach(j *journal)
+	replay(op journalOp) error
+	dumpLocked() ([]json.RawMessage, error)
+	applyLocked(id string, row any)
+	lock()
+	unlock()
+	rlock()
+	runlock()
+}
+
+// Store groups the in-memory repositories that share a journal and take part
+// in the same transactions.
+type Store struct {
+	Publications  PublicationRepository
+	Licenses      LicenseRepository
+	LicenseEvents LicenseEventRepository
+	Notifications NotificationRepository
+
+	txMu    sync.Mutex
+	tables  []storeTable
+	journal *journal
+	stop    chan struct{}
+	done    sync.WaitGroup
+}
+
+// NewStore returns a volatile store whose data lives only in memory.
+func NewStore() *Store {
+	store, _ := OpenStore(StoreOptions{})
+	return store
+}
+
+// OpenStore builds the repositories and, when a directory is configured,
+// restores them from the last snapshot and write-ahead log before journaling
+// every further mutation.
+func OpenStore(opts StoreOptions) (*Store, error) {
+	publications := newPublicationTable()
+	licenses := newLicenseTable()
+	licenseEvents := newLicenseEventTable()
+	notifications := newNotificationTable()
+	store := &Store{
+		Publications:  &publicationRepository{publications: publications},
+		Licenses:      &licenseRepository{licenses: licenses},
+		LicenseEvents: &licenseEventRepository{events: licenseEvents},
+		Notifications: &notificationRepository{notifications: notifications},
+		tables:        []storeTable{publications, licenses, licenseEvents, notifications},
+	}
+	publications.owner = store
+	licenses.owner = store
+	licenseEvents.owner = store
+	notifications.owner = store
+	if opts.Directory == "" {
+		return store, nil
+	}
+
+	j, err := openJournal(opts.Directory)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.restore(j); err != nil {
+		j.close()
+		return nil, err
+	}
+	store.journal = j
+	for _, t := range store.tables {
+		t.attach(j)
+	}
+
+	if opts.SnapshotInterval > 0 {
+		store.stop = make(chan struct{})
+		store.done.Add(1)
+		go store.snapshotLoop(opts.SnapshotInterval)
+	}
+	return store, nil
+}
+
+func (s *Store) restore(j *journal) error {
+	snapshot, entries, err := j.load()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]storeTable, len(s.tables))
+	for _, t := range s.tables {
+		byName[t.tableName()] = t
+	}
+
+	for name, rows := range snapshot.Tables {
+		t, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("snapshot references unknown table %q", name)
+		}
+		for _, row := range rows {
+			if err := t.replay(journalOp{Table: name, Row: row}); err != nil {
+				return fmt.Errorf("restore %s: %w", name, err)
+			}
+		}
+	}
+	for _, entry := range entries {
+		for _, op := range entry.Ops {
+			t, ok := byName[op.Table]
+			if !ok {
+				return fmt.Errorf("journal references unknown table %q", op.Table)
+			}
+			if err := t.replay(op); err != nil {
+				return fmt.Errorf("replay %s: %w", op.Table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) snapshotLoop(interval time.Duration) {
+	defer s.done.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// A failed snapshot leaves the log intact, so the next tick retries.
+			_ = s.Snapshot()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Snapshot compacts the write-ahead log into a snapshot of every table. It is
+// a no-op for volatile stores.
+func (s *Store) Snapshot() error {
+	if s.journal == nil {
+		return nil
+	}
+	for _, t := range s.tables {
+		t.rlock()
+		defer t.runlock()
+	}
+
+	doc := &snapshotDocument{Tables: make(map[string][]json.RawMessage, len(s.tables))}
+	for _, t := range s.tables {
+		rows, err := t.dumpLocked()
+		if err != nil {
+			return err
+		}
+		doc.Tables[t.tableName()] = rows
+	}
+	return s.journal.snapshot(doc)
+}
+
+// Close stops the periodic snapshots, takes a final snapshot and releases the
+// journal.
+func (s *Store) Close() error {
+	if s.journal == nil {
+		return nil
+	}
+	if s.stop != nil {
+		close(s.stop)
+		s.done.Wait()
+		s.stop = nil
+	}
+	return errors.Join(s.Snapshot(), s.journal.close())
+}
@@ -0,0 +1,41 @@
+package lcp
+
+import (
+	"testing"
+
+	"github.com/Mehrbod2002/lcp/internal/adapter/repository/repotest"
+)
+
+// TestConformance runs the repository suite, transactions included, against
+// a volatile store and against one journaling to disk.
+func TestConformance(t *testing.T) {
+	backend := func(store *Store) repotest.Backend {
+		return repotest.Backend{
+			Publications:  store.Publications,
+			Licenses:      store.Licenses,
+			LicenseEvents: store.LicenseEvents,
+			Notifications: store.Notifications,
+			Transactor:    store,
+		}
+	}
+
+	t.Run("Volatile", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) repotest.Backend {
+			return backend(NewStore())
+		})
+	})
+	t.Run("Durable", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) repotest.Backend {
+			store, err := OpenStore(StoreOptions{Directory: t.TempDir()})
+			if err != nil {
+				t.Fatalf("OpenStore: %v", err)
+			}
+			t.Cleanup(func() {
+				if err := store.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+			return backend(store)
+		})
+	})
+}
@@ -0,0 +1,520 @@
+package lcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+)
+
+// table is an in-memory collection of records keyed by ID. Records are
+// cloned on the way in and on the way out so callers never share memory with
+// the stored copy, and secondary indexes keep lookups independent of the
+// collection size. When a journal is attached, every mutation is written to
+// it before being applied.
+type table[T any] struct {
+	name    string
+	owner   *Store
+	journal *journal
+	mu      sync.RWMutex
+	rows    map[string]*T
+	order   []string
+	keyOf   func(*T) string
+	clone   func(*T) *T
+	indexes map[string]*tableIndex[T]
+	sorted  map[string]*sortedIndex[T]
+}
+
+// tableIndex maps a secondary key to the IDs of the records carrying it, in
+// insertion order.
+type tableIndex[T any] struct {
+	keyOf func(*T) string
+	ids   map[string][]string
+}
+
+// sortedIndex keeps the IDs of the records in the order of a secondary key,
+// for range scans. Records with an empty key are left out. Records sharing a
+// key are kept in the order they got it.
+type sortedIndex[T any] struct {
+	keyOf   func(*T) string
+	entries []sortedEntry
+}
+
+type sortedEntry struct {
+	key string
+	id  string
+}
+
+func newTable[T any](name string, keyOf func(*T) string, clone func(*T) *T) *table[T] {
+	return &table[T]{
+		name:    name,
+		rows:    make(map[string]*T),
+		keyOf:   keyOf,
+		clone:   clone,
+		indexes: make(map[string]*tableIndex[T]),
+		sorted:  make(map[string]*sortedIndex[T]),
+	}
+}
+
+// withIndex registers a secondary index. It must be called before the table
+// receives any record.
+func (t *table[T]) withIndex(name string, keyOf func(*T) string) *table[T] {
+	t.indexes[name] = &tableIndex[T]{keyOf: keyOf, ids: make(map[string][]string)}
+	return t
+}
+
+// withSortedIndex registers an index ordered by key, scanned by scan. It must
+// be called before the table receives any record.
+func (t *table[T]) withSortedIndex(name string, keyOf func(*T) string) *table[T] {
+	t.sorted[name] = &sortedIndex[T]{keyOf: keyOf}
+	return t
+}
+
+// staged returns the pending writes of the transaction carried by ctx, if
+// that transaction belongs to the store owning this table.
+func (t *table[T]) staged(ctx context.Context) *stagedTable {
+	tx, ok := ctx.Value(txKey{}).(*memoryTx)
+	if !ok || t.owner == nil || tx.store != t.owner {
+		return nil
+	}
+	staged, ok := tx.tables[t.name]
+	if !ok {
+		staged = &stagedTable{rows: make(map[string]any)}
+		tx.tables[t.name] = staged
+	}
+	return staged
+}
+
+func (t *table[T]) put(ctx context.Context, row *T) error {
+	row = t.clone(row)
+
+	if staged := t.staged(ctx); staged != nil {
+		staged.put(t.keyOf(row), row)
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.journal != nil {
+		encoded, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		op := journalOp{Table: t.name, ID: t.keyOf(row), Row: encoded}
+		if err := t.journal.append(journalEntry{Ops: []journalOp{op}}); err != nil {
+			return err
+		}
+	}
+	t.putLocked(row)
+	return nil
+}
+
+// delete removes the record with the given id and reports whether it
+// existed. Inside a transaction the deletion is staged like any other write.
+func (t *table[T]) delete(ctx context.Context, id string) (bool, error) {
+	if staged := t.staged(ctx); staged != nil {
+		if _, ok := t.get(ctx, id); !ok {
+			return false, nil
+		}
+		staged.put(id, nil)
+		return true, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.rows[id]; !ok {
+		return false, nil
+	}
+	if t.journal != nil {
+		op := journalOp{Table: t.name, ID: id}
+		if err := t.journal.append(journalEntry{Ops: []journalOp{op}}); err != nil {
+			return false, err
+		}
+	}
+	t.deleteLocked(id)
+	return true, nil
+}
+
+// putLocked stores row, which must already be owned by the table.
+func (t *table[T]) putLocked(row *T) {
+	id := t.keyOf(row)
+	if previous, ok := t.rows[id]; ok {
+		for _, idx := range t.indexes {
+			idx.remove(idx.keyOf(previous), id)
+		}
+		for _, idx := range t.sorted {
+			idx.remove(idx.keyOf(previous), id)
+		}
+	} else {
+		t.order = append(t.order, id)
+	}
+	t.rows[id] = row
+	for _, idx := range t.indexes {
+		key := idx.keyOf(row)
+		idx.ids[key] = append(idx.ids[key], id)
+	}
+	for _, idx := range t.sorted {
+		idx.insert(idx.keyOf(row), id)
+	}
+}
+
+// deleteLocked removes the record with the given id. Unlike lookups it is
+// linear in the size of the table, as the insertion order is a plain slice
+// that paging windows directly; records are only deleted along with their
+// publication, which is rare, so keeping paging simple wins over an ordered
+// index here.
+func (t *table[T]) deleteLocked(id string) {
+	previous, ok := t.rows[id]
+	if !ok {
+		return
+	}
+	for _, idx := range t.indexes {
+		idx.remove(idx.keyOf(previous), id)
+	}
+	for _, idx := range t.sorted {
+		idx.remove(idx.keyOf(previous), id)
+	}
+	delete(t.rows, id)
+	for i, candidate := range t.order {
+		if candidate == id {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (t *table[T]) get(ctx context.Context, id string) (*T, bool) {
+	if staged := t.staged(ctx); staged != nil {
+		if row, ok := staged.rows[id]; ok {
+			if row == nil {
+				return nil, false
+			}
+			return t.clone(row.(*T)), true
+		}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	row, ok := t.rows[id]
+	if !ok {
+		return nil, false
+	}
+	return t.clone(row), true
+}
+
+// getMany returns the rows with the given ids, in the order of ids. Missing
+// ids are skipped.
+func (t *table[T]) getMany(ctx context.Context, ids []string) []*T {
+	staged := t.staged(ctx)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	result := make([]*T, 0, len(ids))
+	for _, id := range ids {
+		row, ok := t.rows[id]
+		if staged != nil {
+			if pending, isStaged := staged.rows[id]; isStaged {
+				if pending == nil {
+					continue
+				}
+				row, ok = pending.(*T), true
+			}
+		}
+		if ok {
+			result = append(result, t.clone(row))
+		}
+	}
+	return result
+}
+
+func (t *table[T]) all(ctx context.Context) []*T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.collectLocked(t.staged(ctx), t.order, nil)
+}
+
+// page returns the records in the window selected by p. Outside a
+// transaction only the records in the window are cloned.
+func (t *table[T]) page(ctx context.Context, p lcp.Page) []*T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if staged := t.staged(ctx); staged != nil {
+		return window(t.collectLocked(staged, t.order, nil), p)
+	}
+	return t.collectLocked(nil, window(t.order, p), nil)
+}
+
+// filter returns the records for which match holds, in insertion order and
+// at most limit of them unless limit is zero. Only those records are cloned.
+func (t *table[T]) filter(ctx context.Context, match func(*T) bool, limit int) []*T {
+	staged := t.staged(ctx)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var result []*T
+	visit := func(row *T) bool {
+		if match(row) {
+			result = append(result, t.clone(row))
+		}
+		return limit <= 0 || len(result) < limit
+	}
+	for _, id := range t.order {
+		row := t.rows[id]
+		if staged != nil {
+			if pending, ok := staged.rows[id]; ok {
+				if pending == nil {
+					continue
+				}
+				row = pending.(*T)
+			}
+		}
+		if !visit(row) {
+			return result
+		}
+	}
+	if staged == nil {
+		return result
+	}
+	for _, id := range staged.order {
+		pending := staged.rows[id]
+		if _, committed := t.rows[id]; committed || pending == nil {
+			continue
+		}
+		if !visit(pending.(*T)) {
+			return result
+		}
+	}
+	return result
+}
+
+func (t *table[T]) lookup(ctx context.Context, index, key string) []*T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	idx := t.indexes[index]
+	return t.collectLocked(t.staged(ctx), idx.ids[key], func(row *T) bool {
+		return idx.keyOf(row) == key
+	})
+}
+
+// lookupMany returns the rows matching any of keys under one read lock,
+// grouped by key in the order of keys.
+func (t *table[T]) lookupMany(ctx context.Context, index string, keys []string) []*T {
+	staged := t.staged(ctx)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	idx := t.indexes[index]
+	var result []*T
+	for _, key := range keys {
+		result = append(result, t.collectLocked(staged, idx.ids[key], func(row *T) bool {
+			return idx.keyOf(row) == key
+		})...)
+	}
+	return result
+}
+
+// scan returns, in the order of the sorted index, the records whose key is
+// at most until and for which match holds, at most limit of them unless
+// limit is zero. Records written by the transaction carried by ctx are
+// ordered and matched on their staged version. Only the returned records
+// are cloned.
+func (t *table[T]) scan(ctx context.Context, index, until string, match func(*T) bool, limit int) []*T {
+	staged := t.staged(ctx)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	idx := t.sorted[index]
+
+	// The staged records in range take the place of their committed version.
+	var pending []sortedEntry
+	if staged != nil {
+		for _, id := range staged.order {
+			if row, ok := staged.rows[id].(*T); ok && row != nil {
+				if key := idx.keyOf(row); key != "" && key <= until {
+					pending = append(pending, sortedEntry{key: key, id: id})
+				}
+			}
+		}
+		slices.SortStableFunc(pending, func(a, b sortedEntry) int { return strings.Compare(a.key, b.key) })
+	}
+
+	var result []*T
+	add := func(row *T) bool {
+		if match(row) {
+			result = append(result, t.clone(row))
+		}
+		return limit > 0 && len(result) >= limit
+	}
+	for _, entry := range idx.entries {
+		if entry.key > until {
+			break
+		}
+		for len(pending) > 0 && pending[0].key <= entry.key {
+			if add(staged.rows[pending[0].id].(*T)) {
+				return result
+			}
+			pending = pending[1:]
+		}
+		if staged != nil {
+			if _, ok := staged.rows[entry.id]; ok {
+				continue
+			}
+		}
+		if add(t.rows[entry.id]) {
+			return result
+		}
+	}
+	for _, entry := range pending {
+		if add(staged.rows[entry.id].(*T)) {
+			break
+		}
+	}
+	return result
+}
+
+// collectLocked clones the records with the given IDs, overlaying the staged
+// writes of a transaction when there is one. match filters staged records so
+// they are only reported when they belong to the requested index key.
+func (t *table[T]) collectLocked(staged *stagedTable, ids []string, match func(*T) bool) []*T {
+	result := make([]*T, 0, len(ids))
+	for _, id := range ids {
+		row := t.rows[id]
+		if staged != nil {
+			if pending, ok := staged.rows[id]; ok {
+				if pending == nil {
+					continue
+				}
+				row = pending.(*T)
+				if match != nil && !match(row) {
+					continue
+				}
+			}
+		}
+		result = append(result, t.clone(row))
+	}
+	if staged == nil {
+		return result
+	}
+
+	for _, id := range staged.order {
+		pending := staged.rows[id]
+		if _, committed := t.rows[id]; committed || pending == nil {
+			continue
+		}
+		row := pending.(*T)
+		if match == nil || match(row) {
+			result = append(result, t.clone(row))
+		}
+	}
+	return result
+}
+
+func (t *table[T]) tableName() string {
+	return t.name
+}
+
+func (t *table[T]) attach(j *journal) {
+	t.journal = j
+}
+
+// replay applies a journaled operation without journaling it again.
+func (t *table[T]) replay(op journalOp) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if op.Row == nil {
+		t.deleteLocked(op.ID)
+		return nil
+	}
+	row := new(T)
+	if err := json.Unmarshal(op.Row, row); err != nil {
+		return err
+	}
+	t.putLocked(row)
+	return nil
+}
+
+// applyLocked applies a committed transaction write. The caller must hold
+// the write lock.
+func (t *table[T]) applyLocked(id string, row any) {
+	if row == nil {
+		t.deleteLocked(id)
+		return
+	}
+	t.putLocked(row.(*T))
+}
+
+// dumpLocked encodes every record in insertion order. The caller must hold
+// at least the read lock.
+func (t *table[T]) dumpLocked() ([]json.RawMessage, error) {
+	rows := make([]json.RawMessage, 0, len(t.order))
+	for _, id := range t.order {
+		encoded, err := json.Marshal(t.rows[id])
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, encoded)
+	}
+	return rows, nil
+}
+
+func (t *table[T]) lock() {
+	t.mu.Lock()
+}
+
+func (t *table[T]) unlock() {
+	t.mu.Unlock()
+}
+
+func (t *table[T]) rlock() {
+	t.mu.RLock()
+}
+
+func (t *table[T]) runlock() {
+	t.mu.RUnlock()
+}
+
+func window[E any](items []E, p lcp.Page) []E {
+	start := min(max(p.Offset, 0), len(items))
+	end := len(items)
+	if p.Limit > 0 {
+		end = min(start+p.Limit, end)
+	}
+	return items[start:end]
+}
+
+func (idx *tableIndex[T]) remove(key, id string) {
+	ids := idx.ids[key]
+	for i, candidate := range ids {
+		if candidate == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(idx.ids, key)
+		return
+	}
+	idx.ids[key] = ids
+}
+
+func (idx *sortedIndex[T]) insert(key, id string) {
+	if key == "" {
+		return
+	}
+	// After the entries already carrying key, so that they keep their order.
+	i := sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].key > key })
+	idx.entries = slices.Insert(idx.entries, i, sortedEntry{key: key, id: id})
+}
+
+func (idx *sortedIndex[T]) remove(key, id string) {
+	if key == "" {
+		return
+	}
+	i := sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].key >= key })
+	for ; i < len(idx.entries) && idx.entries[i].key == key; i++ {
+		if idx.entries[i].id == id {
+			idx.entries = slices.Delete(idx.entries, i, i+1)
+			return
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package lcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+)
+
+// Sizes of the benchmark data set, matching the load tests that exposed the
+// linear scans.
+const (
+	benchLicenses     = 200_000
+	benchPublications = 2_000
+	benchUsers        = 20_000
+)
+
+// linearLicenses is the slice-backed repository the indexed tables
+// replaced, kept as the baseline of the benchmarks.
+type linearLicenses struct {
+	mu       sync.RWMutex
+	licenses []*lcp.License
+}
+
+func (r *linearLicenses) findByID(id string) *lcp.License {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, license := range r.licenses {
+		if license.ID == id {
+			return license
+		}
+	}
+	return nil
+}
+
+func (r *linearLicenses) findBy(match func(*lcp.License) bool) []*lcp.License {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var result []*lcp.License
+	for _, license := range r.licenses {
+		if match(license) {
+			result = append(result, license)
+		}
+	}
+	return result
+}
+
+type benchData struct {
+	indexed LicenseRepository
+	linear  *linearLicenses
+}
+
+var loadBenchData = sync.OnceValue(func() *benchData {
+	data := &benchData{indexed: NewLicenseRepository(), linear: &linearLicenses{}}
+	ctx := context.Background()
+	for i := 0; i < benchLicenses; i++ {
+		license := benchLicense(i)
+		if err := data.indexed.Save(ctx, license); err != nil {
+			panic(err)
+		}
+		data.linear.licenses = append(data.linear.licenses, license)
+	}
+	return data
+})
+
+func benchLicense(i int) *lcp.License {
+	rightPrint, end := 10, time.Now().Add(24*time.Hour)
+	return &lcp.License{
+		ID:            fmt.Sprintf("license-%d", i),
+		PublicationID: fmt.Sprintf("publication-%d", i%benchPublications),
+		UserID:        fmt.Sprintf("user-%d", i%benchUsers),
+		RightPrint:    &rightPrint,
+		EndDate:       &end,
+		CreatedAt:     time.Now(),
+	}
+}
+
+// benchLicenseID spreads the lookups over the whole data set, so the linear
+// baseline does not only hit the licenses at the front of its slice.
+func benchLicenseID(i int) string {
+	return fmt.Sprintf("license-%d", (i*7919)%benchLicenses)
+}
+
+func BenchmarkFindByID(b *testing.B) {
+	data := loadBenchData()
+	ctx := context.Background()
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := data.indexed.FindByID(ctx, benchLicenseID(i)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if data.linear.findByID(benchLicenseID(i)) == nil {
+				b.Fatal("license not found")
+			}
+		}
+	})
+}
+
+func BenchmarkFindByPublication(b *testing.B) {
+	data := loadBenchData()
+	ctx := context.Background()
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			id := fmt.Sprintf("publication-%d", i%benchPublications)
+			if _, err := data.indexed.FindByPublication(ctx, &id); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			id := fmt.Sprintf("publication-%d", i%benchPublications)
+			data.linear.findBy(func(license *lcp.License) bool { return license.PublicationID == id })
+		}
+	})
+}
+
+func BenchmarkFindByUser(b *testing.B) {
+	data := loadBenchData()
+	ctx := context.Background()
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := data.indexed.FindByUser(ctx, fmt.Sprintf("user-%d", i%benchUsers)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			id := fmt.Sprintf("user-%d", i%benchUsers)
+			data.linear.findBy(func(license *lcp.License) bool { return license.UserID == id })
+		}
+	})
+}
+
+// TestCopyOnRead checks that neither the record handed to Save nor the ones
+// returned by lookups share memory with the stored copy.
+func TestCopyOnRead(t *testing.T) {
+	ctx := context.Background()
+	repo := NewLicenseRepository()
+	saved := benchLicense(1)
+	if err := repo.Save(ctx, saved); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	want := benchLicense(1)
+	want.CreatedAt = saved.CreatedAt
+	*want.EndDate = *saved.EndDate
+
+	mutate := func(license *lcp.License) {
+		license.UserID = "intruder"
+		*license.RightPrint = 0
+		*license.EndDate = time.Time{}
+	}
+	mutate(saved)
+
+	found, err := repo.FindByID(ctx, want.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	mutate(found)
+	byPublication, err := repo.FindByPublication(ctx, &want.PublicationID)
+	if err != nil || len(byPublication) != 1 {
+		t.Fatalf("FindByPublication = %d licenses, %v", len(byPublication), err)
+	}
+	mutate(byPublication[0])
+	listed, err := repo.List(ctx, lcp.Page{})
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("List = %d licenses, %v", len(listed), err)
+	}
+	mutate(listed[0])
+
+	got, err := repo.FindByID(ctx, want.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.UserID != want.UserID || *got.RightPrint != *want.RightPrint || !got.EndDate.Equal(*want.EndDate) {
+		t.Fatalf("stored license changed through a caller's copy: got user %q, print %d, end %v", got.UserID, *got.RightPrint, got.EndDate)
+	}
+	byUser, err := repo.FindByUser(ctx, want.UserID)
+	if err != nil || len(byUser) != 1 {
+		t.Fatalf("FindByUser(%q) = %d licenses, %v; the user index followed a caller's copy", want.UserID, len(byUser), err)
+	}
+}
@@ -0,0 +1,98 @@
+package lcp
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type txKey struct{}
+
+// memoryTx stages the writes of a unit of work until it commits. Reads made
+// inside the transaction see the staged writes on top of the committed data.
+type memoryTx struct {
+	store  *Store
+	tables map[string]*stagedTable
+}
+
+// stagedTable holds the pending rows of one table in write order. A nil row
+// marks a deletion.
+type stagedTable struct {
+	rows  map[string]any
+	order []string
+}
+
+func (staged *stagedTable) put(id string, row any) {
+	if _, seen := staged.rows[id]; !seen {
+		staged.order = append(staged.order, id)
+	}
+	staged.rows[id] = row
+}
+
+// WithinTransaction runs fn as a single atomic unit of work. Transactions are
+// serialized with each other and their writes become visible, and are
+// journaled, all at once when fn returns nil. Calls nested in an open
+// transaction join it. The transaction context must not be shared between
+// goroutines.
+func (s *Store) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*memoryTx); ok && tx.store == s {
+		return fn(ctx)
+	}
+
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	tx := &memoryTx{store: s, tables: make(map[string]*stagedTable)}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	// A caller that gave up must not see its writes applied afterwards.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.commit(tx)
+}
+
+func (s *Store) commit(tx *memoryTx) error {
+	for _, t := range s.tables {
+		t.lock()
+		defer t.unlock()
+	}
+
+	var ops []journalOp
+	for _, t := range s.tables {
+		staged, ok := tx.tables[t.tableName()]
+		if !ok {
+			continue
+		}
+		for _, id := range staged.order {
+			op := journalOp{Table: t.tableName(), ID: id}
+			if row := staged.rows[id]; row != nil {
+				encoded, err := json.Marshal(row)
+				if err != nil {
+					return err
+				}
+				op.Row = encoded
+			}
+			ops = append(ops, op)
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	if s.journal != nil {
+		if err := s.journal.append(journalEntry{Ops: ops}); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range s.tables {
+		staged, ok := tx.tables[t.tableName()]
+		if !ok {
+			continue
+		}
+		for _, id := range staged.order {
+			t.applyLocked(id, staged.rows[id])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,1031 @@
+// Package repotest is a conformance suite for implementations of the LCP
+// domain repositories. Every backend runs it from its own tests so that
+// behavior stays identical across storage engines:
+//
+//	func TestConformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repotest.Backend {
+//			store := lcp.NewStore()
+//			return repotest.Backend{
+//				Publications:  store.Publications,
+//				Licenses:      store.Licenses,
+//				LicenseEvents: store.LicenseEvents,
+//				Notifications: store.Notifications,
+//				Transactor:    store,
+//			}
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// Backend is the set of repositories under test. All of them must share the
+// same underlying storage so that transactions span them.
+type Backend struct {
+	Publications  lcp.PublicationRepository
+	Licenses      lcp.LicenseRepository
+	LicenseEvents lcp.LicenseEventRepository
+	Notifications lcp.NotificationRepository
+	Transactor    lcp.Transactor
+}
+
+// Factory returns a new, empty backend. It is called once per subtest.
+type Factory func(t *testing.T) Backend
+
+// Run executes the whole conformance suite against the backends built by
+// newBackend.
+func Run(t *testing.T, newBackend Factory) {
+	t.Run("Publications", func(t *testing.T) { RunPublications(t, newBackend) })
+	t.Run("Licenses", func(t *testing.T) { RunLicenses(t, newBackend) })
+	t.Run("LicenseEvents", func(t *testing.T) { RunLicenseEvents(t, newBackend) })
+	t.Run("Notifications", func(t *testing.T) { RunNotifications(t, newBackend) })
+	t.Run("Transactions", func(t *testing.T) { RunTransactions(t, newBackend) })
+}
+
+// RunPublications checks the PublicationRepository contract.
+func RunPublications(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		want := newPublication("p1")
+		mustSavePublication(t, repo, want)
+
+		got, err := repo.FindByID(ctx, "p1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertPublication(t, got, want)
+	})
+
+	t.Run("SaveReplacesExisting", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		mustSavePublication(t, repo, newPublication("p1"))
+		updated := newPublication("p1")
+		updated.Title = "Updated"
+		mustSavePublication(t, repo, updated)
+
+		got, err := repo.FindByID(ctx, "p1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertPublication(t, got, updated)
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		if len(all) != 1 {
+			t.Fatalf("FindAll returned %d publications, want 1", len(all))
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		got, err := repo.FindByID(ctx, "missing")
+		assertNotFound(t, got == nil, err)
+	})
+
+	t.Run("FindByIDs", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		for _, id := range []string{"p1", "p2", "p3"} {
+			mustSavePublication(t, repo, newPublication(id))
+		}
+		got, err := repo.FindByIDs(ctx, []string{"p3", "missing", "p1"})
+		if err != nil {
+			t.Fatalf("FindByIDs: %v", err)
+		}
+		assertIDs(t, publicationIDs(got), []string{"p3", "p1"})
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		for _, id := range []string{"p1", "p2", "p3"} {
+			mustSavePublication(t, repo, newPublication(id))
+		}
+		if err := repo.Delete(ctx, "p2"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		got, err := repo.FindByID(ctx, "p2")
+		assertNotFound(t, got == nil, err)
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		assertIDs(t, publicationIDs(all), []string{"p1", "p3"})
+
+		err = repo.Delete(ctx, "p2")
+		assertNotFound(t, true, err)
+	})
+
+	t.Run("FindAllKeepsInsertionOrder", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		ids := []string{"c", "a", "b"}
+		for _, id := range ids {
+			mustSavePublication(t, repo, newPublication(id))
+		}
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		assertIDs(t, publicationIDs(all), ids)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		for i := range 5 {
+			mustSavePublication(t, repo, newPublication(fmt.Sprintf("p%d", i)))
+		}
+		for _, tc := range []struct {
+			page lcp.Page
+			want []string
+		}{
+			{lcp.Page{}, []string{"p0", "p1", "p2", "p3", "p4"}},
+			{lcp.Page{Limit: 2}, []string{"p0", "p1"}},
+			{lcp.Page{Offset: 2, Limit: 2}, []string{"p2", "p3"}},
+			{lcp.Page{Offset: 4, Limit: 2}, []string{"p4"}},
+			{lcp.Page{Offset: 3}, []string{"p3", "p4"}},
+			{lcp.Page{Offset: 10, Limit: 2}, nil},
+		} {
+			got, err := repo.List(ctx, tc.page)
+			if err != nil {
+				t.Fatalf("List(%+v): %v", tc.page, err)
+			}
+			assertIDs(t, publicationIDs(got), tc.want)
+		}
+	})
+
+	t.Run("ReturnedRecordsAreCopies", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		saved := newPublication("p1")
+		mustSavePublication(t, repo, saved)
+		saved.Title = "mutated after save"
+
+		got, err := repo.FindByID(ctx, "p1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Title != "Title p1" {
+			t.Fatalf("stored title changed through the saved pointer: %q", got.Title)
+		}
+		got.Title = "mutated after read"
+		again, err := repo.FindByID(ctx, "p1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if again.Title != "Title p1" {
+			t.Fatalf("stored title changed through the returned pointer: %q", again.Title)
+		}
+	})
+
+	t.Run("ConcurrentWriters", func(t *testing.T) {
+		repo := newBackend(t).Publications
+		const writers, perWriter = 8, 25
+		var wg sync.WaitGroup
+		errs := make(chan error, writers*perWriter)
+		for w := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range perWriter {
+					if err := repo.Save(ctx, newPublication(fmt.Sprintf("w%d-%d", w, i))); err != nil {
+						errs <- err
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("Save: %v", err)
+		}
+
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		if len(all) != writers*perWriter {
+			t.Fatalf("FindAll returned %d publications, want %d", len(all), writers*perWriter)
+		}
+	})
+}
+
+// RunLicenses checks the LicenseRepository contract.
+func RunLicenses(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		want := newLicense("l1", "p1", "u1")
+		mustSaveLicense(t, backend.Licenses, want)
+
+		got, err := backend.Licenses.FindByID(ctx, "l1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertLicense(t, got, want)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newBackend(t).Licenses
+		got, err := repo.FindByID(ctx, "missing")
+		assertNotFound(t, got == nil, err)
+	})
+
+	t.Run("FindByPublicationAndUser", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		mustSavePublication(t, backend.Publications, newPublication("p2"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l1", "p1", "u1"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l2", "p2", "u1"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l3", "p1", "u2"))
+
+		p1 := "p1"
+		byPublication, err := backend.Licenses.FindByPublication(ctx, &p1)
+		if err != nil {
+			t.Fatalf("FindByPublication: %v", err)
+		}
+		assertIDs(t, licenseIDs(byPublication), []string{"l1", "l3"})
+
+		all, err := backend.Licenses.FindByPublication(ctx, nil)
+		if err != nil {
+			t.Fatalf("FindByPublication(nil): %v", err)
+		}
+		assertIDs(t, licenseIDs(all), []string{"l1", "l2", "l3"})
+
+		byUser, err := backend.Licenses.FindByUser(ctx, "u1")
+		if err != nil {
+			t.Fatalf("FindByUser: %v", err)
+		}
+		assertIDs(t, licenseIDs(byUser), []string{"l1", "l2"})
+
+		none, err := backend.Licenses.FindByUser(ctx, "nobody")
+		if err != nil {
+			t.Fatalf("FindByUser: %v", err)
+		}
+		assertIDs(t, licenseIDs(none), nil)
+	})
+
+	t.Run("FindByPublications", func(t *testing.T) {
+		backend := newBackend(t)
+		for _, id := range []string{"p1", "p2", "p3"} {
+			mustSavePublication(t, backend.Publications, newPublication(id))
+		}
+		mustSaveLicense(t, backend.Licenses, newLicense("l1", "p1", "u1"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l2", "p2", "u1"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l3", "p1", "u2"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l4", "p3", "u2"))
+
+		got, err := backend.Licenses.FindByPublications(ctx, []string{"p2", "p1", "none"})
+		if err != nil {
+			t.Fatalf("FindByPublications: %v", err)
+		}
+		assertIDs(t, licenseIDs(got), []string{"l2", "l1", "l3"})
+	})
+
+	t.Run("SaveMovesIndexes", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		mustSavePublication(t, backend.Publications, newPublication("p2"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l1", "p1", "u1"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l1", "p2", "u2"))
+
+		p1, p2 := "p1", "p2"
+		old, err := backend.Licenses.FindByPublication(ctx, &p1)
+		if err != nil {
+			t.Fatalf("FindByPublication: %v", err)
+		}
+		assertIDs(t, licenseIDs(old), nil)
+		moved, err := backend.Licenses.FindByPublication(ctx, &p2)
+		if err != nil {
+			t.Fatalf("FindByPublication: %v", err)
+		}
+		assertIDs(t, licenseIDs(moved), []string{"l1"})
+		byUser, err := backend.Licenses.FindByUser(ctx, "u1")
+		if err != nil {
+			t.Fatalf("FindByUser: %v", err)
+		}
+		assertIDs(t, licenseIDs(byUser), nil)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		for i := range 4 {
+			mustSaveLicense(t, backend.Licenses, newLicense(fmt.Sprintf("l%d", i), "p1", "u1"))
+		}
+		got, err := backend.Licenses.List(ctx, lcp.Page{Offset: 1, Limit: 2})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertIDs(t, licenseIDs(got), []string{"l1", "l2"})
+	})
+
+	t.Run("ReturnedRecordsAreCopies", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		mustSaveLicense(t, backend.Licenses, newLicense("l1", "p1", "u1"))
+
+		got, err := backend.Licenses.FindByID(ctx, "l1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		*got.RightPrint = 999
+		*got.EndDate = got.EndDate.Add(time.Hour)
+
+		again, err := backend.Licenses.FindByID(ctx, "l1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertLicense(t, again, newLicense("l1", "p1", "u1"))
+	})
+
+	t.Run("FindExpired", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		ended := newLicense("ended", "p1", "u1")
+		open := newLicense("open", "p1", "u1")
+		open.EndDate = nil
+		active := newLicense("active", "p1", "u1")
+		active.Status = status.StatusActive
+		activeEnd := ended.EndDate.Add(-time.Hour)
+		active.EndDate = &activeEnd
+		revoked := newLicense("revoked", "p1", "u1")
+		revoked.Status = status.StatusRevoked
+		expired := newLicense("expired", "p1", "u1")
+		expired.Status = status.StatusExpired
+		for _, license := range []*lcp.License{ended, open, active, revoked, expired} {
+			mustSaveLicense(t, backend.Licenses, license)
+		}
+
+		now := ended.EndDate.Add(time.Second)
+		got, err := backend.Licenses.FindExpired(ctx, now, 0)
+		if err != nil {
+			t.Fatalf("FindExpired: %v", err)
+		}
+		assertIDs(t, licenseIDs(got), []string{"active", "ended"})
+
+		limited, err := backend.Licenses.FindExpired(ctx, now, 1)
+		if err != nil {
+			t.Fatalf("FindExpired with limit: %v", err)
+		}
+		assertIDs(t, licenseIDs(limited), []string{"active"})
+
+		early, err := backend.Licenses.FindExpired(ctx, ended.EndDate.Add(-time.Second), 0)
+		if err != nil {
+			t.Fatalf("FindExpired between the end dates: %v", err)
+		}
+		assertIDs(t, licenseIDs(early), []string{"active"})
+
+		before, err := backend.Licenses.FindExpired(ctx, activeEnd.Add(-time.Second), 0)
+		if err != nil {
+			t.Fatalf("FindExpired before the end dates: %v", err)
+		}
+		assertIDs(t, licenseIDs(before), nil)
+	})
+
+	t.Run("FindExpiredFollowsUpdates", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		license := newLicense("l1", "p1", "u1")
+		mustSaveLicense(t, backend.Licenses, license)
+		now := license.EndDate.Add(time.Second)
+		find := func(name string, want []string) {
+			t.Helper()
+			got, err := backend.Licenses.FindExpired(ctx, now, 0)
+			if err != nil {
+				t.Fatalf("FindExpired %s: %v", name, err)
+			}
+			assertIDs(t, licenseIDs(got), want)
+		}
+		find("once ended", []string{"l1"})
+
+		// A renewal moves the license past now, and an expiry takes it out.
+		renewed := license.EndDate.Add(time.Hour)
+		license.EndDate = &renewed
+		mustSaveLicense(t, backend.Licenses, license)
+		find("after a renewal", nil)
+		now = renewed
+		find("once the renewal ended", []string{"l1"})
+
+		// Changes staged by a transaction are seen within it only.
+		other := newLicense("l2", "p1", "u1")
+		err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			license.Status = status.StatusExpired
+			if err := backend.Licenses.Save(ctx, license); err != nil {
+				return err
+			}
+			if err := backend.Licenses.Save(ctx, other); err != nil {
+				return err
+			}
+			got, err := backend.Licenses.FindExpired(ctx, now, 0)
+			if err != nil {
+				return err
+			}
+			assertIDs(t, licenseIDs(got), []string{"l2"})
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithinTransaction: %v", err)
+		}
+		find("once committed", []string{"l2"})
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newBackend(t).Licenses
+		for _, id := range []string{"l1", "l2", "l3"} {
+			mustSaveLicense(t, repo, newLicense(id, "p1", "u1"))
+		}
+		if err := repo.Delete(ctx, "l2"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		got, err := repo.FindByID(ctx, "l2")
+		assertNotFound(t, got == nil, err)
+		p1 := "p1"
+		byPublication, err := repo.FindByPublication(ctx, &p1)
+		if err != nil {
+			t.Fatalf("FindByPublication: %v", err)
+		}
+		assertIDs(t, licenseIDs(byPublication), []string{"l1", "l3"})
+		byUser, err := repo.FindByUser(ctx, "u1")
+		if err != nil {
+			t.Fatalf("FindByUser: %v", err)
+		}
+		assertIDs(t, licenseIDs(byUser), []string{"l1", "l3"})
+
+		err = repo.Delete(ctx, "l2")
+		assertNotFound(t, true, err)
+	})
+
+	t.Run("ConcurrentWriters", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		const writers, perWriter = 8, 25
+		var wg sync.WaitGroup
+		errs := make(chan error, writers*perWriter)
+		for w := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range perWriter {
+					license := newLicense(fmt.Sprintf("w%d-%d", w, i), "p1", fmt.Sprintf("u%d", w))
+					if err := backend.Licenses.Save(ctx, license); err != nil {
+						errs <- err
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("Save: %v", err)
+		}
+
+		p1 := "p1"
+		all, err := backend.Licenses.FindByPublication(ctx, &p1)
+		if err != nil {
+			t.Fatalf("FindByPublication: %v", err)
+		}
+		if len(all) != writers*perWriter {
+			t.Fatalf("FindByPublication returned %d licenses, want %d", len(all), writers*perWriter)
+		}
+		byUser, err := backend.Licenses.FindByUser(ctx, "u0")
+		if err != nil {
+			t.Fatalf("FindByUser: %v", err)
+		}
+		if len(byUser) != perWriter {
+			t.Fatalf("FindByUser returned %d licenses, want %d", len(byUser), perWriter)
+		}
+	})
+}
+
+// RunLicenseEvents checks the LicenseEventRepository contract.
+func RunLicenseEvents(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	t.Run("SaveAndFindByLicense", func(t *testing.T) {
+		repo := newBackend(t).LicenseEvents
+		want := []*lcp.LicenseEvent{
+			newLicenseEvent("e1", "l1", lcp.LicenseEventRegister),
+			newLicenseEvent("e2", "l2", lcp.LicenseEventRegister),
+			newLicenseEvent("e3", "l1", lcp.LicenseEventRenew),
+		}
+		for _, event := range want {
+			if err := repo.Save(ctx, event); err != nil {
+				t.Fatalf("Save event %s: %v", event.ID, err)
+			}
+		}
+
+		got, err := repo.FindByLicense(ctx, "l1")
+		if err != nil {
+			t.Fatalf("FindByLicense: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("FindByLicense returned %d events, want 2", len(got))
+		}
+		assertLicenseEvent(t, got[0], want[0])
+		assertLicenseEvent(t, got[1], want[2])
+
+		none, err := repo.FindByLicense(ctx, "missing")
+		if err != nil {
+			t.Fatalf("FindByLicense(missing): %v", err)
+		}
+		if len(none) != 0 {
+			t.Fatalf("FindByLicense(missing) returned %d events, want none", len(none))
+		}
+	})
+
+	t.Run("DeleteByLicense", func(t *testing.T) {
+		repo := newBackend(t).LicenseEvents
+		for _, event := range []*lcp.LicenseEvent{
+			newLicenseEvent("e1", "l1", lcp.LicenseEventRegister),
+			newLicenseEvent("e2", "l2", lcp.LicenseEventRegister),
+			newLicenseEvent("e3", "l1", lcp.LicenseEventRenew),
+		} {
+			if err := repo.Save(ctx, event); err != nil {
+				t.Fatalf("Save event %s: %v", event.ID, err)
+			}
+		}
+		if err := repo.DeleteByLicense(ctx, "l1"); err != nil {
+			t.Fatalf("DeleteByLicense: %v", err)
+		}
+		if err := repo.DeleteByLicense(ctx, "missing"); err != nil {
+			t.Fatalf("DeleteByLicense(missing): %v", err)
+		}
+
+		deleted, err := repo.FindByLicense(ctx, "l1")
+		if err != nil {
+			t.Fatalf("FindByLicense: %v", err)
+		}
+		if len(deleted) != 0 {
+			t.Fatalf("FindByLicense returned %d events after DeleteByLicense, want none", len(deleted))
+		}
+		kept, err := repo.FindByLicense(ctx, "l2")
+		if err != nil {
+			t.Fatalf("FindByLicense: %v", err)
+		}
+		if len(kept) != 1 {
+			t.Fatalf("FindByLicense returned %d events of another license, want 1", len(kept))
+		}
+	})
+
+	t.Run("ReturnedRecordsAreCopies", func(t *testing.T) {
+		repo := newBackend(t).LicenseEvents
+		if err := repo.Save(ctx, newLicenseEvent("e1", "l1", lcp.LicenseEventRegister)); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		got, err := repo.FindByLicense(ctx, "l1")
+		if err != nil {
+			t.Fatalf("FindByLicense: %v", err)
+		}
+		got[0].DeviceName = "changed"
+
+		again, err := repo.FindByLicense(ctx, "l1")
+		if err != nil {
+			t.Fatalf("FindByLicense: %v", err)
+		}
+		assertLicenseEvent(t, again[0], newLicenseEvent("e1", "l1", lcp.LicenseEventRegister))
+	})
+}
+
+// RunNotifications checks the NotificationRepository contract.
+func RunNotifications(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	t.Run("SaveFindDelete", func(t *testing.T) {
+		repo := newBackend(t).Notifications
+		for _, notification := range []*lcp.Notification{
+			newNotification("n1", lcp.NotificationLicenseIssued, "l1"),
+			newNotification("n2", lcp.NotificationLicenseIssued, "l2"),
+			newNotification("n3", lcp.NotificationLicenseRevoked, "l1"),
+		} {
+			if err := repo.Save(ctx, notification); err != nil {
+				t.Fatalf("Save notification %s: %v", notification.ID, err)
+			}
+		}
+		// A failed delivery saves the notification again, in its place.
+		retried := newNotification("n1", lcp.NotificationLicenseIssued, "l1")
+		retried.Attempts = 1
+		retried.NextAttemptAt = epoch.Add(time.Minute)
+		retried.LastError = "unreachable"
+		if err := repo.Save(ctx, retried); err != nil {
+			t.Fatalf("Save notification n1: %v", err)
+		}
+		if err := repo.Delete(ctx, "n2"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		assertNotFound(t, true, repo.Delete(ctx, "n2"))
+
+		got, err := repo.FindPending(ctx)
+		if err != nil {
+			t.Fatalf("FindPending: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("FindPending returned %d notifications, want 2", len(got))
+		}
+		assertNotification(t, got[0], retried)
+		assertNotification(t, got[1], newNotification("n3", lcp.NotificationLicenseRevoked, "l1"))
+	})
+
+	t.Run("QueuedWithTransaction", func(t *testing.T) {
+		backend := newBackend(t)
+		errRollback := errors.New("rollback")
+		err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := backend.Notifications.Save(ctx, newNotification("n1", lcp.NotificationLicenseIssued, "l1")); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("WithinTransaction returned %v, want %v", err, errRollback)
+		}
+		got, err := backend.Notifications.FindPending(ctx)
+		if err != nil {
+			t.Fatalf("FindPending: %v", err)
+		}
+		if len(got) != 0 {
+			t.Fatalf("FindPending returned %d notifications of a rolled back transaction, want none", len(got))
+		}
+	})
+}
+
+// RunTransactions checks that the Transactor spans both repositories.
+func RunTransactions(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	t.Run("Commit", func(t *testing.T) {
+		backend := newBackend(t)
+		err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := backend.Publications.Save(ctx, newPublication("p1")); err != nil {
+				return err
+			}
+			return backend.Licenses.Save(ctx, newLicense("l1", "p1", "u1"))
+		})
+		if err != nil {
+			t.Fatalf("WithinTransaction: %v", err)
+		}
+		if _, err := backend.Publications.FindByID(ctx, "p1"); err != nil {
+			t.Fatalf("committed publication: %v", err)
+		}
+		if _, err := backend.Licenses.FindByID(ctx, "l1"); err != nil {
+			t.Fatalf("committed license: %v", err)
+		}
+	})
+
+	t.Run("RollbackOnError", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			updated := newPublication("p1")
+			updated.Title = "Uncommitted"
+			if err := backend.Publications.Save(ctx, updated); err != nil {
+				return err
+			}
+			if err := backend.Licenses.Save(ctx, newLicense("l1", "p1", "u1")); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("WithinTransaction returned %v, want %v", err, errRollback)
+		}
+
+		pub, err := backend.Publications.FindByID(ctx, "p1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertPublication(t, pub, newPublication("p1"))
+		license, err := backend.Licenses.FindByID(ctx, "l1")
+		assertNotFound(t, license == nil, err)
+	})
+
+	t.Run("ReadsOwnWrites", func(t *testing.T) {
+		backend := newBackend(t)
+		err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := backend.Publications.Save(ctx, newPublication("p1")); err != nil {
+				return err
+			}
+			if err := backend.Licenses.Save(ctx, newLicense("l1", "p1", "u1")); err != nil {
+				return err
+			}
+			if _, err := backend.Publications.FindByID(ctx, "p1"); err != nil {
+				return fmt.Errorf("publication not visible inside transaction: %w", err)
+			}
+			p1 := "p1"
+			licenses, err := backend.Licenses.FindByPublication(ctx, &p1)
+			if err != nil {
+				return err
+			}
+			if len(licenses) != 1 {
+				return fmt.Errorf("found %d licenses inside transaction, want 1", len(licenses))
+			}
+			pubs, err := backend.Publications.FindByIDs(ctx, []string{"p1"})
+			if err != nil {
+				return err
+			}
+			if len(pubs) != 1 {
+				return fmt.Errorf("found %d publications by id inside transaction, want 1", len(pubs))
+			}
+			licenses, err = backend.Licenses.FindByPublications(ctx, []string{"p1"})
+			if err != nil {
+				return err
+			}
+			if len(licenses) != 1 {
+				return fmt.Errorf("found %d licenses by publications inside transaction, want 1", len(licenses))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithinTransaction: %v", err)
+		}
+	})
+
+	t.Run("DeleteIsStaged", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := backend.Publications.Delete(ctx, "p1"); err != nil {
+				return err
+			}
+			if _, err := backend.Publications.FindByID(ctx, "p1"); !errors.Is(err, apperrors.ErrNotFound) {
+				return fmt.Errorf("deleted publication still visible inside transaction: %v", err)
+			}
+			if _, err := backend.Publications.FindByID(context.Background(), "p1"); err != nil {
+				return fmt.Errorf("deletion visible outside the transaction before commit: %w", err)
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("WithinTransaction returned %v, want %v", err, errRollback)
+		}
+		if _, err := backend.Publications.FindByID(ctx, "p1"); err != nil {
+			t.Fatalf("rolled back deletion was applied: %v", err)
+		}
+
+		err = backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			return backend.Publications.Delete(ctx, "p1")
+		})
+		if err != nil {
+			t.Fatalf("WithinTransaction: %v", err)
+		}
+		pub, err := backend.Publications.FindByID(ctx, "p1")
+		assertNotFound(t, pub == nil, err)
+	})
+
+	t.Run("FindExpiredSeesStagedWrites", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		for _, id := range []string{"l1", "l2"} {
+			mustSaveLicense(t, backend.Licenses, newLicense(id, "p1", "u1"))
+		}
+		now := epoch.Add(60 * 24 * time.Hour)
+		err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			expired := newLicense("l1", "p1", "u1")
+			expired.Status = status.StatusExpired
+			if err := backend.Licenses.Save(ctx, expired); err != nil {
+				return err
+			}
+			earlier := newLicense("l3", "p1", "u1")
+			end := earlier.EndDate.Add(-time.Hour)
+			earlier.EndDate = &end
+			if err := backend.Licenses.Save(ctx, earlier); err != nil {
+				return err
+			}
+			got, err := backend.Licenses.FindExpired(ctx, now, 0)
+			if err != nil {
+				return err
+			}
+			if ids := strings.Join(licenseIDs(got), ","); ids != "l3,l2" {
+				return fmt.Errorf("FindExpired inside transaction = %s, want l3,l2", ids)
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("WithinTransaction returned %v, want %v", err, errRollback)
+		}
+		got, err := backend.Licenses.FindExpired(ctx, now, 0)
+		if err != nil {
+			t.Fatalf("FindExpired: %v", err)
+		}
+		assertIDs(t, licenseIDs(got), []string{"l1", "l2"})
+	})
+
+	t.Run("NestedJoinsOuter", func(t *testing.T) {
+		backend := newBackend(t)
+		err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			err := backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				return backend.Publications.Save(ctx, newPublication("p1"))
+			})
+			if err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("WithinTransaction returned %v, want %v", err, errRollback)
+		}
+		pub, err := backend.Publications.FindByID(ctx, "p1")
+		assertNotFound(t, pub == nil, err)
+	})
+
+	t.Run("ConcurrentTransactions", func(t *testing.T) {
+		backend := newBackend(t)
+		mustSavePublication(t, backend.Publications, newPublication("p1"))
+		const workers = 8
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- backend.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					return backend.Licenses.Save(ctx, newLicense(fmt.Sprintf("l%d", w), "p1", "u1"))
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("WithinTransaction: %v", err)
+			}
+		}
+		all, err := backend.Licenses.FindByUser(ctx, "u1")
+		if err != nil {
+			t.Fatalf("FindByUser: %v", err)
+		}
+		if len(all) != workers {
+			t.Fatalf("FindByUser returned %d licenses, want %d", len(all), workers)
+		}
+	})
+}
+
+var epoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newPublication(id string) *lcp.Publication {
+	return &lcp.Publication{
+		ID:            id,
+		Title:         "Title " + id,
+		FilePath:      "/tmp/" + id,
+		EncryptedPath: "/var/lib/lcp/" + id,
+		Status:        status.StatusActive,
+		CreatedAt:     epoch,
+	}
+}
+
+func newLicense(id, publicationID, userID string) *lcp.License {
+	prints, copies := 10, 2000
+	start, end := epoch, epoch.Add(30*24*time.Hour)
+	return &lcp.License{
+		ID:             id,
+		Provider:       "http://localhost",
+		PublicationID:  publicationID,
+		UserID:         userID,
+		Passphrase:     "secret",
+		Hint:           "hint",
+		PublicationURL: "http://localhost/licenses/" + id + "/publication",
+		RightPrint:     &prints,
+		RightCopy:      &copies,
+		StartDate:      &start,
+		EndDate:        &end,
+		CreatedAt:      epoch,
+		Status:         status.StatusReady,
+		Renewal:        lcp.RenewalPolicy{MaxRenewals: 2, MaxLoanLength: 60 * 24 * time.Hour, DefaultExtension: 7 * 24 * time.Hour},
+	}
+}
+
+func newLicenseEvent(id, licenseID string, eventType lcp.LicenseEventType) *lcp.LicenseEvent {
+	return &lcp.LicenseEvent{
+		ID:         id,
+		LicenseID:  licenseID,
+		Type:       eventType,
+		DeviceID:   "device-" + licenseID,
+		DeviceName: "Reader",
+		Timestamp:  epoch,
+	}
+}
+
+func newNotification(id string, kind lcp.NotificationKind, licenseID string) *lcp.Notification {
+	return &lcp.Notification{ID: id, Kind: kind, LicenseID: licenseID, CreatedAt: epoch, NextAttemptAt: epoch}
+}
+
+func mustSavePublication(t *testing.T, repo lcp.PublicationRepository, pub *lcp.Publication) {
+	t.Helper()
+	if err := repo.Save(context.Background(), pub); err != nil {
+		t.Fatalf("Save publication %s: %v", pub.ID, err)
+	}
+}
+
+func mustSaveLicense(t *testing.T, repo lcp.LicenseRepository, license *lcp.License) {
+	t.Helper()
+	if err := repo.Save(context.Background(), license); err != nil {
+		t.Fatalf("Save license %s: %v", license.ID, err)
+	}
+}
+
+func assertNotFound(t *testing.T, missing bool, err error) {
+	t.Helper()
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("lookup of a missing record returned error %v, want %v", err, apperrors.ErrNotFound)
+	}
+	if !missing {
+		t.Fatal("lookup of a missing record returned a record")
+	}
+}
+
+func assertPublication(t *testing.T, got, want *lcp.Publication) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("publication %s not found", want.ID)
+	}
+	if got.ID != want.ID || got.Title != want.Title || got.FilePath != want.FilePath ||
+		got.EncryptedPath != want.EncryptedPath || got.Status != want.Status || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("publication = %+v, want %+v", got, want)
+	}
+}
+
+func assertLicense(t *testing.T, got, want *lcp.License) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("license %s not found", want.ID)
+	}
+	if got.ID != want.ID || got.Provider != want.Provider || got.PublicationID != want.PublicationID || got.UserID != want.UserID ||
+		got.Passphrase != want.Passphrase || got.Hint != want.Hint || got.PublicationURL != want.PublicationURL ||
+		!equalInt(got.RightPrint, want.RightPrint) || !equalInt(got.RightCopy, want.RightCopy) ||
+		!equalTime(got.StartDate, want.StartDate) || !equalTime(got.EndDate, want.EndDate) ||
+		!got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) ||
+		got.Status != want.Status || !got.StatusUpdatedAt.Equal(want.StatusUpdatedAt) || got.Renewal != want.Renewal ||
+		!equalTime(got.RevokedAt, want.RevokedAt) || got.RevocationReason != want.RevocationReason {
+		t.Fatalf("license = %+v, want %+v", got, want)
+	}
+}
+
+func assertLicenseEvent(t *testing.T, got, want *lcp.LicenseEvent) {
+	t.Helper()
+	if got.ID != want.ID || got.LicenseID != want.LicenseID || got.Type != want.Type ||
+		got.DeviceID != want.DeviceID || got.DeviceName != want.DeviceName || !got.Timestamp.Equal(want.Timestamp) {
+		t.Fatalf("license event = %+v, want %+v", got, want)
+	}
+}
+
+func assertNotification(t *testing.T, got, want *lcp.Notification) {
+	t.Helper()
+	if got.ID != want.ID || got.Kind != want.Kind || got.LicenseID != want.LicenseID || !got.CreatedAt.Equal(want.CreatedAt) ||
+		got.Attempts != want.Attempts || !got.NextAttemptAt.Equal(want.NextAttemptAt) || got.LastError != want.LastError {
+		t.Fatalf("notification = %+v, want %+v", got, want)
+	}
+}
+
+func assertIDs(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("ids = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("ids = %v, want %v", got, want)
+		}
+	}
+}
+
+func publicationIDs(pubs []*lcp.Publication) []string {
+	ids := make([]string, 0, len(pubs))
+	for _, pub := range pubs {
+		ids = append(ids, pub.ID)
+	}
+	return ids
+}
+
+func licenseIDs(licenses []*lcp.License) []string {
+	ids := make([]string, 0, len(licenses))
+	for _, license := range licenses {
+		ids = append(ids, license.ID)
+	}
+	return ids
+}
+
+func equalInt(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,72 @@
+// Package sqltx runs the units of work of SQL repositories, such as those
+// of PostgreSQL and SQLite, in database/sql transactions.
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type txKey struct{}
+
+// Querier is the subset of *sql.DB and *sql.Tx used by SQL repositories, so
+// the same queries run inside or outside a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Transactor implements lcp.Transactor on top of database/sql. It works with
+// any driver supporting serializable isolation, including PostgreSQL and
+// SQLite. PostgreSQL may abort a serializable transaction that conflicts with
+// another one; the error is returned to the caller, which may run the unit of
+// work again. SQLite deployments should set a busy timeout so concurrent
+// transactions wait instead of failing.
+type Transactor struct {
+	db *sql.DB
+}
+
+// New returns a Transactor opening serializable transactions on db, as
+// lcp.Transactor requires.
+func New(db *sql.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+// WithinTransaction begins a transaction, runs fn with a context carrying it
+// and commits when fn returns nil. Errors and panics roll the transaction
+// back. Calls nested in an open transaction join it.
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// Executor returns the transaction carried by ctx, or db when the call is not
+// part of a transaction. SQL repositories use it for every statement.
+func Executor(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,139 @@
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// recordingConnector opens connections logging the transactions they run.
+type recordingConnector struct {
+	log []string
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{log: &c.log}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver { return nil }
+
+type recordingConn struct {
+	log *[]string
+}
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("statements are not supported")
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("Begin called instead of BeginTx")
+}
+
+func (c *recordingConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	*c.log = append(*c.log, "begin "+sql.IsolationLevel(opts.Isolation).String())
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	*c.log = append(*c.log, "commit")
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	*c.log = append(*c.log, "rollback")
+	return nil
+}
+
+func TestWithinTransaction(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name    string
+		fn      func(tr *Transactor, db *sql.DB) func(ctx context.Context) error
+		wantErr error
+		wantLog []string
+	}{
+		{
+			name: "Commit",
+			fn: func(tr *Transactor, db *sql.DB) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					if _, ok := Executor(ctx, db).(*sql.Tx); !ok {
+						return errors.New("Executor did not return the transaction")
+					}
+					return nil
+				}
+			},
+			wantLog: []string{"begin Serializable", "commit"},
+		},
+		{
+			name: "RollbackOnError",
+			fn: func(tr *Transactor, db *sql.DB) func(ctx context.Context) error {
+				return func(ctx context.Context) error { return errFailed }
+			},
+			wantErr: errFailed,
+			wantLog: []string{"begin Serializable", "rollback"},
+		},
+		{
+			name: "NestedJoins",
+			fn: func(tr *Transactor, db *sql.DB) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					outer := Executor(ctx, db)
+					return tr.WithinTransaction(ctx, func(ctx context.Context) error {
+						if Executor(ctx, db) != outer {
+							return errors.New("nested call opened another transaction")
+						}
+						return errFailed
+					})
+				}
+			},
+			wantErr: errFailed,
+			wantLog: []string{"begin Serializable", "rollback"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := &recordingConnector{}
+			db := sql.OpenDB(connector)
+			defer db.Close()
+			tr := New(db)
+
+			err := tr.WithinTransaction(context.Background(), tt.fn(tr, db))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("WithinTransaction = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(connector.log, tt.wantLog) {
+				t.Fatalf("transactions %v, want %v", connector.log, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestWithinTransactionRollsBackOnPanic(t *testing.T) {
+	connector := &recordingConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("recovered %v, want the panic to propagate", p)
+		}
+		if want := []string{"begin Serializable", "rollback"}; !slices.Equal(connector.log, want) {
+			t.Fatalf("transactions %v, want %v", connector.log, want)
+		}
+	}()
+	_ = New(db).WithinTransaction(context.Background(), func(context.Context) error {
+		panic("boom")
+	})
+}
+
+func TestExecutorOutsideTransaction(t *testing.T) {
+	db := sql.OpenDB(&recordingConnector{})
+	defer db.Close()
+	if got := Executor(context.Background(), db); got != db {
+		t.Fatalf("Executor = %v, want the database", got)
+	}
+}
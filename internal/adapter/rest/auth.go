@@ -0,0 +1,28 @@
+package rest
+
+import (
+	"net/http"
+
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// Authenticator checks the credentials of basic auth requests.
+type Authenticator interface {
+	Match(username, password string) bool
+}
+
+// BasicAuth returns middleware admitting the requests whose basic auth
+// credentials authenticator accepts.
+func BasicAuth(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok || !authenticator.Match(username, password) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="lcp"`)
+				writeError(w, apperrors.Unauthorized("valid credentials are required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type staticUser struct{ username, password string }
+
+func (u staticUser) Match(username, password string) bool {
+	return username == u.username && password == u.password
+}
+
+// TestProtected checks which roles serve routes that need the management
+// middleware, which the server refuses to start without.
+func TestProtected(t *testing.T) {
+	for role, want := range map[Role]bool{RoleFrontend: true, RoleLCP: true, RoleLSD: false} {
+		h, err := NewHandler(Services{}, Options{Roles: []Role{role}})
+		if err != nil {
+			t.Fatalf("NewHandler(%s): %v", role, err)
+		}
+		if got := h.Protected(); got != want {
+			t.Errorf("role %s: Protected() = %v, want %v", role, got, want)
+		}
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	handler := BasicAuth(staticUser{"admin", "secret"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for _, tc := range []struct {
+		name               string
+		username, password string
+		want               int
+	}{
+		{name: "none", want: http.StatusUnauthorized},
+		{name: "wrong password", username: "admin", password: "guess", want: http.StatusUnauthorized},
+		{name: "valid", username: "admin", password: "secret", want: http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/publications", nil)
+		if tc.username != "" {
+			req.SetBasicAuth(tc.username, tc.password)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 without a WWW-Authenticate challenge", tc.name)
+		}
+	}
+}
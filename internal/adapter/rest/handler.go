@@ -0,0 +1,311 @@
+// Package rest serves the publication catalog as a JSON API for partners
+// that do not use GraphQL, along with a Readium lcpserver compatible license
+// API. It shares the use cases with the GraphQL endpoint, so both expose the
+// same rules.
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Mehrbod2002/lcp/internal/adapter/notify"
+	usecaseLicense "github.com/Mehrbod2002/lcp/internal/usecase/lcp/license"
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/outbox"
+	usecasePublication "github.com/Mehrbod2002/lcp/internal/usecase/lcp/publication"
+	usecaseStatus "github.com/Mehrbod2002/lcp/internal/usecase/lcp/status"
+)
+
+// Services are the use cases behind the REST routes.
+type Services struct {
+	Publications usecasePublication.PublicationUsecase
+	Licenses     usecaseLicense.LicenseUsecase
+	Statuses     usecaseStatus.StatusUsecase
+	// Expiry reports the license expiry sweeps in the health check. It may
+	// be nil.
+	Expiry *usecaseStatus.Sweeper
+	// Notifications reports the deliveries of the outbox in the health
+	// check. It may be nil.
+	Notifications *outbox.Dispatcher
+}
+
+// Role names a server of the Readium architecture. Each serves its own
+// routes, so roles can run on separate listeners.
+type Role string
+
+const (
+	// RoleLCP serves the lcpserver API: licenses and the encrypted
+	// publications they lead to.
+	RoleLCP Role = "lcp"
+	// RoleLSD serves License Status Documents and device interactions.
+	RoleLSD Role = "lsd"
+	// RoleFrontend serves the publication catalog.
+	RoleFrontend Role = "frontend"
+)
+
+// Options configures a Handler.
+type Options struct {
+	// Roles selects the routes served. None serves every role.
+	Roles []Role
+	// PublicBaseURL is the base URL of the handler, prefixing the links to
+	// its own routes such as publication downloads.
+	PublicBaseURL string
+	// LicenseBaseURL and StatusBaseURL prefix the links to licenses and to
+	// status documents. They default to PublicBaseURL.
+	LicenseBaseURL string
+	StatusBaseURL  string
+	// StatusNotifyAuth authenticates the license server notifying issued
+	// and revoked licenses, and LicenseUpdateAuth the status server updating
+	// license dates. Routes whose credentials are unset reject every call.
+	StatusNotifyAuth  notify.Credentials
+	LicenseUpdateAuth notify.Credentials
+}
+
+// Handler serves the REST routes.
+type Handler struct {
+	publications      usecasePublication.PublicationUsecase
+	licenses          usecaseLicense.LicenseUsecase
+	statuses          usecaseStatus.StatusUsecase
+	expiry            *usecaseStatus.Sweeper
+	notifications     *outbox.Dispatcher
+	roles             map[Role]bool
+	baseURL           string
+	licenseBaseURL    string
+	statusBaseURL     string
+	statusNotifyAuth  notify.Credentials
+	licenseUpdateAuth notify.Credentials
+	spec              []byte
+}
+
+// NewHandler returns the REST handler. It fails when a route is not fully
+// documented, as the OpenAPI document is built from the route table.
+func NewHandler(services Services, opts Options) (*Handler, error) {
+	h := &Handler{
+		publications:      services.Publications,
+		licenses:          services.Licenses,
+		statuses:          services.Statuses,
+		expiry:            services.Expiry,
+		notifications:     services.Notifications,
+		roles:             make(map[Role]bool),
+		baseURL:           strings.TrimRight(opts.PublicBaseURL, "/"),
+		licenseBaseURL:    strings.TrimRight(opts.LicenseBaseURL, "/"),
+		statusBaseURL:     strings.TrimRight(opts.StatusBaseURL, "/"),
+		statusNotifyAuth:  opts.StatusNotifyAuth,
+		licenseUpdateAuth: opts.LicenseUpdateAuth,
+	}
+	roles := opts.Roles
+	if len(roles) == 0 {
+		roles = []Role{RoleLCP, RoleLSD, RoleFrontend}
+	}
+	for _, role := range roles {
+		h.roles[role] = true
+	}
+	if h.licenseBaseURL == "" {
+		h.licenseBaseURL = h.baseURL
+	}
+	if h.statusBaseURL == "" {
+		h.statusBaseURL = h.baseURL
+	}
+	routes := h.routes()
+	if err := checkRoutes(routes); err != nil {
+		return nil, err
+	}
+	spec, err := openAPIDocument(routes, h.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	h.spec = spec
+	return h, nil
+}
+
+// route is one endpoint of the API, along with its OpenAPI description.
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	// public routes skip the middleware, as content downloads are
+	// authorized by the license rather than by the caller.
+	public bool
+	// auth routes receive notifications from another server, authenticated
+	// with these credentials instead of the middleware.
+	auth Authenticator
+
+	operationID string
+	tag         string
+	summary     string
+	description string
+	// params describes every path parameter, by name.
+	params    map[string]string
+	query     []queryParam
+	body      *content
+	responses []response
+}
+
+var publicationID = map[string]string{"id": "Publication ID."}
+
+// uploadForm is the multipart body of POST /publications.
+var uploadForm = &content{mediaType: "multipart/form-data", schema: schema{
+	"type":     "object",
+	"required": []string{"title", "file"},
+	"properties": map[string]any{
+		"title": map[string]any{"type": "string", "description": "Title of the publication, sent before the file."},
+		"file":  map[string]any{"type": "string", "format": "binary", "description": "The EPUB or PDF to encrypt."},
+	},
+}}
+
+// routes returns the routes of the roles the handler serves. Downloads are
+// served by the catalog and by the license server, which both link to them.
+func (h *Handler) routes() []route {
+	var routes []route
+	if h.roles[RoleFrontend] {
+		routes = append(routes, h.catalogRoutes()...)
+	}
+	if h.roles[RoleFrontend] || h.roles[RoleLCP] {
+		routes = append(routes, h.downloadRoutes()...)
+	}
+	if h.roles[RoleLCP] {
+		routes = append(routes, h.readiumRoutes()...)
+		routes = append(routes, h.licenseNotifyRoutes()...)
+	}
+	if h.roles[RoleLSD] {
+		routes = append(routes, h.statusRoutes()...)
+		routes = append(routes, h.statusNotifyRoutes()...)
+	}
+	return append(routes, h.metaRoutes()...)
+}
+
+func (h *Handler) catalogRoutes() []route {
+	return []route{
+		{
+			method: http.MethodPost, path: "/publications", handler: h.createPublication,
+			operationID: "createPublication", tag: "catalog",
+			summary:     "Upload and encrypt a publication",
+			description: "The title field must precede the file part, which is streamed to the encrypter.",
+			body:        uploadForm,
+			responses: append([]response{
+				{status: http.StatusCreated, description: "The publication was created.", body: jsonBody(publicationResponse{})},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			method: http.MethodGet, path: "/publications", handler: h.listPublications,
+			operationID: "listPublications", tag: "catalog",
+			summary: "List publications",
+			query: []queryParam{
+				{name: "status", description: "Only list publications with this status: active or inactive.", schema: ""},
+				{name: "title", description: "Only list publications whose title contains this text, ignoring case.", schema: ""},
+				{name: "offset", description: "Number of publications to skip.", schema: 0},
+				{name: "limit", description: "Maximum number of publications to return.", schema: 0},
+			},
+			responses: append([]response{
+				{status: http.StatusOK, description: "The matching publications.", body: jsonBody(publicationListResponse{})},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			method: http.MethodGet, path: "/publications/{id}", handler: h.getPublication,
+			operationID: "getPublication", tag: "catalog",
+			summary: "Get a publication",
+			params:  publicationID,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The publication.", body: jsonBody(publicationResponse{})},
+			}, errorResponses(http.StatusNotFound)...),
+		},
+		{
+			method: http.MethodPatch, path: "/publications/{id}", handler: h.updatePublication,
+			operationID: "updatePublication", tag: "catalog",
+			summary: "Update a publication",
+			params:  publicationID,
+			body:    jsonBody(publicationUpdateRequest{}),
+			responses: append([]response{
+				{status: http.StatusOK, description: "The updated publication.", body: jsonBody(publicationResponse{})},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			method: http.MethodDelete, path: "/publications/{id}", handler: h.deletePublication,
+			operationID: "deletePublication", tag: "catalog",
+			summary:     "Delete a publication",
+			description: "Publications with active licenses cannot be deleted.",
+			params:      publicationID,
+			responses: append([]response{
+				{status: http.StatusNoContent, description: "The publication and its files were deleted."},
+			}, errorResponses(http.StatusNotFound, http.StatusConflict)...),
+		},
+		{
+			method: http.MethodPost, path: "/publications/{id}/activate", handler: h.activatePublication,
+			operationID: "activatePublication", tag: "catalog",
+			summary: "Activate a publication",
+			params:  publicationID,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The activated publication.", body: jsonBody(publicationResponse{})},
+			}, errorResponses(http.StatusNotFound)...),
+		},
+		{
+			method: http.MethodPost, path: "/publications/{id}/deactivate", handler: h.deactivatePublication,
+			operationID: "deactivatePublication", tag: "catalog",
+			summary:     "Deactivate a publication",
+			description: "No new license can be issued for an inactive publication.",
+			params:      publicationID,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The deactivated publication.", body: jsonBody(publicationResponse{})},
+			}, errorResponses(http.StatusNotFound)...),
+		},
+	}
+}
+
+func (h *Handler) downloadRoutes() []route {
+	return []route{
+		{
+			method: http.MethodGet, path: "/publications/{id}/content", handler: h.downloadPublication,
+			operationID: "downloadPublication", tag: "download",
+			summary:     "Download the encrypted publication",
+			description: "Reserved to the operators of the catalog. Readers download the publication through the publication link of their license, which stops working once the license ends.",
+			params:      publicationID,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The encrypted file.", body: &content{
+					mediaType: "application/octet-stream",
+					schema:    schema{"type": "string", "format": "binary"},
+				}},
+			}, errorResponses(http.StatusNotFound)...),
+		},
+	}
+}
+
+// metaRoutes describe the server itself and are served by every role.
+func (h *Handler) metaRoutes() []route {
+	return append([]route{
+		{
+			method: http.MethodGet, path: "/openapi.json", handler: h.openAPI, public: true,
+			operationID: "getOpenAPI", tag: "meta",
+			summary: "Get this OpenAPI document",
+			responses: []response{
+				{status: http.StatusOK, description: "The OpenAPI 3 description of the API.", body: jsonBody(schema{"type": "object"})},
+			},
+		},
+	}, h.healthRoutes()...)
+}
+
+// Protected reports whether any route goes through the middleware handed to
+// Register, which must then authenticate the caller.
+func (h *Handler) Protected() bool {
+	for _, rt := range h.routes() {
+		if rt.auth == nil && !rt.public {
+			return true
+		}
+	}
+	return false
+}
+
+// Register adds the routes to mux. Every route but the downloads, the
+// License Status Document interactions, the health check, the OpenAPI
+// document and the notifications goes through middleware, applied in order.
+func (h *Handler) Register(mux *http.ServeMux, middleware ...func(http.Handler) http.Handler) {
+	for _, rt := range h.routes() {
+		var handler http.Handler = rt.handler
+		if rt.auth != nil {
+			handler = BasicAuth(rt.auth)(handler)
+		} else if !rt.public {
+			for i := len(middleware) - 1; i >= 0; i-- {
+				handler = middleware[i](handler)
+			}
+		}
+		mux.Handle(rt.method+" "+rt.path, handler)
+	}
+}
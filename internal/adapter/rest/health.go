@@ -0,0 +1,68 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/outbox"
+	usecaseStatus "github.com/Mehrbod2002/lcp/internal/usecase/lcp/status"
+)
+
+// healthResponse is the body of GET /health.
+type healthResponse struct {
+	// Status is "ok", or "degraded" when the last background job failed or
+	// a notification was rejected.
+	Status        string             `json:"status"`
+	Expiry        expiryHealth       `json:"expiry"`
+	Notifications notificationHealth `json:"notifications"`
+}
+
+// expiryHealth describes the license expiry sweeper.
+type expiryHealth struct {
+	Enabled  bool                    `json:"enabled"`
+	Interval string                  `json:"interval,omitempty"`
+	LastRun  *usecaseStatus.SweepRun `json:"last_run"`
+}
+
+// notificationHealth describes the deliveries of the notification outbox.
+type notificationHealth struct {
+	LastRun *outbox.DeliveryRun `json:"last_run"`
+}
+
+func (h *Handler) healthRoutes() []route {
+	return []route{
+		{
+			method: http.MethodGet, path: "/health", handler: h.health, public: true,
+			operationID: "getHealth", tag: "meta",
+			summary:     "Report the health of the server",
+			description: "Includes the last run of the license expiry sweeper and of the notification outbox.",
+			responses: []response{
+				{status: http.StatusOK, description: "The server is up.", body: jsonBody(healthResponse{})},
+			},
+		},
+	}
+}
+
+func (h *Handler) health(w http.ResponseWriter, r *http.Request) {
+	resp := healthResponse{Status: "ok"}
+	if h.expiry != nil && h.expiry.Interval() > 0 {
+		resp.Expiry.Enabled = true
+		resp.Expiry.Interval = h.expiry.Interval().String()
+	}
+	if h.expiry != nil {
+		if run, ok := h.expiry.LastRun(); ok {
+			resp.Expiry.LastRun = &run
+			if run.Error != "" {
+				resp.Status = "degraded"
+			}
+		}
+	}
+	if h.notifications != nil {
+		if run, ok := h.notifications.LastRun(); ok {
+			resp.Notifications.LastRun = &run
+			if run.Error != "" || run.Retrying > 0 || run.Rejected > 0 {
+				resp.Status = "degraded"
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
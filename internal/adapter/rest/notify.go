@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// statusNotifyRoutes and licenseNotifyRoutes receive the notifications of
+// a license or status server running as a separate process, as sent by the
+// notify package. They are authenticated with the credentials shared with
+// that server.
+func (h *Handler) statusNotifyRoutes() []route {
+	return []route{
+		{
+			method: http.MethodPut, path: "/licenses", handler: h.licenseIssued, auth: h.statusNotifyAuth,
+			operationID: "notifyLicenseIssued", tag: "notify",
+			summary:     "Track the status of an issued license",
+			description: "Sent by the license server for every license it issues. A license already tracked is left unchanged.",
+			body:        jsonBody(lcp.License{}),
+			responses: append([]response{
+				{status: http.StatusNoContent, description: "The license is tracked."},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized)...),
+		},
+		{
+			method: http.MethodPatch, path: "/licenses/{id}/status", handler: h.licenseRevoked, auth: h.statusNotifyAuth,
+			operationID: "notifyLicenseRevoked", tag: "notify",
+			summary:     "Record the revocation of a license",
+			description: "Sent by the license server when it revokes a license. A revocation already recorded changes nothing.",
+			params:      licenseID,
+			body:        jsonBody(lcp.License{}),
+			responses: append([]response{
+				{status: http.StatusNoContent, description: "The revocation is recorded."},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound)...),
+		},
+	}
+}
+
+func (h *Handler) licenseNotifyRoutes() []route {
+	return []route{
+		{
+			method: http.MethodPatch, path: "/licenses/{id}", handler: h.licenseUpdated, auth: h.licenseUpdateAuth,
+			operationID: "notifyLicenseUpdated", tag: "notify",
+			summary:     "Update the dates of a license",
+			description: "Sent by the status server when a loan is renewed or returned. Updates older than the license are ignored.",
+			params:      licenseID,
+			body:        jsonBody(lcp.LicenseUpdate{}),
+			responses: append([]response{
+				{status: http.StatusNoContent, description: "The license is up to date."},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound)...),
+		},
+	}
+}
+
+// Notification bodies are read leniently, so that servers running different
+// versions keep understanding each other.
+
+func (h *Handler) licenseIssued(w http.ResponseWriter, r *http.Request) {
+	var license lcp.License
+	if err := decodeLenientJSON(w, r, &license); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.statuses.LicenseIssued(r.Context(), &license); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) licenseRevoked(w http.ResponseWriter, r *http.Request) {
+	var license lcp.License
+	if err := decodeLenientJSON(w, r, &license); err != nil {
+		writeError(w, err)
+		return
+	}
+	if license.ID != r.PathValue("id") {
+		writeError(w, apperrors.Validation("the license id does not match the path"))
+		return
+	}
+	if err := h.statuses.LicenseRevoked(r.Context(), &license); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) licenseUpdated(w http.ResponseWriter, r *http.Request) {
+	var update lcp.LicenseUpdate
+	if err := decodeLenientJSON(w, r, &update); err != nil {
+		writeError(w, err)
+		return
+	}
+	if update.LicenseID != r.PathValue("id") {
+		writeError(w, apperrors.Validation("the license id does not match the path"))
+		return
+	}
+	if err := h.licenses.UpdateLicense(r.Context(), &update); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,277 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// API metadata published in the OpenAPI document.
+const (
+	apiTitle       = "LCP License Server API"
+	apiVersion     = "1.0"
+	apiDescription = "API for managing LCP licenses and publications."
+)
+
+// queryParam documents a query string parameter of a route.
+type queryParam struct {
+	name        string
+	description string
+	schema      any
+}
+
+// content is a request or response body: its media type and either a Go
+// value whose type describes the JSON document, or a literal schema.
+type content struct {
+	mediaType string
+	schema    any
+}
+
+// response documents one status code a route may answer with.
+type response struct {
+	status      int
+	description string
+	body        *content
+}
+
+// schema is a literal OpenAPI schema object.
+type schema map[string]any
+
+func jsonBody(value any) *content {
+	return &content{mediaType: "application/json", schema: value}
+}
+
+// errorResponses documents the error envelope for the given statuses.
+func errorResponses(statuses ...int) []response {
+	responses := make([]response, 0, len(statuses))
+	for _, status := range statuses {
+		responses = append(responses, response{status: status, description: http.StatusText(status), body: jsonBody(errorResponse{})})
+	}
+	return responses
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// checkRoutes makes sure every route is documented: the OpenAPI document is
+// built from the route table, so an undocumented route would be published
+// with a partial description.
+func checkRoutes(routes []route) error {
+	var problems []string
+	operations := make(map[string]bool)
+	for _, rt := range routes {
+		name := rt.method + " " + rt.path
+		if rt.operationID == "" || operations[rt.operationID] {
+			problems = append(problems, name+" needs a unique operation ID")
+		}
+		operations[rt.operationID] = true
+		if rt.summary == "" {
+			problems = append(problems, name+" has no summary")
+		}
+		if len(rt.responses) == 0 {
+			problems = append(problems, name+" documents no response")
+		}
+		for _, match := range pathParamPattern.FindAllStringSubmatch(rt.path, -1) {
+			if rt.params[match[1]] == "" {
+				problems = append(problems, fmt.Sprintf("%s does not describe the {%s} path parameter", name, match[1]))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("rest: undocumented routes: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// openAPIDocument describes routes as an OpenAPI 3 document.
+func openAPIDocument(routes []route, serverURL string) ([]byte, error) {
+	b := &specBuilder{schemas: make(map[string]any)}
+	paths := make(map[string]map[string]any)
+	for _, rt := range routes {
+		operation := map[string]any{
+			"operationId": rt.operationID,
+			"summary":     rt.summary,
+			"responses":   b.responses(rt.responses),
+		}
+		if rt.description != "" {
+			operation["description"] = rt.description
+		}
+		if rt.tag != "" {
+			operation["tags"] = []string{rt.tag}
+		}
+		if params := b.parameters(rt); len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if rt.body != nil {
+			operation["requestBody"] = map[string]any{"required": true, "content": b.content(rt.body)}
+		}
+		if rt.auth != nil {
+			operation["security"] = []any{map[string]any{"BasicAuth": []string{}}}
+		} else if rt.public {
+			operation["security"] = []any{}
+		}
+		if paths[rt.path] == nil {
+			paths[rt.path] = make(map[string]any)
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = operation
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       apiTitle,
+			"version":     apiVersion,
+			"description": apiDescription,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]any{
+				"BasicAuth": map[string]any{"type": "http", "scheme": "basic"},
+			},
+		},
+		"security": []any{map[string]any{"BasicAuth": []string{}}},
+	}
+	if serverURL != "" {
+		doc["servers"] = []any{map[string]any{"url": serverURL}}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// specBuilder turns Go types into schemas, collecting named structs under
+// components.
+type specBuilder struct {
+	schemas map[string]any
+}
+
+func (b *specBuilder) parameters(rt route) []any {
+	var params []any
+	for _, match := range pathParamPattern.FindAllStringSubmatch(rt.path, -1) {
+		params = append(params, map[string]any{
+			"name":        match[1],
+			"in":          "path",
+			"required":    true,
+			"description": rt.params[match[1]],
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+	for _, q := range rt.query {
+		params = append(params, map[string]any{
+			"name":        q.name,
+			"in":          "query",
+			"description": q.description,
+			"schema":      b.schema(q.schema),
+		})
+	}
+	return params
+}
+
+func (b *specBuilder) responses(responses []response) map[string]any {
+	out := make(map[string]any, len(responses))
+	for _, r := range responses {
+		entry := map[string]any{"description": r.description}
+		if r.body != nil {
+			entry["content"] = b.content(r.body)
+		}
+		out[strconv.Itoa(r.status)] = entry
+	}
+	return out
+}
+
+func (b *specBuilder) content(c *content) map[string]any {
+	return map[string]any{c.mediaType: map[string]any{"schema": b.schema(c.schema)}}
+}
+
+// schema describes value, which is either a literal schema or a Go value.
+func (b *specBuilder) schema(value any) any {
+	if literal, ok := value.(schema); ok {
+		return literal
+	}
+	return b.typeSchema(reflect.TypeOf(value))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (b *specBuilder) typeSchema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		s := b.typeSchema(t.Elem())
+		if _, ref := s["$ref"]; ref {
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	case reflect.Struct:
+		name := schemaName(t)
+		if _, seen := b.schemas[name]; !seen {
+			b.schemas[name] = nil // Guards against recursive types.
+			b.schemas[name] = b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (b *specBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.typeSchema(field.Type)
+		if field.Type.Kind() != reflect.Pointer && !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// schemaName names the component of a struct type after the Go type, so
+// publicationResponse becomes PublicationResponse.
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		return "Object"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func (h *Handler) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(h.spec)
+}
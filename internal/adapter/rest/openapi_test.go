@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var metaOperations = []string{"GET /openapi.json", "GET /health"}
+
+// roleOperations lists the operations every role must serve and document.
+var roleOperations = map[Role][]string{
+	RoleFrontend: {
+		"POST /publications",
+		"GET /publications",
+		"GET /publications/{id}",
+		"PATCH /publications/{id}",
+		"DELETE /publications/{id}",
+		"POST /publications/{id}/activate",
+		"POST /publications/{id}/deactivate",
+		"GET /publications/{id}/content",
+	},
+	RoleLCP: {
+		"GET /publications/{id}/content",
+		"GET /contents",
+		"POST /contents/{id}/license",
+		"GET /licenses/{id}",
+		"GET /licenses/{id}/publication",
+		"POST /licenses/{id}",
+		"PATCH /licenses/{id}",
+	},
+	RoleLSD: {
+		"GET /licenses/{id}/status",
+		"POST /licenses/{id}/register",
+		"PUT /licenses/{id}/renew",
+		"PUT /licenses/{id}/return",
+		"PUT /licenses",
+		"PATCH /licenses/{id}/status",
+	},
+}
+
+// TestOpenAPIDocumentsEveryRoute checks, for each role and for all of them
+// on one handler, that the operations registered on the mux and the ones in
+// /openapi.json are the same.
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	cases := map[string][]Role{
+		"all":                nil,
+		string(RoleFrontend): {RoleFrontend},
+		string(RoleLCP):      {RoleLCP},
+		string(RoleLSD):      {RoleLSD},
+	}
+	for name, roles := range cases {
+		t.Run(name, func(t *testing.T) {
+			h, err := NewHandler(Services{}, Options{Roles: roles, PublicBaseURL: "http://localhost:8080"})
+			if err != nil {
+				t.Fatalf("NewHandler: %v", err)
+			}
+			mux := http.NewServeMux()
+			h.Register(mux)
+
+			documented := documentedOperations(t, mux)
+			want := slices.Clone(metaOperations)
+			for role, operations := range roleOperations {
+				if len(roles) == 0 || slices.Contains(roles, role) {
+					want = append(want, operations...)
+				}
+			}
+			for _, operation := range want {
+				if !documented[operation] {
+					t.Errorf("%s is not documented", operation)
+				}
+			}
+			for _, rt := range h.routes() {
+				if operation := rt.method + " " + rt.path; !documented[operation] {
+					t.Errorf("route %s is not documented", operation)
+				}
+			}
+
+			for operation := range documented {
+				if !slices.Contains(want, operation) {
+					t.Errorf("%s is documented but not expected for roles %v", operation, roles)
+				}
+				method, path, _ := strings.Cut(operation, " ")
+				req := httptest.NewRequest(method, strings.NewReplacer("{", "", "}", "").Replace(path), nil)
+				if _, pattern := mux.Handler(req); pattern != operation {
+					t.Errorf("%s is documented but the mux routes it to %q", operation, pattern)
+				}
+			}
+		})
+	}
+}
+
+// documentedOperations returns the "METHOD /path" operations of the
+// /openapi.json served by mux.
+func documentedOperations(t *testing.T, mux *http.ServeMux) map[string]bool {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d", rec.Code)
+	}
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode /openapi.json: %v", err)
+	}
+	operations := make(map[string]bool)
+	for path, methods := range doc.Paths {
+		for method := range methods {
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return operations
+}
@@ -0,0 +1,202 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// maxTitleSize bounds the title field of an upload.
+const maxTitleSize = 4 << 10
+
+// publicationResponse is the JSON representation of a publication.
+type publicationResponse struct {
+	ID          string        `json:"id"`
+	Title       string        `json:"title"`
+	Status      status.Status `json:"status"`
+	DownloadURL string        `json:"download_url"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type publicationListResponse struct {
+	Publications []publicationResponse `json:"publications"`
+}
+
+// publicationUpdateRequest is the body of PATCH /publications/{id}.
+type publicationUpdateRequest struct {
+	Title *string `json:"title"`
+}
+
+func (h *Handler) publicationResponse(pub *lcp.Publication) publicationResponse {
+	return publicationResponse{
+		ID:          pub.ID,
+		Title:       pub.Title,
+		Status:      pub.CurrentStatus(),
+		DownloadURL: h.contentURL(pub.ID),
+		CreatedAt:   pub.CreatedAt,
+	}
+}
+
+// createPublication ingests a publication sent as multipart/form-data: a
+// "title" field followed by a "file" part, which is streamed to the
+// encrypter.
+func (h *Handler) createPublication(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, apperrors.Validation("publications must be uploaded as multipart/form-data with a title and a file"))
+		return
+	}
+	var title string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			writeError(w, apperrors.Validation("multipart request is missing the \"file\" part"))
+			return
+		}
+		if err != nil {
+			writeError(w, apperrors.Wrap(apperrors.KindValidation, err, "invalid multipart request"))
+			return
+		}
+		switch part.FormName() {
+		case "title":
+			value, err := io.ReadAll(io.LimitReader(part, maxTitleSize+1))
+			if err != nil {
+				writeError(w, apperrors.Wrap(apperrors.KindValidation, err, "invalid title"))
+				return
+			}
+			if len(value) > maxTitleSize {
+				writeError(w, apperrors.Validation("title must not exceed %d bytes", maxTitleSize))
+				return
+			}
+			title = string(value)
+		case "file":
+			if title == "" {
+				writeError(w, apperrors.Validation("the \"title\" field must be sent before the \"file\" part"))
+				return
+			}
+			pub, err := h.publications.UploadAndEncrypt(r.Context(), title, part)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			w.Header().Set("Location", "/publications/"+pub.ID)
+			writeJSON(w, http.StatusCreated, h.publicationResponse(pub))
+			return
+		}
+		part.Close()
+	}
+}
+
+// listPublications lists publications, filtered by the status and title
+// query parameters and paged by offset and limit.
+func (h *Handler) listPublications(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	query := &lcp.PublicationQuery{Title: values.Get("title")}
+	switch s := status.Status(values.Get("status")); s {
+	case "", status.StatusActive, status.StatusInactive:
+		query.Status = s
+	default:
+		writeError(w, apperrors.Validation("status must be %q or %q", status.StatusActive, status.StatusInactive))
+		return
+	}
+	var err error
+	if query.Page.Offset, err = queryInt(values.Get("offset"), "offset"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if query.Page.Limit, err = queryInt(values.Get("limit"), "limit"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	pubs, err := h.publications.Search(r.Context(), query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := publicationListResponse{Publications: make([]publicationResponse, 0, len(pubs))}
+	for _, pub := range pubs {
+		resp.Publications = append(resp.Publications, h.publicationResponse(pub))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) getPublication(w http.ResponseWriter, r *http.Request) {
+	pub, err := h.publications.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.publicationResponse(pub))
+}
+
+func (h *Handler) updatePublication(w http.ResponseWriter, r *http.Request) {
+	var req publicationUpdateRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	pub, err := h.publications.Update(r.Context(), r.PathValue("id"), &lcp.PublicationUpdate{Title: req.Title})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.publicationResponse(pub))
+}
+
+func (h *Handler) deletePublication(w http.ResponseWriter, r *http.Request) {
+	if err := h.publications.Delete(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) activatePublication(w http.ResponseWriter, r *http.Request) {
+	h.changePublication(w, r, h.publications.Activate)
+}
+
+func (h *Handler) deactivatePublication(w http.ResponseWriter, r *http.Request) {
+	h.changePublication(w, r, h.publications.Deactivate)
+}
+
+func (h *Handler) changePublication(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, id string) (*lcp.Publication, error)) {
+	pub, err := change(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.publicationResponse(pub))
+}
+
+// downloadPublication serves the encrypted file of a publication, as
+// referenced by the publication URL of its licenses.
+func (h *Handler) downloadPublication(w http.ResponseWriter, r *http.Request) {
+	pub, err := h.publications.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if pub.EncryptedPath == "" {
+		writeError(w, apperrors.NotFound("publication %s has no encrypted content", pub.ID))
+		return
+	}
+	http.ServeFile(w, r, pub.EncryptedPath)
+}
+
+func queryInt(raw, name string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, apperrors.Validation("%s must be a non-negative integer", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,382 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// The routes below mirror the Readium lcpserver API, so that storefront
+// plugins written against it work unchanged. They speak its JSON shapes and
+// report errors as RFC 7807 problem details.
+
+// Media types of the lcpserver API.
+const (
+	licenseMediaType = "application/vnd.readium.lcp.license.v1.0+json"
+	problemMediaType = "application/problem+json"
+)
+
+// Identifiers of the encryption profile and user key algorithm.
+const (
+	basicProfile     = "http://readium.org/lcp/basic-profile"
+	userKeyAlgorithm = "http://www.w3.org/2001/04/xmlenc#sha256"
+)
+
+// partialLicense is the body of the lcpserver license requests. Unknown
+// fields are ignored.
+type partialLicense struct {
+	Provider   string            `json:"provider"`
+	User       licenseUser       `json:"user"`
+	Encryption partialEncryption `json:"encryption"`
+	Rights     licenseRights     `json:"rights"`
+}
+
+type partialEncryption struct {
+	UserKey partialUserKey `json:"user_key"`
+}
+
+// partialUserKey carries the SHA-256 hash of the user passphrase, either hex
+// encoded or, as a JSON byte array, base64 encoded.
+type partialUserKey struct {
+	TextHint string `json:"text_hint"`
+	HexValue string `json:"hex_value"`
+	Value    []byte `json:"value"`
+}
+
+// licenseDocument is an LCP license as returned by lcpserver.
+type licenseDocument struct {
+	Provider   string            `json:"provider"`
+	ID         string            `json:"id"`
+	Issued     time.Time         `json:"issued"`
+	Updated    *time.Time        `json:"updated,omitempty"`
+	Encryption licenseEncryption `json:"encryption"`
+	Links      []licenseLink     `json:"links"`
+	User       licenseUser       `json:"user"`
+	Rights     licenseRights     `json:"rights"`
+}
+
+type licenseEncryption struct {
+	Profile string         `json:"profile"`
+	UserKey licenseUserKey `json:"user_key"`
+}
+
+type licenseUserKey struct {
+	Algorithm string `json:"algorithm"`
+	TextHint  string `json:"text_hint"`
+}
+
+type licenseLink struct {
+	Rel    string `json:"rel"`
+	Href   string `json:"href"`
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Length int64  `json:"length,omitempty"`
+	// Templated links are URI templates (RFC 6570).
+	Templated bool `json:"templated,omitempty"`
+}
+
+// licenseUser identifies the user of a license. Only the ID is stored; the
+// other fields are echoed from the request that generated the license.
+type licenseUser struct {
+	ID        string   `json:"id"`
+	Email     string   `json:"email,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Encrypted []string `json:"encrypted,omitempty"`
+}
+
+type licenseRights struct {
+	Print *int       `json:"print,omitempty"`
+	Copy  *int       `json:"copy,omitempty"`
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// contentResponse describes a publication in GET /contents.
+type contentResponse struct {
+	ID       string `json:"id"`
+	Location string `json:"location"`
+	Length   int64  `json:"length,omitempty"`
+}
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+var licenseID = map[string]string{"id": "License ID."}
+
+func (h *Handler) readiumRoutes() []route {
+	licenseBody := &content{mediaType: licenseMediaType, schema: licenseDocument{}}
+	return []route{
+		{
+			method: http.MethodGet, path: "/contents", handler: h.listContents,
+			operationID: "listContents", tag: "lcpserver",
+			summary: "List encrypted publications",
+			responses: []response{
+				{status: http.StatusOK, description: "Every publication, as lcpserver contents.", body: jsonBody([]contentResponse{})},
+			},
+		},
+		{
+			method: http.MethodPost, path: "/contents/{id}/license", handler: h.generateLicense,
+			operationID: "generateLicense", tag: "lcpserver",
+			summary:     "Generate a license for a publication",
+			description: "The partial license names the user, the SHA-256 hash of their passphrase, and the rights.",
+			params:      publicationID,
+			body:        jsonBody(partialLicense{}),
+			responses: append([]response{
+				{status: http.StatusCreated, description: "The generated license.", body: licenseBody},
+			}, problemResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)...),
+		},
+		{
+			method: http.MethodGet, path: "/licenses/{id}", handler: h.getLicense,
+			operationID: "getLicense", tag: "lcpserver",
+			summary: "Get a license",
+			params:  licenseID,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The license as issued.", body: licenseBody},
+			}, problemResponses(http.StatusNotFound)...),
+		},
+		{
+			method: http.MethodGet, path: "/licenses/{id}/publication", handler: h.downloadLicensedPublication, public: true,
+			operationID: "downloadLicensedPublication", tag: "download",
+			summary:     "Download the encrypted publication of a license",
+			description: "This is the publication link of the licenses. Downloads stop once the license is revoked, returned, cancelled, or expired.",
+			params:      licenseID,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The encrypted file.", body: &content{
+					mediaType: "application/octet-stream",
+					schema:    schema{"type": "string", "format": "binary"},
+				}},
+			}, problemResponses(http.StatusForbidden, http.StatusNotFound)...),
+		},
+		{
+			method: http.MethodPost, path: "/licenses/{id}", handler: h.freshLicense,
+			operationID: "getFreshLicense", tag: "lcpserver",
+			summary:     "Get a fresh copy of a license",
+			description: "The optional partial license supplies the user details to embed. Its user ID, when set, must be the one the license was issued to.",
+			params:      licenseID,
+			body:        jsonBody(partialLicense{}),
+			responses: append([]response{
+				{status: http.StatusOK, description: "The license, updated now.", body: licenseBody},
+			}, problemResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+	}
+}
+
+func (h *Handler) listContents(w http.ResponseWriter, r *http.Request) {
+	pubs, err := h.publications.GetAll(r.Context())
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	contents := make([]contentResponse, 0, len(pubs))
+	for _, pub := range pubs {
+		contents = append(contents, contentResponse{
+			ID:       pub.ID,
+			Location: h.contentURL(pub.ID),
+			Length:   fileLength(pub.EncryptedPath),
+		})
+	}
+	writeJSON(w, http.StatusOK, contents)
+}
+
+func (h *Handler) generateLicense(w http.ResponseWriter, r *http.Request) {
+	var partial partialLicense
+	if err := decodeLenientJSON(w, r, &partial); err != nil {
+		writeProblem(w, err)
+		return
+	}
+	passphrase, err := userKey(partial.Encryption.UserKey)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	license, err := h.licenses.Create(r.Context(), &lcp.LicenseInput{
+		Provider:      partial.Provider,
+		PublicationID: r.PathValue("id"),
+		UserID:        partial.User.ID,
+		Passphrase:    passphrase,
+		Hint:          partial.Encryption.UserKey.TextHint,
+		RightPrint:    partial.Rights.Print,
+		RightCopy:     partial.Rights.Copy,
+		StartDate:     partial.Rights.Start,
+		EndDate:       partial.Rights.End,
+	})
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	h.writeLicense(w, r.Context(), http.StatusCreated, license, partial.User, nil)
+}
+
+func (h *Handler) getLicense(w http.ResponseWriter, r *http.Request) {
+	license, err := h.licenses.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	h.writeLicense(w, r.Context(), http.StatusOK, license, licenseUser{}, nil)
+}
+
+// freshLicense returns the license stamped with the current time, as
+// lcpserver does before handing a license to a reading system.
+func (h *Handler) freshLicense(w http.ResponseWriter, r *http.Request) {
+	var partial partialLicense
+	if err := decodeLenientJSON(w, r, &partial); err != nil {
+		writeProblem(w, err)
+		return
+	}
+	license, err := h.licenses.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	if partial.User.ID != "" && partial.User.ID != license.UserID {
+		writeProblem(w, apperrors.Validation("license %s was not issued to user %s", license.ID, partial.User.ID))
+		return
+	}
+	updated := time.Now()
+	h.writeLicense(w, r.Context(), http.StatusOK, license, partial.User, &updated)
+}
+
+func (h *Handler) downloadLicensedPublication(w http.ResponseWriter, r *http.Request) {
+	pub, err := h.licenses.LicensedPublication(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	if pub.EncryptedPath == "" {
+		writeProblem(w, apperrors.NotFound("publication %s has no encrypted content", pub.ID))
+		return
+	}
+	http.ServeFile(w, r, pub.EncryptedPath)
+}
+
+func (h *Handler) writeLicense(w http.ResponseWriter, ctx context.Context, status int, license *lcp.License, user licenseUser, updated *time.Time) {
+	doc, err := h.licenseDocument(ctx, license, user)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	if updated != nil {
+		doc.Updated = updated
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+license.ID+`.lcpl"`)
+	w.Header().Set("Content-Type", licenseMediaType)
+	w.WriteHeader(status)
+	writeBody(w, doc)
+}
+
+// licenseDocument describes license with the user details of the request.
+func (h *Handler) licenseDocument(ctx context.Context, license *lcp.License, user licenseUser) (*licenseDocument, error) {
+	publication := licenseLink{Rel: "publication", Href: h.licensedPublicationURL(license.ID)}
+	pub, err := h.publications.GetByID(ctx, license.PublicationID)
+	switch {
+	case err == nil:
+		publication.Title = pub.Title
+		publication.Length = fileLength(pub.EncryptedPath)
+	case !errors.Is(err, apperrors.ErrNotFound):
+		return nil, err
+	}
+
+	user.ID = license.UserID
+	doc := &licenseDocument{
+		Provider: license.Provider,
+		ID:       license.ID,
+		Issued:   license.CreatedAt,
+		Encryption: licenseEncryption{
+			Profile: basicProfile,
+			UserKey: licenseUserKey{Algorithm: userKeyAlgorithm, TextHint: license.Hint},
+		},
+		Links: []licenseLink{
+			publication,
+			{Rel: "status", Href: h.statusBaseURL + "/licenses/" + license.ID + "/status", Type: statusMediaType},
+		},
+		User: user,
+		Rights: licenseRights{
+			Print: license.RightPrint,
+			Copy:  license.RightCopy,
+			Start: license.StartDate,
+			End:   license.EndDate,
+		},
+	}
+	if !license.UpdatedAt.IsZero() {
+		doc.Updated = &license.UpdatedAt
+	}
+	return doc, nil
+}
+
+// userKey returns the hex encoded passphrase hash of a partial license.
+func userKey(key partialUserKey) (string, error) {
+	value := key.Value
+	if key.HexValue != "" {
+		var err error
+		if value, err = hex.DecodeString(key.HexValue); err != nil {
+			return "", apperrors.Validation("encryption.user_key.hex_value must be hex encoded")
+		}
+	}
+	if len(value) == 0 {
+		return "", apperrors.Validation("encryption.user_key.hex_value is required")
+	}
+	if len(value) != sha256.Size {
+		return "", apperrors.Validation("the user key must be a %d byte SHA-256 hash", sha256.Size)
+	}
+	return hex.EncodeToString(value), nil
+}
+
+// fileLength returns the size of the file at path, or zero when it cannot
+// be read.
+func fileLength(path string) int64 {
+	if path == "" {
+		return 0
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// writeProblem reports err as problem details with the status of its kind.
+// Unclassified errors are not described to clients.
+func writeProblem(w http.ResponseWriter, err error) {
+	status := apperrors.HTTPStatus(err)
+	p := problem{Type: "about:blank", Title: http.StatusText(status), Status: status}
+	if apperrors.KindOf(err) != apperrors.KindInternal {
+		p.Detail = err.Error()
+	}
+	w.Header().Set("Content-Type", problemMediaType)
+	w.WriteHeader(status)
+	writeBody(w, p)
+}
+
+// problemResponses documents problem details for the given statuses.
+func problemResponses(statuses ...int) []response {
+	responses := make([]response, 0, len(statuses))
+	for _, status := range statuses {
+		responses = append(responses, response{status: status, description: http.StatusText(status), body: &content{mediaType: problemMediaType, schema: problem{}}})
+	}
+	return responses
+}
+
+// licensedPublicationURL is the publication link of a license, which serves
+// the encrypted publication while the license grants access.
+func (h *Handler) licensedPublicationURL(licenseID string) string {
+	return h.licenseBaseURL + "/licenses/" + licenseID + "/publication"
+}
+
+// contentURL is where operators download the encrypted file of a
+// publication.
+func (h *Handler) contentURL(publicationID string) string {
+	return h.baseURL + "/publications/" + publicationID + "/content"
+}
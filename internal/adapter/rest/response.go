@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// maxBodySize bounds JSON request bodies.
+const maxBodySize = 1 << 20
+
+// errorResponse is the envelope of every error returned by the API.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeBody(w, body)
+}
+
+// writeBody encodes body once the headers are written.
+func writeBody(w http.ResponseWriter, body any) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(body)
+}
+
+// writeError reports err with the status of its kind. Unclassified errors
+// are not described to clients.
+func writeError(w http.ResponseWriter, err error) {
+	status := apperrors.HTTPStatus(err)
+	message := err.Error()
+	if apperrors.KindOf(err) == apperrors.KindInternal {
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// decodeJSON reads a JSON object into target, rejecting unknown fields.
+func decodeJSON(w http.ResponseWriter, r *http.Request, target any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	return decodeBody(dec, target)
+}
+
+// decodeLenientJSON reads a JSON object into target, ignoring unknown fields
+// as the Readium lcpserver does. An empty body leaves target unchanged.
+func decodeLenientJSON(w http.ResponseWriter, r *http.Request, target any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err := decodeBody(dec, target); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func decodeBody(dec *json.Decoder, target any) error {
+	if err := dec.Decode(target); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return apperrors.Validation("request body must not exceed %d bytes", tooLarge.Limit)
+		}
+		return apperrors.Wrap(apperrors.KindValidation, err, "invalid request body")
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+)
+
+// statusMediaType is the media type of License Status Documents.
+const statusMediaType = "application/vnd.readium.license.status.v1.0+json"
+
+// statusDocument is a License Status Document, which reading systems fetch
+// to learn about changes to a license and the actions it allows.
+type statusDocument struct {
+	ID      string        `json:"id"`
+	Status  status.Status `json:"status"`
+	Updated statusUpdated `json:"updated"`
+	Message string        `json:"message"`
+	Links   []licenseLink `json:"links"`
+	// PotentialRights is the furthest a loan can be renewed.
+	PotentialRights *potentialRights `json:"potential_rights,omitempty"`
+	Events          []statusEvent    `json:"events"`
+}
+
+type potentialRights struct {
+	End time.Time `json:"end"`
+}
+
+type statusUpdated struct {
+	License time.Time `json:"license"`
+	Status  time.Time `json:"status"`
+}
+
+type statusEvent struct {
+	Type      lcp.LicenseEventType `json:"type"`
+	Name      string               `json:"name"`
+	ID        string               `json:"id"`
+	Timestamp time.Time            `json:"timestamp"`
+}
+
+// statusMessages describe each status to the reader.
+var statusMessages = map[status.Status]string{
+	status.StatusReady:     "The license is ready to be used.",
+	status.StatusActive:    "The license is active.",
+	status.StatusRevoked:   "The license has been revoked by the provider.",
+	status.StatusReturned:  "The license has been returned.",
+	status.StatusCancelled: "The license has been cancelled.",
+	status.StatusExpired:   "The license has expired.",
+}
+
+// deviceQuery documents the device identifying itself in the query string.
+var deviceQuery = []queryParam{
+	{name: "id", description: "Unique identifier of the device.", schema: ""},
+	{name: "name", description: "Human readable name of the device.", schema: ""},
+}
+
+// statusMessage describes the status of a license, with the reason of its
+// revocation when the provider gave one.
+func statusMessage(licenseStatus *lcp.LicenseStatus) string {
+	if reason := licenseStatus.License.RevocationReason; licenseStatus.Status == status.StatusRevoked && reason != "" {
+		return "The license has been revoked by the provider: " + reason
+	}
+	return statusMessages[licenseStatus.Status]
+}
+
+func (h *Handler) statusRoutes() []route {
+	statusBody := &content{mediaType: statusMediaType, schema: statusDocument{}}
+	return []route{
+		{
+			method: http.MethodGet, path: "/licenses/{id}/status", handler: h.getStatus, public: true,
+			operationID: "getLicenseStatus", tag: "status",
+			summary:     "Get the License Status Document of a license",
+			description: "Reading systems fetch it without credentials, knowing only the license ID.",
+			params:      licenseID,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The License Status Document.", body: statusBody},
+			}, problemResponses(http.StatusNotFound)...),
+		},
+		{
+			method: http.MethodPost, path: "/licenses/{id}/register", handler: h.registerDevice, public: true,
+			operationID: "registerDevice", tag: "status",
+			summary:     "Register a device on a license",
+			description: "Activates a ready license. Registering a device again changes nothing. Licenses can be registered on a limited number of devices.",
+			params:      licenseID,
+			query:       deviceQuery,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The updated License Status Document.", body: statusBody},
+			}, problemResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)...),
+		},
+		{
+			method: http.MethodPut, path: "/licenses/{id}/renew", handler: h.renewLicense, public: true,
+			operationID: "renewLicense", tag: "status",
+			summary:     "Renew a loan",
+			description: "Extends an active license within the limits of its renewal policy.",
+			params:      licenseID,
+			query: append([]queryParam{
+				{name: "end", description: "Requested end of the loan, in RFC 3339 format. Defaults to the current end extended by the renewal policy.", schema: time.Time{}},
+			}, deviceQuery...),
+			responses: append([]response{
+				{status: http.StatusOK, description: "The updated License Status Document.", body: statusBody},
+			}, problemResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)...),
+		},
+		{
+			method: http.MethodPut, path: "/licenses/{id}/return", handler: h.returnLicense, public: true,
+			operationID: "returnLicense", tag: "status",
+			summary:     "Return a loan early",
+			description: "Ends an active license now and marks it returned.",
+			params:      licenseID,
+			query:       deviceQuery,
+			responses: append([]response{
+				{status: http.StatusOK, description: "The updated License Status Document.", body: statusBody},
+			}, problemResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)...),
+		},
+	}
+}
+
+func (h *Handler) getStatus(w http.ResponseWriter, r *http.Request) {
+	licenseStatus, err := h.statuses.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	h.writeStatus(w, licenseStatus)
+}
+
+func (h *Handler) registerDevice(w http.ResponseWriter, r *http.Request) {
+	licenseStatus, err := h.statuses.Register(r.Context(), r.PathValue("id"), deviceOf(r))
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	h.writeStatus(w, licenseStatus)
+}
+
+func (h *Handler) renewLicense(w http.ResponseWriter, r *http.Request) {
+	var end *time.Time
+	if raw := r.URL.Query().Get("end"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeProblem(w, apperrors.Validation("end must be an RFC 3339 date"))
+			return
+		}
+		end = &parsed
+	}
+	licenseStatus, err := h.statuses.Renew(r.Context(), r.PathValue("id"), deviceOf(r), end)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	h.writeStatus(w, licenseStatus)
+}
+
+func (h *Handler) returnLicense(w http.ResponseWriter, r *http.Request) {
+	licenseStatus, err := h.statuses.Return(r.Context(), r.PathValue("id"), deviceOf(r))
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	h.writeStatus(w, licenseStatus)
+}
+
+// deviceOf reads the device identifying itself in the query string.
+func deviceOf(r *http.Request) lcp.Device {
+	query := r.URL.Query()
+	return lcp.Device{ID: query.Get("id"), Name: query.Get("name")}
+}
+
+func (h *Handler) writeStatus(w http.ResponseWriter, licenseStatus *lcp.LicenseStatus) {
+	w.Header().Set("Content-Type", statusMediaType)
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, h.statusDocument(licenseStatus))
+}
+
+func (h *Handler) statusDocument(licenseStatus *lcp.LicenseStatus) *statusDocument {
+	license := licenseStatus.License
+	statusURL := h.statusBaseURL + "/licenses/" + license.ID
+	doc := &statusDocument{
+		ID:      license.ID,
+		Status:  licenseStatus.Status,
+		Updated: statusUpdated{License: license.LicenseUpdated(), Status: license.StatusUpdated()},
+		Message: statusMessage(licenseStatus),
+		Links:   []licenseLink{{Rel: "license", Href: h.licenseBaseURL + "/licenses/" + license.ID, Type: licenseMediaType}},
+		Events:  make([]statusEvent, 0, len(licenseStatus.Events)),
+	}
+	// Devices may only act on licenses that still grant access, and only
+	// loans in use can be returned or renewed
+	switch licenseStatus.Status {
+	case status.StatusReady:
+		doc.Links = append(doc.Links,
+			licenseLink{Rel: "register", Href: statusURL + "/register{?id,name}", Type: statusMediaType, Templated: true},
+		)
+	case status.StatusActive:
+		doc.Links = append(doc.Links,
+			licenseLink{Rel: "register", Href: statusURL + "/register{?id,name}", Type: statusMediaType, Templated: true},
+			licenseLink{Rel: "return", Href: statusURL + "/return{?id,name}", Type: statusMediaType, Templated: true},
+			licenseLink{Rel: "renew", Href: statusURL + "/renew{?end,id,name}", Type: statusMediaType, Templated: true},
+		)
+	}
+	if end := license.PotentialEnd(); end != nil {
+		doc.PotentialRights = &potentialRights{End: *end}
+	}
+	for _, event := range licenseStatus.Events {
+		doc.Events = append(doc.Events, statusEvent{
+			Type:      event.Type,
+			Name:      event.DeviceName,
+			ID:        event.DeviceID,
+			Timestamp: event.Timestamp,
+		})
+	}
+	return doc
+}
@@ -0,0 +1,45 @@
+package rest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+)
+
+// TestStatusDocumentLinks checks each link points to the service serving it
+// when the roles run behind different public URLs.
+func TestStatusDocumentLinks(t *testing.T) {
+	h, err := NewHandler(Services{}, Options{
+		PublicBaseURL:  "https://frontend.example.com",
+		LicenseBaseURL: "https://lcp.example.com/",
+		StatusBaseURL:  "https://lsd.example.com/",
+	})
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	license := &lcp.License{ID: "l1", CreatedAt: time.Now()}
+
+	doc := h.statusDocument(&lcp.LicenseStatus{License: license, Status: status.StatusActive})
+	want := map[string]string{
+		"license":  "https://lcp.example.com/licenses/l1",
+		"register": "https://lsd.example.com/licenses/l1/register{?id,name}",
+		"return":   "https://lsd.example.com/licenses/l1/return{?id,name}",
+		"renew":    "https://lsd.example.com/licenses/l1/renew{?end,id,name}",
+	}
+	if len(doc.Links) != len(want) {
+		t.Fatalf("links = %+v, want %d links", doc.Links, len(want))
+	}
+	for _, link := range doc.Links {
+		if link.Href != want[link.Rel] {
+			t.Errorf("%s link = %q, want %q", link.Rel, link.Href, want[link.Rel])
+		}
+	}
+
+	// Licenses that no longer grant access only link to themselves.
+	doc = h.statusDocument(&lcp.LicenseStatus{License: license, Status: status.StatusReturned})
+	if len(doc.Links) != 1 || doc.Links[0].Rel != "license" {
+		t.Fatalf("links of a returned license = %+v, want the license alone", doc.Links)
+	}
+}
@@ -1,14 +1,49 @@
 package config
 
 import (
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Roles that SERVER_ROLES can start, each on its own listener.
+const (
+	RoleLCP      = "lcp"
+	RoleLSD      = "lsd"
+	RoleFrontend = "frontend"
+)
+
+// RoleServer configures the listener of a role.
+type RoleServer struct {
+	Host          string // Interface to listen on; empty listens on every interface
+	Port          int
+	PublicBaseURL string // Base URL of the links to the role; defaults to http://localhost:PORT
+	AuthFile      string // htpasswd file protecting the management routes of the role
+}
+
+// Address returns the listen address of the role.
+func (s RoleServer) Address() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// BaseURL returns the public base URL of the role.
+func (s RoleServer) BaseURL() string {
+	if baseURL := strings.TrimSpace(s.PublicBaseURL); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return "http://localhost:" + strconv.Itoa(s.Port)
+}
+
 type Config struct {
 	Database struct {
 		DSN string
 	}
 	LCP struct {
+		Server      RoleServer
 		Profile     string // "basic" or "production"
 		Certificate string // Path to X.509 certificate
 		PrivateKey  string // Path to private key
@@ -25,12 +60,60 @@ type Config struct {
 			}
 		}
 	}
+	Memory struct {
+		Directory        string        // Write-ahead log and snapshot location; empty keeps data in memory only
+		SnapshotInterval time.Duration // How often the write-ahead log is compacted
+	}
+	GraphQL struct {
+		PersistedQueryCacheSize int           // Parsed documents cached by query hash
+		PersistedQueriesFile    string        // Allow-list manifest; when set, other queries are rejected
+		MaxBatchSize            int           // Operations accepted in one batched request; 0 disables batching
+		MaxDepth                int           // Deepest field nesting accepted; 0 disables the limit
+		MaxCost                 int           // Highest estimated operation cost accepted; 0 disables the limit
+		DefaultListSize         int           // Items assumed for list fields without @listSize
+		Timeout                 time.Duration // Execution time allowed per operation; 0 disables it
+		Explorer                bool          // Serve GraphiQL to browsers on GET /graphql; needs the vendored bundle
+		AllowedOrigins          []string      // Browser origins allowed to open subscriptions besides the endpoint's own
+		MaxBodySize             int           // Bytes of JSON accepted per request; multipart files are not bounded
+	}
+	LSD struct {
+		Server     RoleServer
+		MaxDevices int // Devices a license can be registered on; 0 allows any number
+		Renewal    struct {
+			MaxRenewals      int           // Renewals allowed per loan; 0 allows any number
+			MaxLoanLength    time.Duration // Longest loan, renewals included; 0 sets no limit
+			DefaultExtension time.Duration // Extension of renewals naming no end date
+		}
+		ExpiryInterval  time.Duration // Time between sweeps expiring ended licenses; 0 disables them
+		ExpiryBatchSize int           // Licenses expired per batch
+		StoreDirectory  string        // Store of the lsd role when SERVER_ROLES is set; empty shares Memory.Directory
+	}
+	Notify struct {
+		LSD struct {
+			URL      string // Status server notified of issued and revoked licenses; empty notifies in process
+			Username string // Basic auth sent by the license server and required by the status server
+			Password string
+		}
+		LCP struct {
+			URL      string // License server updated with renewed and returned dates; empty updates in process
+			Username string // Basic auth sent by the status server and required by the license server
+			Password string
+		}
+		Timeout          time.Duration // Time allowed per notification sent over HTTP
+		RetryInterval    time.Duration // Delay before the first retry of a failed notification, doubled on each failure
+		MaxRetryInterval time.Duration // Longest delay between retries
+	}
+	Frontend struct {
+		Server RoleServer
+	}
 	JWT struct {
 		Secret string
 	}
 	Server struct {
 		Port          string
 		PublicBaseURL string
+		AuthFile      string   // htpasswd file protecting the management routes when no role is selected
+		Roles         []string // Roles started on their own listeners; none serves every role on Port
 	}
 }
 
@@ -46,8 +129,175 @@ func LoadConfig() (*Config, error) {
 	cfg.LCP.Storage.S3.Bucket = os.Getenv("LCP_S3_BUCKET")
 	cfg.LCP.Storage.S3.AccessKey = os.Getenv("LCP_S3_ACCESS_KEY")
 	cfg.LCP.Storage.S3.SecretKey = os.Getenv("LCP_S3_SECRET_KEY")
+	cfg.Memory.Directory = os.Getenv("MEMORY_STORE_DIR")
+	cfg.LSD.StoreDirectory = os.Getenv("LSD_MEMORY_STORE_DIR")
+	var err error
+	if cfg.Memory.SnapshotInterval, err = envDuration("MEMORY_SNAPSHOT_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.GraphQL.PersistedQueryCacheSize, err = envInt("GRAPHQL_PERSISTED_QUERY_CACHE_SIZE", 1000); err != nil {
+		return nil, err
+	}
+	cfg.GraphQL.PersistedQueriesFile = os.Getenv("GRAPHQL_PERSISTED_QUERIES_FILE")
+	if cfg.GraphQL.MaxBatchSize, err = envInt("GRAPHQL_MAX_BATCH_SIZE", 10); err != nil {
+		return nil, err
+	}
+	if cfg.GraphQL.MaxDepth, err = envInt("GRAPHQL_MAX_DEPTH", 10); err != nil {
+		return nil, err
+	}
+	if cfg.GraphQL.MaxCost, err = envInt("GRAPHQL_MAX_COST", 1000); err != nil {
+		return nil, err
+	}
+	if cfg.GraphQL.DefaultListSize, err = envInt("GRAPHQL_DEFAULT_LIST_SIZE", 10); err != nil {
+		return nil, err
+	}
+	if cfg.GraphQL.Timeout, err = envDuration("GRAPHQL_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.GraphQL.Explorer, err = envBool("GRAPHQL_EXPLORER", false); err != nil {
+		return nil, err
+	}
+	if cfg.GraphQL.AllowedOrigins, err = envOrigins("GRAPHQL_ALLOWED_ORIGINS"); err != nil {
+		return nil, err
+	}
+	if cfg.GraphQL.MaxBodySize, err = envInt("GRAPHQL_MAX_BODY_SIZE", 10<<20); err != nil {
+		return nil, err
+	}
+	if cfg.LSD.MaxDevices, err = envInt("LSD_MAX_DEVICES", 5); err != nil {
+		return nil, err
+	}
+	if cfg.LSD.Renewal.MaxRenewals, err = envInt("LSD_RENEW_MAX_RENEWALS", 0); err != nil {
+		return nil, err
+	}
+	if cfg.LSD.Renewal.MaxLoanLength, err = envDuration("LSD_RENEW_MAX_LOAN_LENGTH", 0); err != nil {
+		return nil, err
+	}
+	if cfg.LSD.Renewal.DefaultExtension, err = envDuration("LSD_RENEW_DEFAULT_EXTENSION", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.LSD.ExpiryInterval, err = envDuration("LSD_EXPIRY_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.LSD.ExpiryBatchSize, err = envInt("LSD_EXPIRY_BATCH_SIZE", 100); err != nil {
+		return nil, err
+	}
+	cfg.Notify.LSD.URL = os.Getenv("LSD_NOTIFY_URL")
+	cfg.Notify.LSD.Username = os.Getenv("LSD_NOTIFY_USERNAME")
+	cfg.Notify.LSD.Password = os.Getenv("LSD_NOTIFY_PASSWORD")
+	cfg.Notify.LCP.URL = os.Getenv("LCP_UPDATE_URL")
+	cfg.Notify.LCP.Username = os.Getenv("LCP_UPDATE_USERNAME")
+	cfg.Notify.LCP.Password = os.Getenv("LCP_UPDATE_PASSWORD")
+	if cfg.Notify.Timeout, err = envDuration("NOTIFY_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Notify.RetryInterval, err = envDuration("NOTIFY_RETRY_INTERVAL", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Notify.MaxRetryInterval, err = envDuration("NOTIFY_MAX_RETRY_INTERVAL", 10*time.Minute); err != nil {
+		return nil, err
+	}
 	cfg.JWT.Secret = os.Getenv("JWT_SECRET")
 	cfg.Server.Port = os.Getenv("SERVER_PORT")
 	cfg.Server.PublicBaseURL = os.Getenv("PUBLIC_BASE_URL")
+	cfg.Server.AuthFile = os.Getenv("SERVER_AUTH_FILE")
+	if cfg.Server.Roles, err = envRoles("SERVER_ROLES"); err != nil {
+		return nil, err
+	}
+	if cfg.LCP.Server, err = envRoleServer("LCP", 8989); err != nil {
+		return nil, err
+	}
+	if cfg.LSD.Server, err = envRoleServer("LSD", 8990); err != nil {
+		return nil, err
+	}
+	if cfg.Frontend.Server, err = envRoleServer("FRONTEND", 8991); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+// envRoles reads a comma-separated list of roles.
+func envRoles(name string) ([]string, error) {
+	var roles []string
+	seen := make(map[string]bool)
+	for _, role := range strings.Split(os.Getenv(name), ",") {
+		role = strings.ToLower(strings.TrimSpace(role))
+		switch {
+		case role == "" || seen[role]:
+			continue
+		case role != RoleLCP && role != RoleLSD && role != RoleFrontend:
+			return nil, fmt.Errorf("%s: unknown role %q, expected %s, %s or %s", name, role, RoleLCP, RoleLSD, RoleFrontend)
+		}
+		seen[role] = true
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// envOrigins reads a comma-separated list of origins such as
+// "https://app.example.com", or "*".
+func envOrigins(name string) ([]string, error) {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv(name), ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin != "*" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+				return nil, fmt.Errorf("%s: invalid origin %q, expected scheme://host[:port]", name, origin)
+			}
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
+
+// envRoleServer reads the listener of a role from the variables named after
+// prefix: _HOST, _PORT, _PUBLIC_BASE_URL and _AUTH_FILE.
+func envRoleServer(prefix string, port int) (RoleServer, error) {
+	server := RoleServer{
+		Host:          os.Getenv(prefix + "_HOST"),
+		PublicBaseURL: os.Getenv(prefix + "_PUBLIC_BASE_URL"),
+		AuthFile:      os.Getenv(prefix + "_AUTH_FILE"),
+	}
+	var err error
+	server.Port, err = envInt(prefix+"_PORT", port)
+	return server, err
+}
+
+func envDuration(name string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return value, nil
+}
+
+func envBool(name string, fallback bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	return value, nil
+}
+
+func envInt(name string, fallback int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return value, nil
+}
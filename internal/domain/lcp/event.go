@@ -0,0 +1,49 @@
+package lcp
+
+import (
+	"context"
+	"time"
+)
+
+// EventType names a change that subscribers can observe.
+type EventType string
+
+const (
+	EventPublicationEncrypted EventType = "publication.encrypted"
+	EventPublicationFailed    EventType = "publication.failed"
+	EventLicenseCreated       EventType = "license.created"
+	EventLicenseRevoked       EventType = "license.revoked"
+	EventLicenseStatusChanged EventType = "license.status_changed"
+)
+
+// Event describes a change to a publication or a license. Publication events
+// carry Publication, license events carry License, and failures carry the
+// error message in Error.
+type Event struct {
+	Type        EventType
+	Publication *Publication
+	License     *License
+	Error       string
+	OccurredAt  time.Time
+}
+
+// EventPublisher is used by use cases to announce changes. Publishing never
+// blocks on slow subscribers.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// EventSubscriber delivers published events. The returned channel receives
+// the events of the given types, or of every type when none is given, and is
+// closed once ctx is done or when the subscriber falls too far behind.
+type EventSubscriber interface {
+	Subscribe(ctx context.Context, types ...EventType) <-chan Event
+}
+
+// EventBus carries events from publishers to subscribers. The in-process
+// implementation serves a single replica; a broker-backed one can replace it
+// when several replicas must share events.
+type EventBus interface {
+	EventPublisher
+	EventSubscriber
+}
@@ -1,10 +1,20 @@
 package lcp
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
 
-// License captures access information for a publication.
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+)
+
+// License captures access information for a publication. PublicationURL is
+// the download link recorded when the license was issued; served documents
+// build the link from the license ID instead.
 type License struct {
 	ID             string     `db:"id" json:"id"`
+	Provider       string     `db:"provider" json:"provider"`
 	PublicationID  string     `db:"publication_id" json:"publication_id"`
 	UserID         string     `db:"user_id" json:"user_id"`
 	Passphrase     string     `db:"passphrase" json:"passphrase"`
@@ -15,10 +25,159 @@ type License struct {
 	StartDate      *time.Time `db:"start_date" json:"start_date"`
 	EndDate        *time.Time `db:"end_date" json:"end_date"`
 	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	// UpdatedAt is when the rights of the license last changed, and
+	// StatusUpdatedAt when its status did. Both are zero until then.
+	UpdatedAt       time.Time     `db:"updated_at" json:"updated_at"`
+	Status          status.Status `db:"status" json:"status"`
+	StatusUpdatedAt time.Time     `db:"status_updated_at" json:"status_updated_at"`
+	// RevokedAt and RevocationReason record the revocation of the license
+	// by the provider.
+	RevokedAt        *time.Time `db:"revoked_at" json:"revoked_at"`
+	RevocationReason string     `db:"revocation_reason" json:"revocation_reason"`
+	// Renewal is the policy the license was issued under, stored as a JSON
+	// column.
+	Renewal RenewalPolicy `db:"renewal" json:"renewal"`
+}
+
+// RenewalPolicy limits how a loan can be renewed. Zero values lift the
+// corresponding limit.
+type RenewalPolicy struct {
+	// MaxRenewals is the number of times the loan can be renewed.
+	MaxRenewals int `json:"max_renewals"`
+	// MaxLoanLength bounds the time between the start of the loan and its
+	// end, renewals included.
+	MaxLoanLength time.Duration `json:"max_loan_length"`
+	// DefaultExtension extends the loan when a renewal names no end date.
+	// Without it, renewals must name one.
+	DefaultExtension time.Duration `json:"default_extension"`
+}
+
+// Value encodes the policy for its JSON column.
+func (p RenewalPolicy) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan decodes the policy from its JSON column. NULL leaves every limit
+// lifted.
+func (p *RenewalPolicy) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*p = RenewalPolicy{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("renewal policy: cannot scan %T", src)
+	}
+}
+
+// LoanStart returns when the loan began: its start date, or its creation.
+func (l *License) LoanStart() time.Time {
+	if l.StartDate != nil {
+		return *l.StartDate
+	}
+	return l.CreatedAt
+}
+
+// PotentialEnd returns the latest end date renewals can reach, or nil when
+// the policy of the license sets no maximum loan length.
+func (l *License) PotentialEnd() *time.Time {
+	if l.Renewal.MaxLoanLength <= 0 {
+		return nil
+	}
+	end := l.LoanStart().Add(l.Renewal.MaxLoanLength)
+	return &end
+}
+
+// CurrentStatus returns the stored status of the license. Records stored
+// before licenses had a status are ready.
+func (l *License) CurrentStatus() status.Status {
+	if l.Status == "" {
+		return status.StatusReady
+	}
+	return l.Status
+}
+
+// StatusAt returns the status of the license at t: a ready or active
+// license whose end date has passed is expired, even before the change is
+// stored.
+func (l *License) StatusAt(t time.Time) status.Status {
+	current := l.CurrentStatus()
+	if (current == status.StatusReady || current == status.StatusActive) && l.EndDate != nil && !l.EndDate.After(t) {
+		return status.StatusExpired
+	}
+	return current
+}
+
+// ActiveAt reports whether the license still grants access at t, that is
+// whether it is ready or active at t.
+func (l *License) ActiveAt(t time.Time) bool {
+	current := l.StatusAt(t)
+	return current == status.StatusReady || current == status.StatusActive
+}
+
+// LicenseUpdated returns when the license was last issued or updated.
+func (l *License) LicenseUpdated() time.Time {
+	if l.UpdatedAt.IsZero() {
+		return l.CreatedAt
+	}
+	return l.UpdatedAt
+}
+
+// StatusUpdated returns when the status of the license last changed.
+func (l *License) StatusUpdated() time.Time {
+	if l.StatusUpdatedAt.IsZero() {
+		return l.CreatedAt
+	}
+	return l.StatusUpdatedAt
+}
+
+// LicenseEventType names an interaction recorded in a License Status
+// Document.
+type LicenseEventType string
+
+const (
+	LicenseEventRegister LicenseEventType = "register"
+	LicenseEventRenew    LicenseEventType = "renew"
+	LicenseEventReturn   LicenseEventType = "return"
+	LicenseEventRevoke   LicenseEventType = "revoke"
+	LicenseEventCancel   LicenseEventType = "cancel"
+	// LicenseEventExpire records the expiry of a license once its end date
+	// has passed.
+	LicenseEventExpire LicenseEventType = "expire"
+)
+
+// LicenseEvent records a device interaction with a license, or a change made
+// by the provider. Provider changes carry no device.
+type LicenseEvent struct {
+	ID         string           `db:"id" json:"id"`
+	LicenseID  string           `db:"license_id" json:"license_id"`
+	Type       LicenseEventType `db:"type" json:"type"`
+	DeviceID   string           `db:"device_id" json:"device_id"`
+	DeviceName string           `db:"device_name" json:"device_name"`
+	Timestamp  time.Time        `db:"timestamp" json:"timestamp"`
+}
+
+// Device identifies the reading system acting on a license.
+type Device struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// LicenseStatus is the state of a license as reported by its License Status
+// Document.
+type LicenseStatus struct {
+	License *License
+	// Status is the status of the license when the document was built.
+	Status status.Status
+	Events []*LicenseEvent
 }
 
 // LicenseInput is the input contract for creating a license.
 type LicenseInput struct {
+	Provider      string     `json:"provider"`
 	PublicationID string     `json:"publication_id"`
 	UserID        string     `json:"user_id"`
 	Passphrase    string     `json:"passphrase"`
@@ -0,0 +1,61 @@
+package lcp
+
+import (
+	"context"
+	"time"
+)
+
+// StatusNotifier tells the status service about the licenses the license
+// service issues and revokes, so that it can serve their status documents.
+// Notifications are idempotent: delivering one twice changes nothing.
+type StatusNotifier interface {
+	LicenseIssued(ctx context.Context, license *License) error
+	LicenseRevoked(ctx context.Context, license *License) error
+}
+
+// LicenseUpdater tells the license service about the dates the status
+// service changes when a loan is renewed or returned, so that fresh copies of
+// the license carry them. Updates older than the license are ignored.
+type LicenseUpdater interface {
+	UpdateLicense(ctx context.Context, update *LicenseUpdate) error
+}
+
+// LicenseUpdate carries the dates of a license as changed at UpdatedAt.
+type LicenseUpdate struct {
+	LicenseID string     `json:"id"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// NotificationKind names the notification a Notification stands for.
+type NotificationKind string
+
+const (
+	NotificationLicenseIssued  NotificationKind = "license_issued"
+	NotificationLicenseRevoked NotificationKind = "license_revoked"
+	NotificationLicenseUpdated NotificationKind = "license_updated"
+)
+
+// Notification waits in the outbox until the other service accepts it. It
+// names the license rather than carrying it: the license is read when the
+// notification is delivered, so a late delivery sends its current state.
+type Notification struct {
+	ID            string           `db:"id" json:"id"`
+	Kind          NotificationKind `db:"kind" json:"kind"`
+	LicenseID     string           `db:"license_id" json:"license_id"`
+	CreatedAt     time.Time        `db:"created_at" json:"created_at"`
+	Attempts      int              `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time        `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     string           `db:"last_error" json:"last_error"`
+}
+
+// Outbox queues the notifications of a unit of work. Queue joins the
+// transaction carried by ctx, so a notification is kept if and only if the
+// change it reports commits. Wake asks for the delivery of the queued
+// notifications and is called once that change has committed: notifiers are
+// never called within a transaction.
+type Outbox interface {
+	Queue(ctx context.Context, kind NotificationKind, licenseID string) error
+	Wake()
+}
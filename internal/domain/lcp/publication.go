@@ -1,12 +1,41 @@
 package lcp
 
-import "time"
+import (
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+)
 
 // Publication represents an encrypted book stored by the service.
 type Publication struct {
-	ID            string    `db:"id" json:"id"`
-	Title         string    `db:"title" json:"title"`
-	FilePath      string    `db:"file_path" json:"file_path"`
-	EncryptedPath string    `db:"encrypted_path" json:"encrypted_path"`
-	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	ID            string        `db:"id" json:"id"`
+	Title         string        `db:"title" json:"title"`
+	FilePath      string        `db:"file_path" json:"file_path"`
+	EncryptedPath string        `db:"encrypted_path" json:"encrypted_path"`
+	Status        status.Status `db:"status" json:"status"`
+	CreatedAt     time.Time     `db:"created_at" json:"created_at"`
+}
+
+// CurrentStatus returns the lifecycle status of the publication. Records
+// stored before publications had a status are active.
+func (p *Publication) CurrentStatus() status.Status {
+	if p.Status == "" {
+		return status.StatusActive
+	}
+	return p.Status
+}
+
+// PublicationQuery filters and pages a listing of publications. Empty
+// filters match every publication; Title matches case-insensitively on any
+// part of the title.
+type PublicationQuery struct {
+	Status status.Status
+	Title  string
+	Page   Page
+}
+
+// PublicationUpdate holds the metadata changes applied by an update. Nil
+// fields are left unchanged.
+type PublicationUpdate struct {
+	Title *string `json:"title"`
 }
@@ -1,16 +1,70 @@
 package lcp
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// Page selects a window of a result set ordered by insertion. A zero Limit
+// returns every record after Offset.
+type Page struct {
+	Offset int
+	Limit  int
+}
 
 // PublicationRepository describes the persistence operations for publications.
+// Lookups of a missing record fail with an error matching errors.ErrNotFound
+// from internal/pkg/errors. FindByIDs instead skips the ids it cannot find,
+// so that callers loading many records at once can tell which are missing.
+// Deleting a missing record fails with a not-found error too.
 type PublicationRepository interface {
 	Save(ctx context.Context, pub *Publication) error
 	FindAll(ctx context.Context) ([]*Publication, error)
+	List(ctx context.Context, page Page) ([]*Publication, error)
 	FindByID(ctx context.Context, id string) (*Publication, error)
+	FindByIDs(ctx context.Context, ids []string) ([]*Publication, error)
+	Delete(ctx context.Context, id string) error
 }
 
-// LicenseRepository describes the persistence operations for licenses.
+// LicenseRepository describes the persistence operations for licenses, with
+// the same not-found semantics as PublicationRepository. Delete removes a
+// license, not its events. FindExpired returns, by end date and at most
+// limit of them unless limit is zero, the licenses whose end date has passed
+// at now but whose stored status still grants access.
 type LicenseRepository interface {
 	Save(ctx context.Context, license *License) error
+	List(ctx context.Context, page Page) ([]*License, error)
+	FindByID(ctx context.Context, id string) (*License, error)
 	FindByPublication(ctx context.Context, publicationID *string) ([]*License, error)
+	FindByPublications(ctx context.Context, publicationIDs []string) ([]*License, error)
+	FindByUser(ctx context.Context, userID string) ([]*License, error)
+	FindExpired(ctx context.Context, now time.Time, limit int) ([]*License, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// LicenseEventRepository stores the events of License Status Documents.
+// FindByLicense returns the events of a license in the order they were saved,
+// and DeleteByLicense removes them all.
+type LicenseEventRepository interface {
+	Save(ctx context.Context, event *LicenseEvent) error
+	FindByLicense(ctx context.Context, licenseID string) ([]*LicenseEvent, error)
+	DeleteByLicense(ctx context.Context, licenseID string) error
+}
+
+// NotificationRepository keeps the outbox of notifications waiting for
+// delivery. FindPending returns them in the order they were first saved.
+type NotificationRepository interface {
+	Save(ctx context.Context, notification *Notification) error
+	FindPending(ctx context.Context) ([]*Notification, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// Transactor runs a unit of work atomically across repositories. Repository
+// calls made with the context handed to fn join the transaction, and any
+// error returned by fn rolls every change back. Transactions must be
+// serializable: a record read within fn is not changed by another
+// transaction before fn's changes commit, so that checking a record and then
+// writing it is safe against concurrent units of work.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
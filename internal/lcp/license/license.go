@@ -1,9 +1,8 @@
 package license
 
 import (
-	"fmt"
-
 	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
 )
 
 // Service provides a minimal implementation for generating and revoking
@@ -19,7 +18,7 @@ func NewService() *Service {
 // an error when data is incomplete.
 func (s *Service) GenerateLicense(license *lcp.License) error {
 	if license.PublicationID == "" || license.UserID == "" {
-		return fmt.Errorf("missing publication or user identifiers")
+		return apperrors.Validation("missing publication or user identifiers")
 	}
 	// In production, integrate with the DRM backend here.
 	return nil
@@ -29,7 +28,7 @@ func (s *Service) GenerateLicense(license *lcp.License) error {
 // call a DRM revocation endpoint.
 func (s *Service) RevokeLicense(id string) error {
 	if id == "" {
-		return fmt.Errorf("missing license id")
+		return apperrors.Validation("missing license id")
 	}
 	return nil
 }
@@ -7,3 +7,13 @@ const (
 	StatusActive   Status = "active"
 	StatusInactive Status = "inactive"
 )
+
+// License statuses, as reported by License Status Documents. A license is
+// ready until a device registers it, which makes it active.
+const (
+	StatusReady     Status = "ready"
+	StatusRevoked   Status = "revoked"
+	StatusReturned  Status = "returned"
+	StatusCancelled Status = "cancelled"
+	StatusExpired   Status = "expired"
+)
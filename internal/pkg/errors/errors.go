@@ -1,10 +1,136 @@
 package errors
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind classifies an error independently of the transport reporting it. Its
+// value doubles as the GraphQL `extensions.code`.
+type Kind string
+
+const (
+	KindInternal       Kind = "INTERNAL_SERVER_ERROR"
+	KindNotFound       Kind = "NOT_FOUND"
+	KindConflict       Kind = "CONFLICT"
+	KindValidation     Kind = "BAD_USER_INPUT"
+	KindUnauthorized   Kind = "UNAUTHENTICATED"
+	KindForbidden      Kind = "FORBIDDEN"
+	KindNotImplemented Kind = "NOT_IMPLEMENTED"
+	KindTimeout        Kind = "TIMEOUT"
+)
+
+// Error is an error carrying a Kind. It optionally wraps the error that
+// caused it.
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
 
-// Common reusable errors for adapters and use cases.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error of the same kind, so that
+// errors.Is(err, ErrNotFound) matches every not-found error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind
+}
+
+// Common reusable errors for adapters and use cases. Compare against them
+// with errors.Is.
 var (
-	ErrNotFound       = errors.New("not found")
-	ErrUnauthorized   = errors.New("unauthorized")
-	ErrNotImplemented = errors.New("not implemented")
+	ErrNotFound       = &Error{Kind: KindNotFound, Message: "not found"}
+	ErrConflict       = &Error{Kind: KindConflict, Message: "conflict"}
+	ErrValidation     = &Error{Kind: KindValidation, Message: "invalid input"}
+	ErrUnauthorized   = &Error{Kind: KindUnauthorized, Message: "unauthorized"}
+	ErrForbidden      = &Error{Kind: KindForbidden, Message: "forbidden"}
+	ErrNotImplemented = &Error{Kind: KindNotImplemented, Message: "not implemented"}
 )
+
+// New returns an error of the given kind with a formatted message.
+func New(kind Kind, format string, args ...any) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap classifies err with the given kind, keeping it in the chain.
+func Wrap(kind Kind, err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// NotFound reports a missing record.
+func NotFound(format string, args ...any) error {
+	return New(KindNotFound, format, args...)
+}
+
+// Conflict reports a request that clashes with the current state.
+func Conflict(format string, args ...any) error {
+	return New(KindConflict, format, args...)
+}
+
+// Validation reports invalid or incomplete input.
+func Validation(format string, args ...any) error {
+	return New(KindValidation, format, args...)
+}
+
+// Unauthorized reports missing or invalid credentials.
+func Unauthorized(format string, args ...any) error {
+	return New(KindUnauthorized, format, args...)
+}
+
+// Forbidden reports an authenticated caller lacking permission.
+func Forbidden(format string, args ...any) error {
+	return New(KindForbidden, format, args...)
+}
+
+// KindOf returns the kind of the first *Error in err's chain. Unclassified
+// errors are KindTimeout when a deadline expired and KindInternal otherwise.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+	return KindInternal
+}
+
+// HTTPStatus maps err to the HTTP status code reported to clients.
+func HTTPStatus(err error) int {
+	switch KindOf(err) {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotImplemented:
+		return http.StatusNotImplemented
+	case KindTimeout:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,221 @@
+package gql
+
+// Document is a parsed executable GraphQL document.
+type Document struct {
+	Operations []*Operation
+	Fragments  []*FragmentDefinition
+}
+
+// Fragment returns the fragment definition with the given name, or nil.
+func (d *Document) Fragment(name string) *FragmentDefinition {
+	for _, f := range d.Fragments {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// OperationType is query, mutation or subscription.
+type OperationType string
+
+const (
+	Query        OperationType = "query"
+	Mutation     OperationType = "mutation"
+	Subscription OperationType = "subscription"
+)
+
+// Operation is an operation definition of an executable document.
+type Operation struct {
+	Type         OperationType
+	Name         string
+	Variables    []*VariableDefinition
+	Directives   []*Directive
+	SelectionSet []Selection
+	Loc          Location
+}
+
+// VariableDefinition declares an operation variable.
+type VariableDefinition struct {
+	Name       string
+	Type       *TypeRef
+	Default    *Value
+	Directives []*Directive
+	Loc        Location
+}
+
+// FragmentDefinition is a named fragment.
+type FragmentDefinition struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Loc           Location
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment.
+type Selection interface {
+	location() Location
+}
+
+// Field selects a field of the enclosing type.
+type Field struct {
+	Alias        string
+	Name         string
+	Arguments    []*Argument
+	Directives   []*Directive
+	SelectionSet []Selection
+	Loc          Location
+}
+
+// ResponseKey is the key under which the field appears in the response.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FragmentSpread includes a named fragment.
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Loc        Location
+}
+
+// InlineFragment includes a selection set, optionally for a type condition.
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Loc           Location
+}
+
+func (f *Field) location() Location          { return f.Loc }
+func (f *FragmentSpread) location() Location { return f.Loc }
+func (f *InlineFragment) location() Location { return f.Loc }
+
+// Argument is a named argument value of a field or directive.
+type Argument struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
+
+// Directive is an applied directive such as @include(if: $flag).
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+	Loc       Location
+}
+
+// ValueKind identifies the kind of a literal value.
+type ValueKind int
+
+const (
+	VariableValue ValueKind = iota
+	IntValue
+	FloatValue
+	StringValue
+	BooleanValue
+	NullValue
+	EnumValue
+	ListValue
+	ObjectValue
+)
+
+// Value is an input value literal. Raw holds the variable name, the scalar
+// text or the enum name; List and Fields hold the items of composite values.
+type Value struct {
+	Kind   ValueKind
+	Raw    string
+	List   []*Value
+	Fields []*ObjectField
+	Loc    Location
+}
+
+// ObjectField is one field of an input object literal.
+type ObjectField struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
+
+// TypeRef is a type reference such as [ID!]!.
+type TypeRef struct {
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+	Loc     Location
+}
+
+func (t *TypeRef) String() string {
+	var s string
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	} else {
+		s = t.Name
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// schemaDocument is a parsed type system document.
+type schemaDocument struct {
+	schema     *schemaDefinition
+	types      []*typeDefinition
+	directives []*directiveDefinition
+}
+
+type schemaDefinition struct {
+	operations map[OperationType]string
+	loc        Location
+}
+
+type typeDefinition struct {
+	kind        TypeKind
+	name        string
+	description string
+	interfaces  []string
+	fields      []*fieldDefinition
+	inputFields []*inputValueDefinition
+	enumValues  []*enumValueDefinition
+	members     []string
+	directives  []*Directive
+	loc         Location
+}
+
+type fieldDefinition struct {
+	name        string
+	description string
+	args        []*inputValueDefinition
+	typ         *TypeRef
+	directives  []*Directive
+	loc         Location
+}
+
+type inputValueDefinition struct {
+	name         string
+	description  string
+	typ          *TypeRef
+	defaultValue *Value
+	directives   []*Directive
+	loc          Location
+}
+
+type enumValueDefinition struct {
+	name        string
+	description string
+	directives  []*Directive
+}
+
+type directiveDefinition struct {
+	name        string
+	description string
+	args        []*inputValueDefinition
+	repeatable  bool
+	locations   []string
+	loc         Location
+}
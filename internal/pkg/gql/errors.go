@@ -0,0 +1,55 @@
+package gql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Location points at a line and column of a GraphQL document, both starting
+// at 1.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is a GraphQL error as it appears in the "errors" list of a response.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+
+	// Err is the resolver error this error was built from, if any.
+	Err error `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors is a list of GraphQL errors.
+type Errors []*Error
+
+func (errs Errors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func newError(loc Location, format string, args ...any) *Error {
+	err := &Error{Message: fmt.Sprintf(format, args...)}
+	if loc.Line > 0 {
+		err.Locations = []Location{loc}
+	}
+	return err
+}
+
+func syntaxError(loc Location, format string, args ...any) *Error {
+	return newError(loc, "Syntax Error: "+format, args...)
+}
@@ -0,0 +1,767 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldResolver produces the value of a field for one parent object. It may
+// return a Thunk to defer the work until its siblings have been resolved.
+type FieldResolver func(p ResolveParams) (any, error)
+
+// Thunk is a deferred field value. The executor calls thunks only once every
+// object of the current level has been resolved, so resolvers can record the
+// keys they need and load them all with the first call.
+type Thunk func() (any, error)
+
+// TypeResolver names the object type of a value returned for an interface or
+// union field.
+type TypeResolver func(value any) string
+
+// ResolveParams is passed to every FieldResolver.
+type ResolveParams struct {
+	Context context.Context
+	Source  any
+	Args    map[string]any
+	Info    ResolveInfo
+}
+
+// ResolveInfo describes the field being resolved.
+type ResolveInfo struct {
+	FieldName  string
+	ParentType *Type
+	ReturnType *Type
+	Path       []any
+	// Fields are the selections merged under the response key; their
+	// selection sets describe what will be read from the returned value.
+	Fields    []*Field
+	Operation *Operation
+	Document  *Document
+	Variables map[string]any
+	Schema    *Schema
+}
+
+// Config describes an Executor.
+type Config struct {
+	Schema *Schema
+	// Resolvers maps type names to field names to resolvers. Fields without a
+	// resolver read the entry of the same name when the parent is a
+	// map[string]any, or the matching exported struct field.
+	Resolvers map[string]map[string]FieldResolver
+	// TypeResolvers name the runtime object type of values returned for
+	// abstract types. Values that are maps may carry a "__typename" key
+	// instead.
+	TypeResolvers map[string]TypeResolver
+	// Streams open the source event streams of the subscription root fields,
+	// keyed by field name. Each event is then resolved as the value of the
+	// field unless Resolvers has a resolver for it.
+	Streams map[string]StreamResolver
+	// ErrorExtensions returns the extensions attached to the GraphQL error
+	// built from a resolver error.
+	ErrorExtensions func(err error) map[string]any
+	// Context derives the context of one execution from the request
+	// context, for example to attach per-request caches. Subscriptions
+	// derive a context for every event.
+	Context func(ctx context.Context) context.Context
+	// Limits bound the depth and estimated cost of operations.
+	Limits Limits
+	// RequireResolvers makes NewExecutor fail unless every field of every
+	// object type has a resolver, every subscription field a stream, and
+	// every interface and union a type resolver.
+	RequireResolvers bool
+}
+
+// Executor parses, validates and executes GraphQL requests against a schema.
+type Executor struct {
+	cfg   Config
+	meta  map[string]map[string]FieldResolver
+	costs map[*FieldDef]fieldCost
+}
+
+// NewExecutor checks that every resolver targets a field of the schema and
+// returns an Executor. Introspection fields are always resolved by the
+// executor itself.
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.Schema == nil {
+		return nil, errors.New("gql: executor requires a schema")
+	}
+	var errs Errors
+	for typeName, fields := range cfg.Resolvers {
+		t := cfg.Schema.Type(typeName)
+		if isIntrospectionType(typeName) {
+			errs = append(errs, &Error{Message: fmt.Sprintf("resolvers cannot be defined for introspection type %q", typeName)})
+			continue
+		}
+		if t == nil || (t.Kind != KindObject && t.Kind != KindInterface) {
+			errs = append(errs, &Error{Message: fmt.Sprintf("resolvers defined for unknown object type %q", typeName)})
+			continue
+		}
+		for fieldName := range fields {
+			if t.Field(fieldName) == nil {
+				errs = append(errs, &Error{Message: fmt.Sprintf("resolver defined for unknown field %s.%s", typeName, fieldName)})
+			}
+		}
+	}
+	for fieldName := range cfg.Streams {
+		if cfg.Schema.Subscription == nil || cfg.Schema.Subscription.Field(fieldName) == nil {
+			errs = append(errs, &Error{Message: fmt.Sprintf("stream defined for unknown subscription field %q", fieldName)})
+		}
+	}
+	for typeName := range cfg.TypeResolvers {
+		if t := cfg.Schema.Type(typeName); t == nil || !t.IsAbstract() {
+			errs = append(errs, &Error{Message: fmt.Sprintf("type resolver defined for %q, which is not an interface or union", typeName)})
+		}
+	}
+	costs, costErrs := fieldCosts(cfg.Schema)
+	errs = append(errs, costErrs...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if cfg.RequireResolvers {
+		if err := checkCoverage(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return &Executor{cfg: cfg, meta: introspectionResolvers(cfg.Schema), costs: costs}, nil
+}
+
+// Schema returns the schema the executor serves.
+func (e *Executor) Schema() *Schema {
+	return e.cfg.Schema
+}
+
+// Request is a GraphQL request as sent by clients.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// Response is the result of a GraphQL request. Data is omitted from the JSON
+// encoding when the request failed before execution started.
+type Response struct {
+	Data       any
+	Errors     Errors
+	Extensions map[string]any
+
+	executed bool
+}
+
+// MarshalJSON encodes the response following the GraphQL response format.
+func (r *Response) MarshalJSON() ([]byte, error) {
+	out := NewOrderedMap()
+	if len(r.Errors) > 0 {
+		out.Set("errors", r.Errors)
+	}
+	if r.executed {
+		out.Set("data", r.Data)
+	}
+	if len(r.Extensions) > 0 {
+		out.Set("extensions", r.Extensions)
+	}
+	return out.MarshalJSON()
+}
+
+// ErrorResponse returns a response carrying only errors.
+func ErrorResponse(errs ...*Error) *Response {
+	return &Response{Errors: errs}
+}
+
+// Parse parses and validates a query for the executor's schema.
+func (e *Executor) Parse(query string) (*Document, Errors) {
+	doc, err := Parse(query)
+	if err != nil {
+		var gqlErr *Error
+		if errors.As(err, &gqlErr) {
+			return nil, Errors{gqlErr}
+		}
+		return nil, Errors{{Message: err.Error()}}
+	}
+	if errs := Validate(e.cfg.Schema, doc); len(errs) > 0 {
+		return nil, errs
+	}
+	return doc, nil
+}
+
+// Execute parses, validates and executes a request.
+func (e *Executor) Execute(ctx context.Context, req Request) *Response {
+	doc, errs := e.Parse(req.Query)
+	if errs != nil {
+		return &Response{Errors: errs}
+	}
+	return e.ExecuteDocument(ctx, doc, req.OperationName, req.Variables)
+}
+
+// ExecuteDocument executes an already validated document.
+func (e *Executor) ExecuteDocument(ctx context.Context, doc *Document, operationName string, variables map[string]any) *Response {
+	x, errResp := e.prepare(ctx, doc, operationName, variables)
+	if errResp != nil {
+		return errResp
+	}
+	if x.op.Type == Subscription {
+		return ErrorResponse(newError(x.op.Loc, "Subscriptions must be executed with Subscribe."))
+	}
+	return x.execute(nil)
+}
+
+// SelectOperation returns the operation of doc to run for operationName.
+func SelectOperation(doc *Document, operationName string) (*Operation, *Error) {
+	if operationName == "" {
+		if len(doc.Operations) != 1 {
+			return nil, &Error{Message: "Must provide operation name if query contains multiple operations."}
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == operationName {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("Unknown operation named %q.", operationName)}
+}
+
+func (e *Executor) prepare(ctx context.Context, doc *Document, operationName string, variables map[string]any) (*execution, *Response) {
+	op, err := SelectOperation(doc, operationName)
+	if err != nil {
+		return nil, ErrorResponse(err)
+	}
+	root := e.cfg.Schema.RootType(op.Type)
+	if root == nil {
+		return nil, ErrorResponse(newError(op.Loc, "Schema is not configured for %ss.", op.Type))
+	}
+	vars, errs := coerceVariables(e.cfg.Schema, op, variables)
+	if len(errs) > 0 {
+		return nil, &Response{Errors: errs}
+	}
+	x := &execution{
+		ex:        e,
+		ctx:       ctx,
+		doc:       doc,
+		op:        op,
+		root:      root,
+		vars:      vars,
+		fragments: fragmentIndex(doc),
+	}
+	if err := x.checkLimits(); err != nil {
+		return nil, ErrorResponse(err)
+	}
+	if e.cfg.Context != nil && op.Type != Subscription {
+		x.ctx = e.cfg.Context(ctx)
+	}
+	return x, nil
+}
+
+func fragmentIndex(doc *Document) map[string]*FragmentDefinition {
+	fragments := make(map[string]*FragmentDefinition, len(doc.Fragments))
+	for _, f := range doc.Fragments {
+		fragments[f.Name] = f
+	}
+	return fragments
+}
+
+// execution holds the state of one operation run. Objects are completed
+// level by level: every field of every object at one depth is resolved before
+// the objects below are visited.
+type execution struct {
+	ex        *Executor
+	ctx       context.Context
+	doc       *Document
+	op        *Operation
+	root      *Type
+	vars      map[string]any
+	fragments map[string]*FragmentDefinition
+	errs      Errors
+	data      any
+	deferred  []*deferredField
+}
+
+// deferredField is a field whose resolver returned a Thunk.
+type deferredField struct {
+	parent *Type
+	def    *FieldDef
+	thunk  Thunk
+	slot   *slot
+	fields []*Field
+	path   []any
+}
+
+// slot is a location in the response that a completed value is written to.
+// A non-null slot that would receive null nulls its parent instead.
+type slot struct {
+	parent  *slot
+	nonNull bool
+	dead    bool
+	set     func(any)
+}
+
+// alive reports whether no enclosing value has been nulled.
+func (s *slot) alive() bool {
+	for ; s != nil; s = s.parent {
+		if s.dead {
+			return false
+		}
+	}
+	return true
+}
+
+// objectTask is an object value waiting for its selection set.
+type objectTask struct {
+	source any
+	result *OrderedMap
+	slot   *slot
+	path   []any
+}
+
+// batch groups the objects of one concrete type sharing a selection set.
+type batch struct {
+	typ        *Type
+	selections []Selection
+	tasks      []*objectTask
+}
+
+// queue collects the batches of the next level, keeping their order.
+type queue struct {
+	batches []*batch
+	index   map[batchKey]*batch
+}
+
+type batchKey struct {
+	field *Field
+	typ   *Type
+}
+
+func (q *queue) add(key batchKey, selections []Selection, task *objectTask) {
+	if q.index == nil {
+		q.index = make(map[batchKey]*batch)
+	}
+	b, ok := q.index[key]
+	if !ok {
+		b = &batch{typ: key.typ, selections: selections}
+		q.index[key] = b
+		q.batches = append(q.batches, b)
+	}
+	b.tasks = append(b.tasks, task)
+}
+
+func (x *execution) execute(rootValue any) *Response {
+	result := NewOrderedMap()
+	x.data = result
+	rootSlot := &slot{set: func(v any) { x.data = v }}
+	task := &objectTask{source: rootValue, result: result, slot: rootSlot}
+
+	if x.op.Type == Mutation {
+		// Root mutation fields run one after the other, each with its whole
+		// selection tree, so later fields observe the effects of earlier ones.
+		fields := x.collectFields(x.root, x.op.SelectionSet)
+		for _, key := range fields.keys {
+			result.Set(key, nil)
+		}
+		for _, key := range fields.keys {
+			var next queue
+			x.executeField(x.root, []*objectTask{task}, key, fields.fields[key], &next)
+			x.flush(&next)
+			x.drain(next.batches)
+		}
+	} else {
+		x.drain([]*batch{{typ: x.root, selections: x.op.SelectionSet, tasks: []*objectTask{task}}})
+	}
+	return &Response{Data: x.data, Errors: x.errs, executed: true}
+}
+
+// drain executes batches level by level until no objects remain.
+func (x *execution) drain(level []*batch) {
+	for len(level) > 0 {
+		var next queue
+		for _, b := range level {
+			x.executeBatch(b, &next)
+		}
+		x.flush(&next)
+		level = next.batches
+	}
+}
+
+// flush calls the thunks returned while resolving the current level and
+// completes their values. Thunks may return further thunks.
+func (x *execution) flush(next *queue) {
+	for len(x.deferred) > 0 {
+		pending := x.deferred
+		x.deferred = nil
+		for _, d := range pending {
+			if !d.slot.alive() {
+				continue
+			}
+			value, err := x.force(d)
+			if err != nil {
+				x.fieldError(err, d.fields, d.path)
+				x.nullify(d.slot)
+				continue
+			}
+			if thunk, ok := value.(Thunk); ok {
+				d.thunk = thunk
+				x.deferred = append(x.deferred, d)
+				continue
+			}
+			x.complete(d.parent, d.def.Type, value, d.slot, d.fields, d.path, next)
+		}
+	}
+}
+
+func (x *execution) force(d *deferredField) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("internal error resolving %s.%s: %v", d.parent.Name, d.fields[0].Name, r)
+		}
+	}()
+	return d.thunk()
+}
+
+func (x *execution) executeBatch(b *batch, next *queue) {
+	tasks := b.tasks[:0:0]
+	for _, task := range b.tasks {
+		if task.slot.alive() {
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		return
+	}
+	fields := x.collectFields(b.typ, b.selections)
+	for _, task := range tasks {
+		for _, key := range fields.keys {
+			task.result.Set(key, nil)
+		}
+	}
+	for _, key := range fields.keys {
+		x.executeField(b.typ, tasks, key, fields.fields[key], next)
+	}
+}
+
+// executeField resolves one response key for every task and completes the
+// values.
+func (x *execution) executeField(parent *Type, tasks []*objectTask, key string, fields []*Field, next *queue) {
+	field := fields[0]
+	def := x.fieldDef(parent, field.Name)
+	if def == nil {
+		return
+	}
+
+	for _, task := range tasks {
+		if !task.slot.alive() {
+			continue
+		}
+		result := task.result
+		path := appendPath(task.path, key)
+		fieldSlot := &slot{parent: task.slot, nonNull: def.Type.Kind == KindNonNull, set: func(v any) { result.Set(key, v) }}
+
+		value, err := x.resolve(parent, def, task.source, fields, path)
+		if err != nil {
+			x.fieldError(err, fields, path)
+			x.nullify(fieldSlot)
+			continue
+		}
+		if thunk, ok := value.(Thunk); ok {
+			x.deferred = append(x.deferred, &deferredField{parent: parent, def: def, thunk: thunk, slot: fieldSlot, fields: fields, path: path})
+			continue
+		}
+		x.complete(parent, def.Type, value, fieldSlot, fields, path, next)
+	}
+}
+
+func (x *execution) fieldDef(parent *Type, name string) *FieldDef {
+	if name == "__typename" {
+		return x.ex.cfg.Schema.typename
+	}
+	if def := metaFieldDef(x.ex.cfg.Schema, parent, name); def != nil {
+		return def
+	}
+	return parent.Field(name)
+}
+
+func (x *execution) resolve(parent *Type, def *FieldDef, source any, fields []*Field, path []any) (value any, err error) {
+	field := fields[0]
+	if field.Name == "__typename" {
+		return parent.Name, nil
+	}
+	if err := x.ctx.Err(); err != nil {
+		return nil, err
+	}
+	args, err := coerceArguments(def.Args, field.Arguments, x.vars)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("internal error resolving %s.%s: %v", parent.Name, field.Name, r)
+		}
+	}()
+	params := x.params(parent, def, source, fields, path, args)
+	if resolver := x.ex.cfg.Resolvers[parent.Name][field.Name]; resolver != nil {
+		return resolver(params)
+	}
+	if resolver := x.ex.meta[parent.Name][field.Name]; resolver != nil {
+		return resolver(params)
+	}
+	if parent == x.ex.cfg.Schema.Subscription {
+		// The root value of a subscription event execution is the event.
+		return source, nil
+	}
+	return defaultResolver(params)
+}
+
+func (x *execution) params(parent *Type, def *FieldDef, source any, fields []*Field, path []any, args map[string]any) ResolveParams {
+	return ResolveParams{
+		Context: x.ctx,
+		Source:  source,
+		Args:    args,
+		Info: ResolveInfo{
+			FieldName:  fields[0].Name,
+			ParentType: parent,
+			ReturnType: def.Type,
+			Path:       path,
+			Fields:     fields,
+			Operation:  x.op,
+			Document:   x.doc,
+			Variables:  x.vars,
+			Schema:     x.ex.cfg.Schema,
+		},
+	}
+}
+
+// defaultResolver reads a field from a map or struct parent.
+func defaultResolver(p ResolveParams) (any, error) {
+	name := p.Info.FieldName
+	if m, ok := p.Source.(map[string]any); ok {
+		return m[name], nil
+	}
+	rv := reflect.ValueOf(p.Source)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if tag == name || tag == "" && strings.EqualFold(sf.Name, name) {
+			return rv.Field(i).Interface(), nil
+		}
+	}
+	return nil, nil
+}
+
+// complete writes value, of type t, to s, queueing objects in next.
+func (x *execution) complete(parent *Type, t *Type, value any, s *slot, fields []*Field, path []any, next *queue) {
+	if t.Kind == KindNonNull {
+		if isNil(value) {
+			x.fieldError(fmt.Errorf("Cannot return null for non-nullable field %s.%s.", parent.Name, fields[0].Name), fields, path)
+			x.nullify(s)
+			return
+		}
+		t = t.OfType
+	}
+	if isNil(value) {
+		s.set(nil)
+		return
+	}
+
+	switch t.Kind {
+	case KindList:
+		rv := reflect.ValueOf(value)
+		for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			x.fieldError(fmt.Errorf("Expected Iterable, but did not find one for field %s.%s.", parent.Name, fields[0].Name), fields, path)
+			x.nullify(s)
+			return
+		}
+		items := make([]any, rv.Len())
+		s.set(items)
+		for i := range items {
+			itemSlot := &slot{parent: s, nonNull: t.OfType.Kind == KindNonNull, set: func(v any) { items[i] = v }}
+			x.complete(parent, t.OfType, rv.Index(i).Interface(), itemSlot, fields, appendPath(path, i), next)
+			if !s.alive() {
+				return
+			}
+		}
+	case KindScalar, KindEnum:
+		serialized, err := serializeLeaf(t, value)
+		if err != nil {
+			x.fieldError(err, fields, path)
+			x.nullify(s)
+			return
+		}
+		s.set(serialized)
+	default:
+		objType, err := x.resolveType(t, value)
+		if err != nil {
+			x.fieldError(err, fields, path)
+			x.nullify(s)
+			return
+		}
+		result := NewOrderedMap()
+		s.set(result)
+		var selections []Selection
+		for _, f := range fields {
+			selections = append(selections, f.SelectionSet...)
+		}
+		next.add(batchKey{field: fields[0], typ: objType}, selections, &objectTask{
+			source: value,
+			result: result,
+			slot:   s,
+			path:   path,
+		})
+	}
+}
+
+func (x *execution) resolveType(t *Type, value any) (*Type, error) {
+	if t.Kind == KindObject {
+		return t, nil
+	}
+	var name string
+	if m, ok := value.(map[string]any); ok {
+		name, _ = m["__typename"].(string)
+	}
+	if name == "" {
+		if resolver := x.ex.cfg.TypeResolvers[t.Name]; resolver != nil {
+			name = resolver(value)
+		}
+	}
+	objType := x.ex.cfg.Schema.Type(name)
+	if objType == nil || objType.Kind != KindObject || !objType.Implements(t) {
+		return nil, fmt.Errorf("Abstract type %q must resolve to an Object type at runtime, received %q.", t.Name, name)
+	}
+	return objType, nil
+}
+
+// nullify writes null at s, or at the nearest nullable enclosing slot, and
+// marks the values below it as discarded.
+func (x *execution) nullify(s *slot) {
+	for ; s != nil; s = s.parent {
+		s.dead = true
+		if !s.nonNull {
+			s.set(nil)
+			return
+		}
+	}
+	x.data = nil
+}
+
+func (x *execution) fieldError(err error, fields []*Field, path []any) {
+	gqlErr := &Error{Message: err.Error(), Err: err}
+	var existing *Error
+	if errors.As(err, &existing) {
+		gqlErr.Message = existing.Message
+		gqlErr.Extensions = existing.Extensions
+	}
+	if gqlErr.Extensions == nil && x.ex.cfg.ErrorExtensions != nil {
+		gqlErr.Extensions = x.ex.cfg.ErrorExtensions(err)
+	}
+	gqlErr.Locations = []Location{fields[0].Loc}
+	gqlErr.Path = append([]any(nil), path...)
+	x.errs = append(x.errs, gqlErr)
+}
+
+// collectedFields are the fields selected on an object, grouped by response
+// key in selection order.
+type collectedFields struct {
+	keys   []string
+	fields map[string][]*Field
+}
+
+func (x *execution) collectFields(t *Type, selections []Selection) *collectedFields {
+	collected := &collectedFields{fields: make(map[string][]*Field)}
+	x.collectInto(t, selections, collected, make(map[string]bool))
+	return collected
+}
+
+func (x *execution) collectInto(t *Type, selections []Selection, collected *collectedFields, visited map[string]bool) {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *Field:
+			if !x.included(sel.Directives) {
+				continue
+			}
+			key := sel.ResponseKey()
+			if _, seen := collected.fields[key]; !seen {
+				collected.keys = append(collected.keys, key)
+			}
+			collected.fields[key] = append(collected.fields[key], sel)
+		case *InlineFragment:
+			if !x.included(sel.Directives) || !x.fragmentApplies(t, sel.TypeCondition) {
+				continue
+			}
+			x.collectInto(t, sel.SelectionSet, collected, visited)
+		case *FragmentSpread:
+			if !x.included(sel.Directives) || visited[sel.Name] {
+				continue
+			}
+			visited[sel.Name] = true
+			fragment := x.fragments[sel.Name]
+			if fragment == nil || !x.fragmentApplies(t, fragment.TypeCondition) {
+				continue
+			}
+			x.collectInto(t, fragment.SelectionSet, collected, visited)
+		}
+	}
+}
+
+func (x *execution) fragmentApplies(t *Type, condition string) bool {
+	if condition == "" || condition == t.Name {
+		return true
+	}
+	conditionType := x.ex.cfg.Schema.Type(condition)
+	return conditionType != nil && conditionType.IsAbstract() && t.Implements(conditionType)
+}
+
+// included evaluates @skip and @include.
+func (x *execution) included(directives []*Directive) bool {
+	for _, d := range directives {
+		if d.Name != "skip" && d.Name != "include" {
+			continue
+		}
+		def := x.ex.cfg.Schema.Directives[d.Name]
+		args, err := coerceArguments(def.Args, d.Arguments, x.vars)
+		if err != nil {
+			continue
+		}
+		if cond, _ := args["if"].(bool); cond == (d.Name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+func appendPath(path []any, segment any) []any {
+	next := make([]any, len(path)+1)
+	copy(next, path)
+	next[len(path)] = segment
+	return next
+}
+
+// isNil reports whether value completes to null. Nil slices are empty lists,
+// as is idiomatic in Go.
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,316 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// testSchema is the schema the engine tests run against.
+const testSchema = `
+schema { query: Query mutation: Mutation }
+
+"A character of the saga."
+interface Character {
+  id: ID!
+  name: String!
+  friends: [Character!]!
+}
+
+type Human implements Character {
+  id: ID!
+  name: String!
+  friends: [Character!]!
+  height(unit: Unit = METER): Float
+  homePlanet: String @deprecated(reason: "Use planet.")
+  planet: String
+}
+
+type Droid implements Character {
+  id: ID!
+  name: String!
+  friends: [Character!]!
+  primaryFunction: String
+}
+
+union SearchResult = Human | Droid
+
+enum Unit {
+  METER
+  FOOT @deprecated
+}
+
+enum Episode { NEWHOPE EMPIRE JEDI }
+
+input ReviewInput {
+  stars: Int!
+  commentary: String
+  tags: [String!] = []
+  episode: Episode = NEWHOPE
+}
+
+type Review {
+  stars: Int!
+  commentary: String
+  episode: Episode
+}
+
+type Query {
+  hero(episode: Episode): Character
+  human(id: ID!): Human
+  droid(id: ID!): Droid!
+  search(text: String!): [SearchResult!]! @listSize(assumedSize: 5)
+  echo(review: ReviewInput, ids: [ID!], count: Int, ratio: Float, flag: Boolean, unit: Unit): String
+  failing: String
+  failingNonNull: String!
+}
+
+type Mutation {
+  createReview(episode: Episode!, review: ReviewInput!): Review
+  counter: Int! @cost(weight: 10)
+}
+`
+
+// characters are the objects served by the test resolvers. Maps carry their
+// __typename, so no type resolver is needed.
+func characters() map[string]map[string]any {
+	luke := map[string]any{"__typename": "Human", "id": "1000", "name": "Luke Skywalker", "height": 1.72, "homePlanet": "Tatooine", "planet": "Tatooine"}
+	r2 := map[string]any{"__typename": "Droid", "id": "2001", "name": "R2-D2", "primaryFunction": "Astromech"}
+	// A droid whose name failed to load.
+	broken := map[string]any{"__typename": "Droid", "id": "2999", "name": nil}
+	han := map[string]any{"__typename": "Human", "id": "1002", "name": "Han Solo"}
+	luke["friends"] = []any{r2, han}
+	r2["friends"] = []any{luke}
+	broken["friends"] = []any{}
+	han["friends"] = []any{luke, broken}
+	return map[string]map[string]any{"1000": luke, "1002": han, "2001": r2, "2999": broken}
+}
+
+var errUnavailable = errors.New("unavailable")
+
+func newTestExecutor(t *testing.T, limits Limits) *Executor {
+	t.Helper()
+	schema, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	byID := characters()
+	lookup := func(p ResolveParams) (any, error) {
+		if c, ok := byID[p.Args["id"].(string)]; ok {
+			return c, nil
+		}
+		return nil, nil
+	}
+	counter := 0
+	executor, err := NewExecutor(Config{
+		Schema: schema,
+		Resolvers: map[string]map[string]FieldResolver{
+			"Query": {
+				"hero": func(p ResolveParams) (any, error) {
+					if p.Args["episode"] == "EMPIRE" {
+						return byID["1000"], nil
+					}
+					return byID["2001"], nil
+				},
+				"human": lookup,
+				"droid": lookup,
+				"search": func(p ResolveParams) (any, error) {
+					return []any{byID["1000"], byID["2001"]}, nil
+				},
+				"echo": func(p ResolveParams) (any, error) {
+					encoded, err := json.Marshal(p.Args)
+					return string(encoded), err
+				},
+				"failing":        func(ResolveParams) (any, error) { return nil, errUnavailable },
+				"failingNonNull": func(ResolveParams) (any, error) { return nil, errUnavailable },
+			},
+			"Human": {
+				"height": func(p ResolveParams) (any, error) {
+					height, _ := p.Source.(map[string]any)["height"].(float64)
+					if p.Args["unit"] == "FOOT" {
+						return height * 4, nil
+					}
+					return height, nil
+				},
+			},
+			"Mutation": {
+				"createReview": func(p ResolveParams) (any, error) {
+					review := p.Args["review"].(map[string]any)
+					return map[string]any{"stars": review["stars"], "commentary": review["commentary"], "episode": p.Args["episode"]}, nil
+				},
+				"counter": func(ResolveParams) (any, error) {
+					counter++
+					return counter, nil
+				},
+			},
+		},
+		ErrorExtensions: func(err error) map[string]any {
+			if errors.Is(err, errUnavailable) {
+				return map[string]any{"code": "UNAVAILABLE"}
+			}
+			return nil
+		},
+		Limits: limits,
+	})
+	if err != nil {
+		t.Fatalf("NewExecutor: %v", err)
+	}
+	return executor
+}
+
+// run executes a request and returns its JSON encoding.
+func run(t *testing.T, executor *Executor, req Request) string {
+	t.Helper()
+	encoded, err := json.Marshal(executor.Execute(context.Background(), req))
+	if err != nil {
+		t.Fatalf("encode response: %v", err)
+	}
+	return string(encoded)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "Aliases",
+			req:  Request{Query: `{ luke: human(id: "1000") { name } han: human(id: "1002") { fullName: name } }`},
+			want: `{"data":{"luke":{"name":"Luke Skywalker"},"han":{"fullName":"Han Solo"}}}`,
+		},
+		{
+			name: "ArgumentsAndDefaults",
+			req:  Request{Query: `{ human(id: "1000") { metric: height imperial: height(unit: FOOT) } }`},
+			want: `{"data":{"human":{"metric":1.72,"imperial":6.88}}}`,
+		},
+		{
+			name: "FragmentsOnInterfaces",
+			req: Request{Query: `
+				{ hero { ...Names ... on Droid { primaryFunction } ... on Human { planet } } }
+				fragment Names on Character { __typename name friends { name } }`},
+			want: `{"data":{"hero":{"__typename":"Droid","name":"R2-D2","friends":[{"name":"Luke Skywalker"}],"primaryFunction":"Astromech"}}}`,
+		},
+		{
+			name: "FragmentsOnUnions",
+			req:  Request{Query: `{ search(text: "") { __typename ... on Character { id } ... on Human { name } } }`},
+			want: `{"data":{"search":[{"__typename":"Human","id":"1000","name":"Luke Skywalker"},{"__typename":"Droid","id":"2001"}]}}`,
+		},
+		{
+			name: "MergedSelections",
+			req:  Request{Query: `{ hero { name } hero { id } }`},
+			want: `{"data":{"hero":{"name":"R2-D2","id":"2001"}}}`,
+		},
+		{
+			name: "SkipAndInclude",
+			req: Request{
+				Query:     `query ($withId: Boolean!) { hero { id @include(if: $withId) name @skip(if: true) ... @skip(if: $withId) { friends { id } } } }`,
+				Variables: map[string]any{"withId": true},
+			},
+			want: `{"data":{"hero":{"id":"2001"}}}`,
+		},
+		{
+			name: "OperationName",
+			req: Request{
+				Query:         `query R2 { hero { name } } query Luke { hero(episode: EMPIRE) { name } }`,
+				OperationName: "Luke",
+			},
+			want: `{"data":{"hero":{"name":"Luke Skywalker"}}}`,
+		},
+		{
+			name: "MissingOperationName",
+			req:  Request{Query: `query R2 { hero { name } } query Luke { hero(episode: EMPIRE) { name } }`},
+			want: `{"errors":[{"message":"Must provide operation name if query contains multiple operations."}]}`,
+		},
+		{
+			name: "UnknownOperationName",
+			req:  Request{Query: `query R2 { hero { name } }`, OperationName: "Leia"},
+			want: `{"errors":[{"message":"Unknown operation named \"Leia\"."}]}`,
+		},
+		{
+			name: "NullableFieldError",
+			req:  Request{Query: `{ failing hero { name } }`},
+			want: `{"errors":[{"message":"unavailable","locations":[{"line":1,"column":3}],"path":["failing"],"extensions":{"code":"UNAVAILABLE"}}],"data":{"failing":null,"hero":{"name":"R2-D2"}}}`,
+		},
+		{
+			name: "NonNullFieldErrorNullsData",
+			req:  Request{Query: `{ hero { name } failingNonNull }`},
+			want: `{"errors":[{"message":"unavailable","locations":[{"line":1,"column":17}],"path":["failingNonNull"],"extensions":{"code":"UNAVAILABLE"}}],"data":null}`,
+		},
+		{
+			name: "NullPropagatesToNullableParent",
+			req:  Request{Query: `{ han: human(id: "1002") { name friends { name } } luke: human(id: "1000") { name } }`},
+			want: `{"errors":[{"message":"Cannot return null for non-nullable field Droid.name.","locations":[{"line":1,"column":43}],"path":["han","friends",1,"name"]}],"data":{"han":null,"luke":{"name":"Luke Skywalker"}}}`,
+		},
+		{
+			name: "NullForNonNullRootField",
+			req:  Request{Query: `{ droid(id: "404") { name } }`},
+			want: `{"errors":[{"message":"Cannot return null for non-nullable field Query.droid.","locations":[{"line":1,"column":3}],"path":["droid"]}],"data":null}`,
+		},
+		{
+			name: "MutationsRunInOrder",
+			req:  Request{Query: `mutation { first: counter second: counter third: counter }`},
+			want: `{"data":{"first":1,"second":2,"third":3}}`,
+		},
+		{
+			name: "InputObjectDefaults",
+			req: Request{
+				Query:     `mutation ($review: ReviewInput!) { createReview(episode: JEDI, review: $review) { stars commentary episode } }`,
+				Variables: map[string]any{"review": map[string]any{"stars": float64(5)}},
+			},
+			want: `{"data":{"createReview":{"stars":5,"commentary":null,"episode":"JEDI"}}}`,
+		},
+		{
+			name: "ValidationErrorsSkipExecution",
+			req:  Request{Query: `{ hero { height } }`},
+			want: `{"errors":[{"message":"Cannot query field \"height\" on type \"Character\".","locations":[{"line":1,"column":10}]}]}`,
+		},
+		{
+			name: "SubscriptionsNeedSubscribe",
+			req:  Request{Query: `subscription { hero { name } }`},
+			want: `{"errors":[{"message":"Schema is not configured for subscriptions.","locations":[{"line":1,"column":1}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(t, newTestExecutor(t, Limits{}), tt.req); got != tt.want {
+				t.Errorf("response\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewExecutorRejectsUnknownResolvers(t *testing.T) {
+	schema, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	_, err = NewExecutor(Config{
+		Schema: schema,
+		Resolvers: map[string]map[string]FieldResolver{
+			"Query":    {"villain": defaultResolver},
+			"Unit":     {"METER": defaultResolver},
+			"__Schema": {"types": defaultResolver},
+		},
+		TypeResolvers: map[string]TypeResolver{"Human": func(any) string { return "Human" }},
+	})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 4 {
+		t.Fatalf("NewExecutor error = %v, want four errors", err)
+	}
+}
+
+func TestRequireResolvers(t *testing.T) {
+	schema, err := ParseSchema(`type Query { a: String b: String }`)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	resolve := func(ResolveParams) (any, error) { return "a", nil }
+	if _, err := NewExecutor(Config{Schema: schema, Resolvers: map[string]map[string]FieldResolver{"Query": {"a": resolve}}, RequireResolvers: true}); err == nil {
+		t.Fatal("NewExecutor accepted a field without a resolver")
+	}
+	if _, err := NewExecutor(Config{Schema: schema, Resolvers: map[string]map[string]FieldResolver{"Query": {"a": resolve, "b": resolve}}, RequireResolvers: true}); err != nil {
+		t.Fatalf("NewExecutor: %v", err)
+	}
+}
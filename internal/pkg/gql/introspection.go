@@ -0,0 +1,369 @@
+package gql
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+const introspectionDefinitions = `
+"A GraphQL Schema defines the capabilities of a GraphQL server."
+type __Schema {
+  description: String
+  types: [__Type!]!
+  queryType: __Type!
+  mutationType: __Type
+  subscriptionType: __Type
+  directives: [__Directive!]!
+}
+
+"The fundamental unit of any GraphQL Schema is the type."
+type __Type {
+  kind: __TypeKind!
+  name: String
+  description: String
+  specifiedByURL: String
+  fields(includeDeprecated: Boolean = false): [__Field!]
+  interfaces: [__Type!]
+  possibleTypes: [__Type!]
+  enumValues(includeDeprecated: Boolean = false): [__EnumValue!]
+  inputFields(includeDeprecated: Boolean = false): [__InputValue!]
+  ofType: __Type
+}
+
+"An enum describing what kind of type a given __Type is."
+enum __TypeKind {
+  SCALAR
+  OBJECT
+  INTERFACE
+  UNION
+  ENUM
+  INPUT_OBJECT
+  LIST
+  NON_NULL
+}
+
+"Object and Interface types are described by a list of Fields."
+type __Field {
+  name: String!
+  description: String
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  type: __Type!
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+"Arguments provided to Fields or Directives and the input fields of an InputObject."
+type __InputValue {
+  name: String!
+  description: String
+  type: __Type!
+  defaultValue: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+"One possible value for a given Enum."
+type __EnumValue {
+  name: String!
+  description: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+"A Directive provides a way to describe alternate runtime execution and type validation behavior."
+type __Directive {
+  name: String!
+  description: String
+  isRepeatable: Boolean!
+  locations: [__DirectiveLocation!]!
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+}
+
+"A Directive can be adjacent to many parts of the GraphQL language."
+enum __DirectiveLocation {
+  QUERY
+  MUTATION
+  SUBSCRIPTION
+  FIELD
+  FRAGMENT_DEFINITION
+  FRAGMENT_SPREAD
+  INLINE_FRAGMENT
+  VARIABLE_DEFINITION
+  SCHEMA
+  SCALAR
+  OBJECT
+  FIELD_DEFINITION
+  ARGUMENT_DEFINITION
+  INTERFACE
+  UNION
+  ENUM
+  ENUM_VALUE
+  INPUT_OBJECT
+  INPUT_FIELD_DEFINITION
+}
+`
+
+// metaFieldDef returns the __schema and __type fields available on the
+// query root type.
+func metaFieldDef(s *Schema, parent *Type, name string) *FieldDef {
+	if parent != s.Query {
+		return nil
+	}
+	switch name {
+	case "__schema":
+		return s.schemaField
+	case "__type":
+		return s.typeField
+	}
+	return nil
+}
+
+func (b *schemaBuilder) addMetaFields() {
+	s := b.schema
+	s.typename = &FieldDef{
+		Name:        "__typename",
+		Description: "The name of the current Object type at runtime.",
+		Type:        nonNullOf(s.Types["String"]),
+	}
+	s.schemaField = &FieldDef{
+		Name:        "__schema",
+		Description: "Access the current type schema of this server.",
+		Type:        nonNullOf(s.Types["__Schema"]),
+	}
+	s.typeField = &FieldDef{
+		Name:        "__type",
+		Description: "Request the type information of a single type.",
+		Type:        s.Types["__Type"],
+		Args:        []*InputValue{{Name: "name", Type: nonNullOf(s.Types["String"])}},
+	}
+}
+
+// isIntrospectionType reports whether name belongs to the introspection
+// system rather than to the application schema.
+func isIntrospectionType(name string) bool {
+	return strings.HasPrefix(name, "__")
+}
+
+func introspectionResolvers(s *Schema) map[string]map[string]FieldResolver {
+	includeDeprecated := func(p ResolveParams) bool {
+		include, _ := p.Args["includeDeprecated"].(bool)
+		return include
+	}
+	return map[string]map[string]FieldResolver{
+		s.Query.Name: {
+			"__schema": func(p ResolveParams) (any, error) { return s, nil },
+			"__type": func(p ResolveParams) (any, error) {
+				name, _ := p.Args["name"].(string)
+				if t := s.Type(name); t != nil {
+					return t, nil
+				}
+				return nil, nil
+			},
+		},
+		"__Schema": {
+			"description": func(p ResolveParams) (any, error) { return nil, nil },
+			"types": func(p ResolveParams) (any, error) {
+				types := make([]*Type, 0, len(s.Types))
+				for _, name := range s.TypeNames() {
+					types = append(types, s.Types[name])
+				}
+				return types, nil
+			},
+			"queryType":        func(p ResolveParams) (any, error) { return s.Query, nil },
+			"mutationType":     func(p ResolveParams) (any, error) { return s.Mutation, nil },
+			"subscriptionType": func(p ResolveParams) (any, error) { return s.Subscription, nil },
+			"directives": func(p ResolveParams) (any, error) {
+				names := make([]string, 0, len(s.Directives))
+				for name := range s.Directives {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				directives := make([]*DirectiveDef, 0, len(names))
+				for _, name := range names {
+					directives = append(directives, s.Directives[name])
+				}
+				return directives, nil
+			},
+		},
+		"__Type": {
+			"kind": typeResolver(func(t *Type) any { return string(t.Kind) }),
+			"name": typeResolver(func(t *Type) any {
+				if t.Name == "" {
+					return nil
+				}
+				return t.Name
+			}),
+			"description": typeResolver(func(t *Type) any { return optionalString(t.Description) }),
+			"specifiedByURL": typeResolver(func(t *Type) any {
+				for _, d := range t.Directives {
+					if d.Name != "specifiedBy" {
+						continue
+					}
+					for _, arg := range d.Arguments {
+						if arg.Name == "url" {
+							return arg.Value.Raw
+						}
+					}
+				}
+				return nil
+			}),
+			"fields": func(p ResolveParams) (any, error) {
+				t := p.Source.(*Type)
+				if t.Kind != KindObject && t.Kind != KindInterface {
+					return nil, nil
+				}
+				fields := make([]*FieldDef, 0, len(t.Fields))
+				for _, f := range t.Fields {
+					if f.DeprecationReason == nil || includeDeprecated(p) {
+						fields = append(fields, f)
+					}
+				}
+				return fields, nil
+			},
+			"interfaces": typeResolver(func(t *Type) any {
+				if t.Kind != KindObject && t.Kind != KindInterface {
+					return nil
+				}
+				return append([]*Type{}, t.Interfaces...)
+			}),
+			"possibleTypes": typeResolver(func(t *Type) any {
+				if !t.IsAbstract() {
+					return nil
+				}
+				return t.PossibleTypes
+			}),
+			"enumValues": func(p ResolveParams) (any, error) {
+				t := p.Source.(*Type)
+				if t.Kind != KindEnum {
+					return nil, nil
+				}
+				values := make([]*EnumValueDef, 0, len(t.EnumValues))
+				for _, v := range t.EnumValues {
+					if v.DeprecationReason == nil || includeDeprecated(p) {
+						values = append(values, v)
+					}
+				}
+				return values, nil
+			},
+			"inputFields": func(p ResolveParams) (any, error) {
+				t := p.Source.(*Type)
+				if t.Kind != KindInputObject {
+					return nil, nil
+				}
+				return filterInputValues(t.InputFields, includeDeprecated(p)), nil
+			},
+			"ofType": typeResolver(func(t *Type) any { return t.OfType }),
+		},
+		"__Field": {
+			"name":        fieldResolver(func(f *FieldDef) any { return f.Name }),
+			"description": fieldResolver(func(f *FieldDef) any { return optionalString(f.Description) }),
+			"args": func(p ResolveParams) (any, error) {
+				return filterInputValues(p.Source.(*FieldDef).Args, includeDeprecated(p)), nil
+			},
+			"type":              fieldResolver(func(f *FieldDef) any { return f.Type }),
+			"isDeprecated":      fieldResolver(func(f *FieldDef) any { return f.DeprecationReason != nil }),
+			"deprecationReason": fieldResolver(func(f *FieldDef) any { return f.DeprecationReason }),
+		},
+		"__InputValue": {
+			"name":        inputValueResolver(func(v *InputValue) any { return v.Name }),
+			"description": inputValueResolver(func(v *InputValue) any { return optionalString(v.Description) }),
+			"type":        inputValueResolver(func(v *InputValue) any { return v.Type }),
+			"defaultValue": inputValueResolver(func(v *InputValue) any {
+				if v.DefaultValue == nil {
+					return nil
+				}
+				return printValue(v.DefaultValue)
+			}),
+			"isDeprecated":      inputValueResolver(func(v *InputValue) any { return v.DeprecationReason != nil }),
+			"deprecationReason": inputValueResolver(func(v *InputValue) any { return v.DeprecationReason }),
+		},
+		"__EnumValue": {
+			"name":              enumValueResolver(func(v *EnumValueDef) any { return v.Name }),
+			"description":       enumValueResolver(func(v *EnumValueDef) any { return optionalString(v.Description) }),
+			"isDeprecated":      enumValueResolver(func(v *EnumValueDef) any { return v.DeprecationReason != nil }),
+			"deprecationReason": enumValueResolver(func(v *EnumValueDef) any { return v.DeprecationReason }),
+		},
+		"__Directive": {
+			"name":         directiveResolver(func(d *DirectiveDef) any { return d.Name }),
+			"description":  directiveResolver(func(d *DirectiveDef) any { return optionalString(d.Description) }),
+			"isRepeatable": directiveResolver(func(d *DirectiveDef) any { return d.IsRepeatable }),
+			"locations":    directiveResolver(func(d *DirectiveDef) any { return d.Locations }),
+			"args": func(p ResolveParams) (any, error) {
+				return filterInputValues(p.Source.(*DirectiveDef).Args, includeDeprecated(p)), nil
+			},
+		},
+	}
+}
+
+func typeResolver(get func(*Type) any) FieldResolver {
+	return func(p ResolveParams) (any, error) { return get(p.Source.(*Type)), nil }
+}
+
+func fieldResolver(get func(*FieldDef) any) FieldResolver {
+	return func(p ResolveParams) (any, error) { return get(p.Source.(*FieldDef)), nil }
+}
+
+func inputValueResolver(get func(*InputValue) any) FieldResolver {
+	return func(p ResolveParams) (any, error) { return get(p.Source.(*InputValue)), nil }
+}
+
+func enumValueResolver(get func(*EnumValueDef) any) FieldResolver {
+	return func(p ResolveParams) (any, error) { return get(p.Source.(*EnumValueDef)), nil }
+}
+
+func directiveResolver(get func(*DirectiveDef) any) FieldResolver {
+	return func(p ResolveParams) (any, error) { return get(p.Source.(*DirectiveDef)), nil }
+}
+
+func filterInputValues(values []*InputValue, includeDeprecated bool) []*InputValue {
+	result := make([]*InputValue, 0, len(values))
+	for _, v := range values {
+		if v.DeprecationReason == nil || includeDeprecated {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// checkCoverage reports every field of the application schema that has no
+// resolver or stream and every abstract type that cannot be resolved to an
+// object type.
+func checkCoverage(cfg Config) error {
+	var missing []string
+	for _, name := range cfg.Schema.TypeNames() {
+		t := cfg.Schema.Types[name]
+		if isIntrospectionType(name) {
+			continue
+		}
+		switch {
+		case t == cfg.Schema.Subscription:
+			for _, f := range t.Fields {
+				if cfg.Streams[f.Name] == nil {
+					missing = append(missing, name+"."+f.Name+" (stream)")
+				}
+			}
+		case t.Kind == KindObject:
+			for _, f := range t.Fields {
+				if cfg.Resolvers[name][f.Name] == nil {
+					missing = append(missing, name+"."+f.Name)
+				}
+			}
+		case t.IsAbstract():
+			if cfg.TypeResolvers[name] == nil {
+				missing = append(missing, name+" (type resolver)")
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("gql: schema has no resolvers for " + strings.Join(missing, ", "))
+	}
+	return nil
+}
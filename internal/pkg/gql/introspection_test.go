@@ -0,0 +1,266 @@
+package gql
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestIntrospection(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "Typename",
+			query: `{ __typename hero { __typename ... on Droid { kind: __typename } } }`,
+			want:  `{"data":{"__typename":"Query","hero":{"__typename":"Droid","kind":"Droid"}}}`,
+		},
+		{
+			name:  "TypenameOnMutation",
+			query: `mutation { __typename }`,
+			want:  `{"data":{"__typename":"Mutation"}}`,
+		},
+		{
+			name:  "Type",
+			query: `{ __type(name: "Human") { kind name interfaces { name } possibleTypes { name } enumValues { name } inputFields { name } } }`,
+			want:  `{"data":{"__type":{"kind":"OBJECT","name":"Human","interfaces":[{"name":"Character"}],"possibleTypes":null,"enumValues":null,"inputFields":null}}}`,
+		},
+		{
+			name:  "UnknownType",
+			query: `{ __type(name: "Wookiee") { name } }`,
+			want:  `{"data":{"__type":null}}`,
+		},
+		{
+			name:  "WrappedTypes",
+			query: `{ __type(name: "Character") { description possibleTypes { name } fields { name type { kind name ofType { kind name ofType { kind name ofType { name } } } } } } }`,
+			want: `{"data":{"__type":{"description":"A character of the saga.","possibleTypes":[{"name":"Human"},{"name":"Droid"}],"fields":[` +
+				`{"name":"id","type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"ID","ofType":null}}},` +
+				`{"name":"name","type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"String","ofType":null}}},` +
+				`{"name":"friends","type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"LIST","name":null,"ofType":{"kind":"NON_NULL","name":null,"ofType":{"name":"Character"}}}}}]}}}`,
+		},
+		{
+			name:  "DeprecatedFieldsHidden",
+			query: `{ __type(name: "Human") { fields { name } } }`,
+			want:  `{"data":{"__type":{"fields":[{"name":"id"},{"name":"name"},{"name":"friends"},{"name":"height"},{"name":"planet"}]}}}`,
+		},
+		{
+			name:  "DeprecatedFields",
+			query: `{ __type(name: "Human") { fields(includeDeprecated: true) { name isDeprecated deprecationReason } } }`,
+			want: `{"data":{"__type":{"fields":[{"name":"id","isDeprecated":false,"deprecationReason":null},{"name":"name","isDeprecated":false,"deprecationReason":null},` +
+				`{"name":"friends","isDeprecated":false,"deprecationReason":null},{"name":"height","isDeprecated":false,"deprecationReason":null},` +
+				`{"name":"homePlanet","isDeprecated":true,"deprecationReason":"Use planet."},{"name":"planet","isDeprecated":false,"deprecationReason":null}]}}}`,
+		},
+		{
+			name:  "DeprecatedEnumValues",
+			query: `{ hidden: __type(name: "Unit") { enumValues { name } } all: __type(name: "Unit") { enumValues(includeDeprecated: true) { name isDeprecated deprecationReason } } }`,
+			want: `{"data":{"hidden":{"enumValues":[{"name":"METER"}]},"all":{"enumValues":[{"name":"METER","isDeprecated":false,"deprecationReason":null},` +
+				`{"name":"FOOT","isDeprecated":true,"deprecationReason":"No longer supported"}]}}}`,
+		},
+		{
+			name:  "ArgumentsAndDefaults",
+			query: `{ __type(name: "ReviewInput") { inputFields { name defaultValue type { name } } } }`,
+			want: `{"data":{"__type":{"inputFields":[{"name":"stars","defaultValue":null,"type":{"name":null}},{"name":"commentary","defaultValue":null,"type":{"name":"String"}},` +
+				`{"name":"tags","defaultValue":"[]","type":{"name":null}},{"name":"episode","defaultValue":"NEWHOPE","type":{"name":"Episode"}}]}}}`,
+		},
+		{
+			name:  "RootTypes",
+			query: `{ __schema { queryType { name } mutationType { name } subscriptionType { name } } }`,
+			want:  `{"data":{"__schema":{"queryType":{"name":"Query"},"mutationType":{"name":"Mutation"},"subscriptionType":null}}}`,
+		},
+		{
+			name:  "Directives",
+			query: `{ __schema { directives { name isRepeatable locations args { name defaultValue } } } }`,
+			want: `{"data":{"__schema":{"directives":[` +
+				`{"name":"cost","isRepeatable":false,"locations":["FIELD_DEFINITION"],"args":[{"name":"weight","defaultValue":null}]},` +
+				`{"name":"deprecated","isRepeatable":false,"locations":["FIELD_DEFINITION","ARGUMENT_DEFINITION","INPUT_FIELD_DEFINITION","ENUM_VALUE"],"args":[{"name":"reason","defaultValue":"\"No longer supported\""}]},` +
+				`{"name":"include","isRepeatable":false,"locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","defaultValue":null}]},` +
+				`{"name":"listSize","isRepeatable":false,"locations":["FIELD_DEFINITION"],"args":[{"name":"assumedSize","defaultValue":null}]},` +
+				`{"name":"skip","isRepeatable":false,"locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","defaultValue":null}]},` +
+				`{"name":"specifiedBy","isRepeatable":false,"locations":["SCALAR"],"args":[{"name":"url","defaultValue":null}]}]}}}`,
+		},
+		{
+			name:  "MetaFieldsOnlyOnQuery",
+			query: `{ hero { __schema { types { name } } } }`,
+			want:  `{"errors":[{"message":"Cannot query field \"__schema\" on type \"Character\".","locations":[{"line":1,"column":10}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(t, newTestExecutor(t, Limits{}), Request{Query: tt.query}); got != tt.want {
+				t.Errorf("response\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// introspectionQuery is the query GraphiQL and code generators send, as
+// printed by graphql-js with every option enabled.
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    description
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives {
+      name
+      description
+      isRepeatable
+      locations
+      args(includeDeprecated: true) { ...InputValue }
+    }
+  }
+}
+
+fragment FullType on __Type {
+  kind
+  name
+  description
+  specifiedByURL
+  fields(includeDeprecated: true) {
+    name
+    description
+    args(includeDeprecated: true) { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated
+    deprecationReason
+  }
+  inputFields(includeDeprecated: true) { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) {
+    name
+    description
+    isDeprecated
+    deprecationReason
+  }
+  possibleTypes { ...TypeRef }
+}
+
+fragment InputValue on __InputValue {
+  name
+  description
+  type { ...TypeRef }
+  defaultValue
+  isDeprecated
+  deprecationReason
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType {
+    kind
+    name
+    ofType {
+      kind
+      name
+      ofType {
+        kind
+        name
+        ofType {
+          kind
+          name
+          ofType {
+            kind
+            name
+            ofType {
+              kind
+              name
+              ofType {
+                kind
+                name
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+// introspectedType is the part of the full introspection result the test
+// checks.
+type introspectedType struct {
+	Kind   string
+	Name   string
+	Fields []struct {
+		Name              string
+		Args              []struct{ Name string }
+		Type              typeRef
+		IsDeprecated      bool
+		DeprecationReason *string
+	}
+	PossibleTypes []typeRef
+}
+
+type typeRef struct {
+	Kind   string
+	Name   *string
+	OfType *typeRef
+}
+
+// TestFullIntrospectionQuery runs the introspection query of GraphiQL, whose
+// depth exceeds common limits, under tight limits, and reads the schema back
+// from the result.
+func TestFullIntrospectionQuery(t *testing.T) {
+	got := run(t, newTestExecutor(t, Limits{MaxDepth: 2, MaxCost: 1}), Request{Query: introspectionQuery})
+	var resp struct {
+		Data struct {
+			Schema struct {
+				QueryType    struct{ Name string }
+				MutationType struct{ Name string }
+				Types        []introspectedType
+				Directives   []struct{ Name string }
+			} `json:"__schema"`
+		}
+		Errors []any
+	}
+	if err := json.Unmarshal([]byte(got), &resp); err != nil || len(resp.Errors) > 0 {
+		t.Fatalf("introspection failed: %.500s", got)
+	}
+	schema := resp.Data.Schema
+	if schema.QueryType.Name != "Query" || schema.MutationType.Name != "Mutation" || len(schema.Directives) != 6 {
+		t.Fatalf("schema = %+v", schema)
+	}
+
+	types := make(map[string]introspectedType)
+	for _, typ := range schema.Types {
+		types[typ.Name] = typ
+	}
+	for name, kind := range map[string]string{
+		"Query": "OBJECT", "Character": "INTERFACE", "SearchResult": "UNION", "Unit": "ENUM",
+		"ReviewInput": "INPUT_OBJECT", "ID": "SCALAR", "__Schema": "OBJECT", "__TypeKind": "ENUM",
+	} {
+		if types[name].Kind != kind {
+			t.Errorf("type %s has kind %q, want %q", name, types[name].Kind, kind)
+		}
+	}
+	if possible := types["SearchResult"].PossibleTypes; len(possible) != 2 || *possible[0].Name != "Human" || *possible[1].Name != "Droid" {
+		t.Errorf("possible types of SearchResult = %+v", possible)
+	}
+
+	human := types["Human"]
+	var deprecated []string
+	for _, field := range human.Fields {
+		if field.IsDeprecated {
+			deprecated = append(deprecated, field.Name+": "+*field.DeprecationReason)
+		}
+		if field.Name == "friends" {
+			// [Character!]!
+			ref := field.Type
+			if ref.Kind != "NON_NULL" || ref.OfType.Kind != "LIST" || ref.OfType.OfType.Kind != "NON_NULL" || *ref.OfType.OfType.OfType.Name != "Character" {
+				t.Errorf("type of Human.friends = %+v", ref)
+			}
+		}
+		if field.Name == "height" && (len(field.Args) != 1 || field.Args[0].Name != "unit") {
+			t.Errorf("arguments of Human.height = %+v", field.Args)
+		}
+	}
+	if want := []string{"homePlanet: Use planet."}; !slices.Equal(deprecated, want) {
+		t.Errorf("deprecated fields of Human = %q, want %q", deprecated, want)
+	}
+}
@@ -0,0 +1,300 @@
+package gql
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+	tokenBlockString
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "<EOF>"
+	case tokenPunct:
+		return "punctuator"
+	case tokenName:
+		return "name"
+	case tokenInt:
+		return "int"
+	case tokenFloat:
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   Location
+}
+
+// lexer splits a GraphQL source document into tokens, skipping whitespace,
+// commas and comments as the specification requires.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	col  int
+}
+
+func newLexer(src string) *lexer {
+	src = strings.TrimPrefix(src, "\uFEFF")
+	return &lexer{src: src, line: 1, col: 1}
+}
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else if l.src[l.pos]&0xC0 != 0x80 {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; c {
+		case ' ', '\t', '\n', '\r', ',':
+			l.advance(1)
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.advance(1)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	start := Location{Line: l.line, Column: l.col}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.advance(3)
+		return token{kind: tokenPunct, value: "...", pos: start}, nil
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		l.advance(1)
+		return token{kind: tokenPunct, value: string(c), pos: start}, nil
+	case c == '_' || isLetter(c):
+		begin := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+		return token{kind: tokenName, value: l.src[begin:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		return l.number(start)
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		return l.blockString(start)
+	case c == '"':
+		return l.string(start)
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, syntaxError(start, "unexpected character %q", r)
+}
+
+func (l *lexer) number(start Location) (token, error) {
+	begin := l.pos
+	kind := tokenInt
+	if l.src[l.pos] == '-' {
+		l.advance(1)
+	}
+	intStart := l.pos
+	if !l.digits() {
+		return token{}, syntaxError(start, "invalid number")
+	}
+	if l.src[intStart] == '0' && l.pos-intStart > 1 {
+		return token{}, syntaxError(start, "invalid number, unexpected digit after 0")
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.advance(1)
+		if !l.digits() {
+			return token{}, syntaxError(start, "invalid number, expected digit after '.'")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.advance(1)
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.advance(1)
+		}
+		if !l.digits() {
+			return token{}, syntaxError(start, "invalid number, expected digit in exponent")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '_' || l.src[l.pos] == '.' || isLetter(l.src[l.pos])) {
+		return token{}, syntaxError(start, "invalid number, unexpected %q", l.src[l.pos])
+	}
+	return token{kind: kind, value: l.src[begin:l.pos], pos: start}, nil
+}
+
+func (l *lexer) digits() bool {
+	begin := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.advance(1)
+	}
+	return l.pos > begin
+}
+
+func (l *lexer) string(start Location) (token, error) {
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return token{kind: tokenString, value: b.String(), pos: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, syntaxError(start, "unterminated string")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, syntaxError(start, "unterminated string")
+			}
+			esc := l.src[l.pos+1]
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				r, width, ok := parseUnicodeEscape(l.src[l.pos:])
+				if !ok {
+					return token{}, syntaxError(start, "invalid unicode escape sequence")
+				}
+				b.WriteRune(r)
+				l.advance(width)
+				continue
+			default:
+				return token{}, syntaxError(start, "invalid escape sequence \\%c", esc)
+			}
+			l.advance(2)
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+	return token{}, syntaxError(start, "unterminated string")
+}
+
+// parseUnicodeEscape decodes a \uXXXX escape, combining surrogate pairs, and
+// returns the rune and the number of bytes consumed.
+func parseUnicodeEscape(s string) (rune, int, bool) {
+	hex := func(s string) (rune, bool) {
+		if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
+			return 0, false
+		}
+		var r rune
+		for _, c := range s[2:6] {
+			r <<= 4
+			switch {
+			case c >= '0' && c <= '9':
+				r |= c - '0'
+			case c >= 'a' && c <= 'f':
+				r |= c - 'a' + 10
+			case c >= 'A' && c <= 'F':
+				r |= c - 'A' + 10
+			default:
+				return 0, false
+			}
+		}
+		return r, true
+	}
+	r, ok := hex(s)
+	if !ok {
+		return 0, 0, false
+	}
+	if r >= 0xD800 && r <= 0xDBFF {
+		low, ok := hex(s[6:])
+		if !ok || low < 0xDC00 || low > 0xDFFF {
+			return 0, 0, false
+		}
+		return (r-0xD800)<<10 + (low - 0xDC00) + 0x10000, 12, true
+	}
+	return r, 6, true
+}
+
+func (l *lexer) blockString(start Location) (token, error) {
+	l.advance(3)
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.advance(3)
+			return token{kind: tokenBlockString, value: blockStringValue(b.String()), pos: start}, nil
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			b.WriteString(`"""`)
+			l.advance(4)
+		default:
+			b.WriteByte(l.src[l.pos])
+			l.advance(1)
+		}
+	}
+	return token{}, syntaxError(start, "unterminated block string")
+}
+
+// blockStringValue removes the common indentation and the leading and
+// trailing blank lines of a block string.
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(raw, "\r\n", "\n"), "\r", "\n"), "\n")
+	common := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (common < 0 || indent < common) {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= common {
+				lines[i] = lines[i][common:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,148 @@
+package gql
+
+import (
+	"errors"
+	"testing"
+)
+
+// lexAll returns the tokens of src up to, but not including, <EOF>.
+func lexAll(src string) ([]token, error) {
+	l := newLexer(src)
+	var tokens []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return tokens, err
+		}
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func TestLexerTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		kinds []tokenKind
+		value []string
+	}{
+		{
+			name:  "Punctuators",
+			src:   "! $ & ( ) ... : = @ [ ] { | }",
+			kinds: []tokenKind{tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct, tokenPunct},
+			value: []string{"!", "$", "&", "(", ")", "...", ":", "=", "@", "[", "]", "{", "|", "}"},
+		},
+		{
+			name:  "IgnoredTokens",
+			src:   "\uFEFFquery, # a comment\r\n\t_name2",
+			kinds: []tokenKind{tokenName, tokenName},
+			value: []string{"query", "_name2"},
+		},
+		{
+			name:  "Numbers",
+			src:   "0 -12 3.5 1e10 6.02E-23 -0.0",
+			kinds: []tokenKind{tokenInt, tokenInt, tokenFloat, tokenFloat, tokenFloat, tokenFloat},
+			value: []string{"0", "-12", "3.5", "1e10", "6.02E-23", "-0.0"},
+		},
+		{
+			name:  "StringEscapes",
+			src:   `"quote \" slash \/ back \\ controls \b\f\n\r\t"`,
+			kinds: []tokenKind{tokenString},
+			value: []string{"quote \" slash / back \\ controls \b\f\n\r\t"},
+		},
+		{
+			name:  "UnicodeEscapes",
+			src:   `"\u00e9\u00C9 \uD83D\uDE00"`,
+			kinds: []tokenKind{tokenString},
+			value: []string{"éÉ 😀"},
+		},
+		{
+			name:  "BlockStringIndentation",
+			src:   "\"\"\"\n\n    Hello,\n      World!\n\n    Yours,\n      GraphQL.\n  \"\"\"",
+			kinds: []tokenKind{tokenBlockString},
+			value: []string{"Hello,\n  World!\n\nYours,\n  GraphQL."},
+		},
+		{
+			name:  "BlockStringEscapes",
+			src:   `"""contains \""" and \n verbatim"""`,
+			kinds: []tokenKind{tokenBlockString},
+			value: []string{`contains """ and \n verbatim`},
+		},
+		{
+			name:  "BlockStringLineEndings",
+			src:   "\"\"\"  first\r\n  second\r  third\"\"\"",
+			kinds: []tokenKind{tokenBlockString},
+			value: []string{"  first\nsecond\nthird"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lexAll(tt.src)
+			if err != nil {
+				t.Fatalf("lex: %v", err)
+			}
+			if len(tokens) != len(tt.kinds) {
+				t.Fatalf("got %d tokens %+v, want %d", len(tokens), tokens, len(tt.kinds))
+			}
+			for i, tok := range tokens {
+				if tok.kind != tt.kinds[i] || tok.value != tt.value[i] {
+					t.Errorf("token %d = %s %q, want %s %q", i, tok.kind, tok.value, tt.kinds[i], tt.value[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLexerPositions(t *testing.T) {
+	tokens, err := lexAll("{\n  hero(id: \"é\")\n}")
+	if err != nil {
+		t.Fatalf("lex: %v", err)
+	}
+	want := []Location{{1, 1}, {2, 3}, {2, 7}, {2, 8}, {2, 10}, {2, 12}, {2, 15}, {3, 1}}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		if tok.pos != want[i] {
+			t.Errorf("token %q at %+v, want %+v", tok.value, tok.pos, want[i])
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		message string
+		loc     Location
+	}{
+		{"UnexpectedCharacter", "{\n  ?", `Syntax Error: unexpected character '?'`, Location{2, 3}},
+		{"LeadingZero", "01", "Syntax Error: invalid number, unexpected digit after 0", Location{1, 1}},
+		{"MissingFraction", "1.", "Syntax Error: invalid number, expected digit after '.'", Location{1, 1}},
+		{"MissingExponent", "  1e", "Syntax Error: invalid number, expected digit in exponent", Location{1, 3}},
+		{"NameAfterNumber", "12abc", `Syntax Error: invalid number, unexpected 'a'`, Location{1, 1}},
+		{"UnterminatedString", `x "abc`, "Syntax Error: unterminated string", Location{1, 3}},
+		{"StringWithNewline", "\"ab\ncd\"", "Syntax Error: unterminated string", Location{1, 1}},
+		{"InvalidEscape", `"\x"`, `Syntax Error: invalid escape sequence \x`, Location{1, 1}},
+		{"ShortUnicodeEscape", `"\u12"`, "Syntax Error: invalid unicode escape sequence", Location{1, 1}},
+		{"LoneSurrogate", `"\uD83D"`, "Syntax Error: invalid unicode escape sequence", Location{1, 1}},
+		{"UnterminatedBlockString", "\n\"\"\"abc", "Syntax Error: unterminated block string", Location{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lexAll(tt.src)
+			var gqlErr *Error
+			if !errors.As(err, &gqlErr) {
+				t.Fatalf("lex error = %v, want a GraphQL error", err)
+			}
+			if gqlErr.Message != tt.message {
+				t.Errorf("message = %q, want %q", gqlErr.Message, tt.message)
+			}
+			if len(gqlErr.Locations) != 1 || gqlErr.Locations[0] != tt.loc {
+				t.Errorf("locations = %+v, want %+v", gqlErr.Locations, tt.loc)
+			}
+		})
+	}
+}
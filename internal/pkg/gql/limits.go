@@ -0,0 +1,215 @@
+package gql
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultListSize is the number of items assumed for list fields without a
+// @listSize annotation.
+const DefaultListSize = 10
+
+// Limits bound the operations an Executor runs. They are checked once the
+// operation is selected and its variables coerced, before any resolver is
+// called. Zero disables a limit. Introspection fields are not counted.
+type Limits struct {
+	// MaxDepth is the deepest chain of nested fields an operation may
+	// select. Root fields are at depth 1.
+	MaxDepth int
+	// MaxCost bounds the estimated cost of an operation. A field costs its
+	// @cost weight, by default 1 when it returns an object and 0 for leaves,
+	// plus the cost of its own selections. List fields multiply the cost of
+	// their selections by their @listSize, or by ListSize.
+	MaxCost int
+	// ListSize is the number of items assumed for list fields without a
+	// @listSize annotation. Zero means DefaultListSize.
+	ListSize int
+}
+
+// Codes set in the extensions of the errors reporting an exceeded limit.
+const (
+	CodeDepthLimitExceeded = "DEPTH_LIMIT_EXCEEDED"
+	CodeCostLimitExceeded  = "COST_LIMIT_EXCEEDED"
+)
+
+// fieldCost holds the @cost and @listSize annotations of a field definition.
+type fieldCost struct {
+	weight   int
+	listSize int
+}
+
+// fieldCosts reads the cost annotations of every field of the schema.
+func fieldCosts(s *Schema) (map[*FieldDef]fieldCost, Errors) {
+	costs := make(map[*FieldDef]fieldCost)
+	var errs Errors
+	for _, name := range s.TypeNames() {
+		t := s.Types[name]
+		if t.Kind != KindObject && t.Kind != KindInterface {
+			continue
+		}
+		for _, f := range t.Fields {
+			cost := fieldCost{weight: -1, listSize: -1}
+			for _, d := range f.Directives {
+				var target *int
+				switch d.Name {
+				case "cost":
+					target = &cost.weight
+				case "listSize":
+					target = &cost.listSize
+				default:
+					continue
+				}
+				value, err := annotationValue(s.Directives[d.Name], d)
+				if err != nil {
+					errs = append(errs, &Error{Message: fmt.Sprintf("invalid @%s on %s.%s: %v", d.Name, t.Name, f.Name, err)})
+					continue
+				}
+				*target = value
+			}
+			if cost.weight >= 0 || cost.listSize >= 0 {
+				costs[f] = cost
+			}
+		}
+	}
+	return costs, errs
+}
+
+func annotationValue(def *DirectiveDef, d *Directive) (int, error) {
+	args, err := coerceArguments(def.Args, d.Arguments, nil)
+	if err != nil {
+		return 0, err
+	}
+	value, _ := args[def.Args[0].Name].(int)
+	if value < 0 {
+		return 0, fmt.Errorf("%s must not be negative", def.Args[0].Name)
+	}
+	return value, nil
+}
+
+// checkLimits measures the operation of x against the executor's limits.
+func (x *execution) checkLimits() *Error {
+	limits := x.ex.cfg.Limits
+	if limits.MaxDepth <= 0 && limits.MaxCost <= 0 {
+		return nil
+	}
+	a := &analysis{x: x, listSize: limits.ListSize, memo: make(map[batchKey]measure)}
+	if a.listSize <= 0 {
+		a.listSize = DefaultListSize
+	}
+	m := a.selectionSet(x.root, x.op.SelectionSet)
+	if limits.MaxDepth > 0 && m.depth > limits.MaxDepth {
+		err := newError(x.op.Loc, "Operation has depth %d, which exceeds the maximum depth of %d.", m.depth, limits.MaxDepth)
+		err.Extensions = map[string]any{"code": CodeDepthLimitExceeded, "depth": m.depth, "maxDepth": limits.MaxDepth}
+		return err
+	}
+	if limits.MaxCost > 0 && m.cost > limits.MaxCost {
+		err := newError(x.op.Loc, "Operation has an estimated cost of %d, which exceeds the maximum cost of %d.", m.cost, limits.MaxCost)
+		err.Extensions = map[string]any{"code": CodeCostLimitExceeded, "cost": m.cost, "maxCost": limits.MaxCost}
+		return err
+	}
+	return nil
+}
+
+// measure is the depth and estimated cost of a selection.
+type measure struct {
+	depth int
+	cost  int
+}
+
+// analysis walks an operation the way the executor would, honouring @skip,
+// @include and fragment type conditions. Fields reached again through a
+// repeated fragment are measured once.
+type analysis struct {
+	x        *execution
+	listSize int
+	memo     map[batchKey]measure
+}
+
+// selectionSet measures selections on t. Selections on an abstract type are
+// measured for each possible type and the largest measure is kept.
+func (a *analysis) selectionSet(t *Type, selections []Selection) measure {
+	if !t.IsAbstract() {
+		return a.object(t, selections)
+	}
+	var m measure
+	for _, possible := range t.PossibleTypes {
+		pm := a.object(possible, selections)
+		m.depth = max(m.depth, pm.depth)
+		m.cost = max(m.cost, pm.cost)
+	}
+	return m
+}
+
+func (a *analysis) object(t *Type, selections []Selection) measure {
+	var m measure
+	fields := a.x.collectFields(t, selections)
+	for _, key := range fields.keys {
+		fm := a.field(t, fields.fields[key])
+		m.depth = max(m.depth, fm.depth)
+		m.cost = saturatingAdd(m.cost, fm.cost)
+	}
+	return m
+}
+
+func (a *analysis) field(parent *Type, fields []*Field) measure {
+	if strings.HasPrefix(fields[0].Name, "__") {
+		return measure{}
+	}
+	def := a.x.fieldDef(parent, fields[0].Name)
+	if def == nil {
+		return measure{}
+	}
+	key := batchKey{field: fields[0], typ: parent}
+	if len(fields) == 1 {
+		if m, ok := a.memo[key]; ok {
+			return m
+		}
+	}
+
+	cost, annotated := a.x.ex.costs[def]
+	if !annotated || cost.weight < 0 {
+		cost.weight = 0
+		if !def.Type.Named().IsLeaf() {
+			cost.weight = 1
+		}
+	}
+	if !annotated || cost.listSize < 0 {
+		cost.listSize = a.listSize
+	}
+
+	var children measure
+	if named := def.Type.Named(); !named.IsLeaf() {
+		var selections []Selection
+		for _, f := range fields {
+			selections = append(selections, f.SelectionSet...)
+		}
+		children = a.selectionSet(named, selections)
+	}
+	if isListType(def.Type) {
+		children.cost = saturatingMul(children.cost, cost.listSize)
+	}
+	m := measure{depth: children.depth + 1, cost: saturatingAdd(cost.weight, children.cost)}
+	if len(fields) == 1 {
+		a.memo[key] = m
+	}
+	return m
+}
+
+func isListType(t *Type) bool {
+	return t.Nullable().Kind == KindList
+}
+
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
@@ -0,0 +1,132 @@
+package gql
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// limitError returns the extensions of the error reported when executing
+// query against limits, or nil when it ran.
+func limitError(t *testing.T, limits Limits, query string) map[string]any {
+	t.Helper()
+	var resp struct {
+		Errors []struct {
+			Message    string
+			Extensions map[string]any
+		}
+	}
+	if err := json.Unmarshal([]byte(run(t, newTestExecutor(t, limits), Request{Query: query})), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Errors) == 0 {
+		return nil
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions == nil {
+		t.Fatalf("errors = %+v, want one limit error", resp.Errors)
+	}
+	return resp.Errors[0].Extensions
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		// listSize is the ListSize limit, zero for the default.
+		listSize int
+		depth    int
+		cost     int
+	}{
+		{name: "leaves", query: `{ hero { id name } }`, depth: 2, cost: 1},
+		{name: "list", query: `{ hero { friends { name } } }`, depth: 3, cost: 2},
+		{
+			name:  "nested lists multiply",
+			query: `{ hero { friends { friends { name } } } }`,
+			depth: 4,
+			cost:  1 + 1 + DefaultListSize*1,
+		},
+		{
+			name:     "list size option",
+			query:    `{ hero { friends { friends { name } } } }`,
+			listSize: 2,
+			depth:    4,
+			cost:     1 + 1 + 2*1,
+		},
+		{
+			name:  "annotated list size and abstract selections",
+			query: `{ search(text: "a") { ... on Human { friends { name } } ... on Droid { name } } }`,
+			depth: 3,
+			cost:  1 + 5*1,
+		},
+		{
+			name:  "fragment spreads",
+			query: `{ hero { ...Friends } } fragment Friends on Character { friends { ...Names } } fragment Names on Character { friends { name } }`,
+			depth: 4,
+			cost:  1 + 1 + DefaultListSize*1,
+		},
+		{
+			name:  "fragment repeated in a selection set",
+			query: `{ hero { ...Friends ...Friends friends { id } } } fragment Friends on Character { friends { name } }`,
+			depth: 3,
+			cost:  2,
+		},
+		{
+			name:  "fragment spread in two fields",
+			query: `{ a: hero { ...Friends } b: hero { ...Friends } } fragment Friends on Character { friends { name } }`,
+			depth: 3,
+			cost:  4,
+		},
+		{
+			name:  "skipped fields",
+			query: `{ hero { friends @skip(if: true) { friends { name } } } }`,
+			depth: 1,
+			cost:  1,
+		},
+		{name: "cost annotation", query: `mutation { counter }`, depth: 1, cost: 10},
+		{name: "introspection", query: `{ __schema { types { name fields { name type { name } } } } }`, depth: 0, cost: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Limits of one report any larger measure.
+			ext := limitError(t, Limits{MaxDepth: 1, ListSize: tt.listSize}, tt.query)
+			if tt.depth <= 1 {
+				if ext != nil {
+					t.Fatalf("depth limit reported %v, want depth %d", ext, tt.depth)
+				}
+			} else if ext["code"] != CodeDepthLimitExceeded || ext["depth"] != float64(tt.depth) || ext["maxDepth"] != float64(1) {
+				t.Fatalf("depth limit reported %v, want depth %d", ext, tt.depth)
+			}
+
+			ext = limitError(t, Limits{MaxCost: 1, ListSize: tt.listSize}, tt.query)
+			if tt.cost <= 1 {
+				if ext != nil {
+					t.Fatalf("cost limit reported %v, want cost %d", ext, tt.cost)
+				}
+			} else if ext["code"] != CodeCostLimitExceeded || ext["cost"] != float64(tt.cost) || ext["maxCost"] != float64(1) {
+				t.Fatalf("cost limit reported %v, want cost %d", ext, tt.cost)
+			}
+		})
+	}
+}
+
+func TestLimitsAllowOperationsWithinThem(t *testing.T) {
+	query := `{ hero { friends { friends { name } } } }`
+	if ext := limitError(t, Limits{MaxDepth: 4, MaxCost: 12}, query); ext != nil {
+		t.Fatalf("operation at the limits reported %v", ext)
+	}
+	got := run(t, newTestExecutor(t, Limits{MaxDepth: 3}), Request{Query: query})
+	want := `{"errors":[{"message":"Operation has depth 4, which exceeds the maximum depth of 3.","locations":[{"line":1,"column":1}],"extensions":{"code":"DEPTH_LIMIT_EXCEEDED","depth":4,"maxDepth":3}}]}`
+	if got != want {
+		t.Fatalf("response = %s\nwant %s", got, want)
+	}
+}
+
+func TestInvalidCostAnnotations(t *testing.T) {
+	schema, err := ParseSchema(`type Query { books: [String] @listSize(assumedSize: -1) }`)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	_, err = NewExecutor(Config{Schema: schema})
+	if err == nil || err.Error() != "invalid @listSize on Query.books: assumedSize must not be negative" {
+		t.Fatalf("NewExecutor = %v, want the invalid annotation", err)
+	}
+}
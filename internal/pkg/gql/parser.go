@@ -0,0 +1,801 @@
+package gql
+
+// Parse parses an executable GraphQL document.
+func Parse(src string) (*Document, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	if p.tok.kind == tokenEOF {
+		return nil, syntaxError(p.tok.pos, "unexpected <EOF>, expected an operation or fragment")
+	}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peekPunct("{"):
+			loc := p.tok.pos
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: Query, SelectionSet: selections, Loc: loc})
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.peekName("fragment"):
+			fragment, err := p.fragmentDefinition()
+			if err != nil {
+				return nil, err
+			}
+			doc.Fragments = append(doc.Fragments, fragment)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	return doc, nil
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{lex: newLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peekPunct(value string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == value
+}
+
+func (p *parser) peekName(value string) bool {
+	return p.tok.kind == tokenName && p.tok.value == value
+}
+
+func (p *parser) peekString() bool {
+	return p.tok.kind == tokenString || p.tok.kind == tokenBlockString
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return syntaxError(p.tok.pos, "unexpected <EOF>")
+	}
+	return syntaxError(p.tok.pos, "unexpected %q", p.tok.value)
+}
+
+func (p *parser) expectPunct(value string) error {
+	if !p.peekPunct(value) {
+		if p.tok.kind == tokenEOF {
+			return syntaxError(p.tok.pos, "expected %q, found <EOF>", value)
+		}
+		return syntaxError(p.tok.pos, "expected %q, found %q", value, p.tok.value)
+	}
+	return p.advance()
+}
+
+// skipPunct consumes the punctuator if it is next and reports whether it did.
+func (p *parser) skipPunct(value string) (bool, error) {
+	if !p.peekPunct(value) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expectKeyword(value string) error {
+	if !p.peekName(value) {
+		return syntaxError(p.tok.pos, "expected %q, found %q", value, p.tok.value)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, Location, error) {
+	if p.tok.kind != tokenName {
+		if p.tok.kind == tokenEOF {
+			return "", p.tok.pos, syntaxError(p.tok.pos, "expected a name, found <EOF>")
+		}
+		return "", p.tok.pos, syntaxError(p.tok.pos, "expected a name, found %q", p.tok.value)
+	}
+	name, loc := p.tok.value, p.tok.pos
+	return name, loc, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Type: OperationType(p.tok.value), Loc: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName {
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		op.Name = name
+	}
+	if p.peekPunct("(") {
+		vars, err := p.variableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		op.Variables = vars
+	}
+	directives, err := p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	op.Directives = directives
+	if op.SelectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*VariableDefinition, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var defs []*VariableDefinition
+	for {
+		if ok, err := p.skipPunct(")"); err != nil || ok {
+			return defs, err
+		}
+		loc := p.tok.pos
+		if err := p.expectPunct("$"); err != nil {
+			return nil, err
+		}
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		def := &VariableDefinition{Name: name, Type: typ, Loc: loc}
+		if ok, err := p.skipPunct("="); err != nil {
+			return nil, err
+		} else if ok {
+			if def.Default, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		if def.Directives, err = p.directives(true); err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+}
+
+func (p *parser) typeRef() (*TypeRef, error) {
+	var ref *TypeRef
+	loc := p.tok.pos
+	if ok, err := p.skipPunct("["); err != nil {
+		return nil, err
+	} else if ok {
+		elem, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return nil, err
+		}
+		ref = &TypeRef{Elem: elem, Loc: loc}
+	} else {
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		ref = &TypeRef{Name: name, Loc: loc}
+	}
+	nonNull, err := p.skipPunct("!")
+	ref.NonNull = nonNull
+	return ref, err
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var selections []Selection
+	for {
+		end := p.tok.pos
+		if ok, err := p.skipPunct("}"); err != nil {
+			return nil, err
+		} else if ok {
+			if len(selections) == 0 {
+				return nil, syntaxError(end, "selection set must not be empty")
+			}
+			return selections, nil
+		}
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+}
+
+func (p *parser) selection() (Selection, error) {
+	if !p.peekPunct("...") {
+		return p.field()
+	}
+	loc := p.tok.pos
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		directives, err := p.directives(false)
+		if err != nil {
+			return nil, err
+		}
+		return &FragmentSpread{Name: name, Directives: directives, Loc: loc}, nil
+	}
+
+	fragment := &InlineFragment{Loc: loc}
+	if p.peekName("on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		fragment.TypeCondition = name
+	}
+	var err error
+	if fragment.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	if fragment.SelectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+func (p *parser) field() (*Field, error) {
+	name, loc, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	field := &Field{Name: name, Loc: loc}
+	if ok, err := p.skipPunct(":"); err != nil {
+		return nil, err
+	} else if ok {
+		field.Alias = name
+		if field.Name, _, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if field.Arguments, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if field.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	if p.peekPunct("{") {
+		if field.SelectionSet, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*Argument, error) {
+	if ok, err := p.skipPunct("("); err != nil || !ok {
+		return nil, err
+	}
+	var args []*Argument
+	for {
+		end := p.tok.pos
+		if ok, err := p.skipPunct(")"); err != nil {
+			return nil, err
+		} else if ok {
+			if len(args) == 0 {
+				return nil, syntaxError(end, "argument list must not be empty")
+			}
+			return args, nil
+		}
+		name, loc, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &Argument{Name: name, Value: value, Loc: loc})
+	}
+}
+
+func (p *parser) directives(constant bool) ([]*Directive, error) {
+	var directives []*Directive
+	for p.peekPunct("@") {
+		loc := p.tok.pos
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments(constant)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &Directive{Name: name, Arguments: args, Loc: loc})
+	}
+	return directives, nil
+}
+
+func (p *parser) value(constant bool) (*Value, error) {
+	tok := p.tok
+	value := &Value{Raw: tok.value, Loc: tok.pos}
+	switch tok.kind {
+	case tokenInt:
+		value.Kind = IntValue
+	case tokenFloat:
+		value.Kind = FloatValue
+	case tokenString, tokenBlockString:
+		value.Kind = StringValue
+	case tokenName:
+		switch tok.value {
+		case "true", "false":
+			value.Kind = BooleanValue
+		case "null":
+			value.Kind = NullValue
+		default:
+			value.Kind = EnumValue
+		}
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, syntaxError(tok.pos, "unexpected variable in constant value")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, _, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			value.Kind, value.Raw = VariableValue, name
+			return value, nil
+		case "[":
+			return p.listValue(value, constant)
+		case "{":
+			return p.objectValue(value, constant)
+		}
+		return nil, p.unexpected()
+	default:
+		return nil, p.unexpected()
+	}
+	return value, p.advance()
+}
+
+func (p *parser) listValue(value *Value, constant bool) (*Value, error) {
+	value.Kind, value.Raw = ListValue, ""
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	value.List = []*Value{}
+	for {
+		if ok, err := p.skipPunct("]"); err != nil || ok {
+			return value, err
+		}
+		item, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		value.List = append(value.List, item)
+	}
+}
+
+func (p *parser) objectValue(value *Value, constant bool) (*Value, error) {
+	value.Kind, value.Raw = ObjectValue, ""
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.skipPunct("}"); err != nil || ok {
+			return value, err
+		}
+		name, loc, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		fieldValue, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		value.Fields = append(value.Fields, &ObjectField{Name: name, Value: fieldValue, Loc: loc})
+	}
+}
+
+func (p *parser) fragmentDefinition() (*FragmentDefinition, error) {
+	loc := p.tok.pos
+	if err := p.expectKeyword("fragment"); err != nil {
+		return nil, err
+	}
+	name, _, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, syntaxError(loc, "fragment cannot be named \"on\"")
+	}
+	if err := p.expectKeyword("on"); err != nil {
+		return nil, err
+	}
+	typeCondition, _, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	fragment := &FragmentDefinition{Name: name, TypeCondition: typeCondition, Loc: loc}
+	if fragment.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	if fragment.SelectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+// parseSchemaDocument parses a type system document in the schema definition
+// language.
+func parseSchemaDocument(src string) (*schemaDocument, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	doc := &schemaDocument{}
+	for p.tok.kind != tokenEOF {
+		description, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenName {
+			return nil, p.unexpected()
+		}
+		loc := p.tok.pos
+		switch p.tok.value {
+		case "schema":
+			if doc.schema != nil {
+				return nil, syntaxError(loc, "schema must be defined only once")
+			}
+			if doc.schema, err = p.schemaDefinition(); err != nil {
+				return nil, err
+			}
+		case "directive":
+			def, err := p.directiveDefinition(description)
+			if err != nil {
+				return nil, err
+			}
+			doc.directives = append(doc.directives, def)
+		case "scalar", "type", "interface", "union", "enum", "input":
+			def, err := p.typeDefinition(description)
+			if err != nil {
+				return nil, err
+			}
+			doc.types = append(doc.types, def)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	return doc, nil
+}
+
+func (p *parser) description() (string, error) {
+	if !p.peekString() {
+		return "", nil
+	}
+	description := p.tok.value
+	return description, p.advance()
+}
+
+func (p *parser) schemaDefinition() (*schemaDefinition, error) {
+	def := &schemaDefinition{operations: make(map[OperationType]string), loc: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(true); err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.skipPunct("}"); err != nil || ok {
+			return def, err
+		}
+		operation, loc, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		switch OperationType(operation) {
+		case Query, Mutation, Subscription:
+		default:
+			return nil, syntaxError(loc, "unknown operation type %q", operation)
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		typeName, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		def.operations[OperationType(operation)] = typeName
+	}
+}
+
+func (p *parser) directiveDefinition(description string) (*directiveDefinition, error) {
+	def := &directiveDefinition{description: description, loc: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct("@"); err != nil {
+		return nil, err
+	}
+	var err error
+	if def.name, _, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct("(") {
+		if def.args, err = p.inputValueDefinitions("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+	if p.peekName("repeatable") {
+		def.repeatable = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectKeyword("on"); err != nil {
+		return nil, err
+	}
+	if _, err := p.skipPunct("|"); err != nil {
+		return nil, err
+	}
+	for {
+		location, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		def.locations = append(def.locations, location)
+		if ok, err := p.skipPunct("|"); err != nil {
+			return nil, err
+		} else if !ok {
+			return def, nil
+		}
+	}
+}
+
+func (p *parser) typeDefinition(description string) (*typeDefinition, error) {
+	keyword := p.tok.value
+	def := &typeDefinition{description: description, loc: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if def.name, _, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	switch keyword {
+	case "scalar":
+		def.kind = KindScalar
+		def.directives, err = p.directives(true)
+		return def, err
+	case "type", "interface":
+		def.kind = KindObject
+		if keyword == "interface" {
+			def.kind = KindInterface
+		}
+		if p.peekName("implements") {
+			if def.interfaces, err = p.implementsInterfaces(); err != nil {
+				return nil, err
+			}
+		}
+		if def.directives, err = p.directives(true); err != nil {
+			return nil, err
+		}
+		if p.peekPunct("{") {
+			def.fields, err = p.fieldDefinitions()
+		}
+		return def, err
+	case "union":
+		def.kind = KindUnion
+		if def.directives, err = p.directives(true); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skipPunct("="); err != nil || !ok {
+			return def, err
+		}
+		if _, err := p.skipPunct("|"); err != nil {
+			return nil, err
+		}
+		for {
+			member, _, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			def.members = append(def.members, member)
+			if ok, err := p.skipPunct("|"); err != nil {
+				return nil, err
+			} else if !ok {
+				return def, nil
+			}
+		}
+	case "enum":
+		def.kind = KindEnum
+		if def.directives, err = p.directives(true); err != nil {
+			return nil, err
+		}
+		if p.peekPunct("{") {
+			def.enumValues, err = p.enumValueDefinitions()
+		}
+		return def, err
+	default:
+		def.kind = KindInputObject
+		if def.directives, err = p.directives(true); err != nil {
+			return nil, err
+		}
+		if p.peekPunct("{") {
+			def.inputFields, err = p.inputValueDefinitions("{", "}")
+		}
+		return def, err
+	}
+}
+
+func (p *parser) implementsInterfaces() ([]string, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if _, err := p.skipPunct("&"); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if ok, err := p.skipPunct("&"); err != nil {
+			return nil, err
+		} else if !ok {
+			return names, nil
+		}
+	}
+}
+
+func (p *parser) fieldDefinitions() ([]*fieldDefinition, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var fields []*fieldDefinition
+	for {
+		if ok, err := p.skipPunct("}"); err != nil || ok {
+			return fields, err
+		}
+		description, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+		name, loc, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		field := &fieldDefinition{name: name, description: description, loc: loc}
+		if p.peekPunct("(") {
+			if field.args, err = p.inputValueDefinitions("(", ")"); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if field.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if field.directives, err = p.directives(true); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+}
+
+func (p *parser) inputValueDefinitions(open, close string) ([]*inputValueDefinition, error) {
+	if err := p.expectPunct(open); err != nil {
+		return nil, err
+	}
+	var values []*inputValueDefinition
+	for {
+		if ok, err := p.skipPunct(close); err != nil || ok {
+			return values, err
+		}
+		description, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+		name, loc, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		value := &inputValueDefinition{name: name, description: description, loc: loc}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if value.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skipPunct("="); err != nil {
+			return nil, err
+		} else if ok {
+			if value.defaultValue, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		if value.directives, err = p.directives(true); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+}
+
+func (p *parser) enumValueDefinitions() ([]*enumValueDefinition, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var values []*enumValueDefinition
+	for {
+		if ok, err := p.skipPunct("}"); err != nil || ok {
+			return values, err
+		}
+		description, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+		name, loc, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if name == "true" || name == "false" || name == "null" {
+			return nil, syntaxError(loc, "enum value cannot be %q", name)
+		}
+		directives, err := p.directives(true)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, &enumValueDefinition{name: name, description: description, directives: directives})
+	}
+}
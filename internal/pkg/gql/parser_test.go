@@ -0,0 +1,118 @@
+package gql
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDocument(t *testing.T) {
+	doc, err := Parse(`
+		query Hero($id: ID!, $episodes: [Episode!] = [NEWHOPE], $review: ReviewInput @deprecated) @include(if: true) {
+			leader: hero(id: $id) {
+				name
+				...Details @skip(if: false)
+				... on Droid { primaryFunction }
+				... @include(if: $flag) { id }
+			}
+			search(filter: {text: "x", tags: ["a", "b"], min: -1.5, none: null, on: true})
+		}
+
+		fragment Details on Character { id }
+
+		{ anonymous }
+	`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(doc.Operations) != 2 || len(doc.Fragments) != 1 {
+		t.Fatalf("parsed %d operations and %d fragments, want 2 and 1", len(doc.Operations), len(doc.Fragments))
+	}
+
+	op := doc.Operations[0]
+	if op.Type != Query || op.Name != "Hero" || len(op.Directives) != 1 || op.Loc != (Location{2, 3}) {
+		t.Fatalf("operation = %+v", op)
+	}
+	if got := [3]string{op.Variables[0].Type.String(), op.Variables[1].Type.String(), op.Variables[2].Type.String()}; got != [3]string{"ID!", "[Episode!]", "ReviewInput"} {
+		t.Fatalf("variable types = %v", got)
+	}
+	if def := op.Variables[1].Default; def == nil || def.Kind != ListValue || len(def.List) != 1 || def.List[0].Kind != EnumValue || def.List[0].Raw != "NEWHOPE" {
+		t.Fatalf("default of $episodes = %+v", def)
+	}
+	if len(op.Variables[2].Directives) != 1 {
+		t.Fatalf("directives of $review = %+v", op.Variables[2].Directives)
+	}
+
+	hero := op.SelectionSet[0].(*Field)
+	if hero.Alias != "leader" || hero.Name != "hero" || hero.ResponseKey() != "leader" || hero.Loc != (Location{3, 4}) {
+		t.Fatalf("aliased field = %+v", hero)
+	}
+	if arg := hero.Arguments[0]; arg.Name != "id" || arg.Value.Kind != VariableValue || arg.Value.Raw != "id" {
+		t.Fatalf("argument = %+v", arg)
+	}
+	if spread, ok := hero.SelectionSet[1].(*FragmentSpread); !ok || spread.Name != "Details" || len(spread.Directives) != 1 {
+		t.Fatalf("fragment spread = %+v", hero.SelectionSet[1])
+	}
+	if inline, ok := hero.SelectionSet[2].(*InlineFragment); !ok || inline.TypeCondition != "Droid" {
+		t.Fatalf("inline fragment = %+v", hero.SelectionSet[2])
+	}
+	if inline, ok := hero.SelectionSet[3].(*InlineFragment); !ok || inline.TypeCondition != "" || len(inline.Directives) != 1 {
+		t.Fatalf("inline fragment without condition = %+v", hero.SelectionSet[3])
+	}
+
+	filter := op.SelectionSet[1].(*Field).Arguments[0].Value
+	if filter.Kind != ObjectValue || len(filter.Fields) != 5 {
+		t.Fatalf("object value = %+v", filter)
+	}
+	wantKinds := []ValueKind{StringValue, ListValue, FloatValue, NullValue, BooleanValue}
+	for i, field := range filter.Fields {
+		if field.Value.Kind != wantKinds[i] {
+			t.Errorf("field %s has kind %d, want %d", field.Name, field.Value.Kind, wantKinds[i])
+		}
+	}
+	if printValue(filter) != `{text: "x", tags: ["a", "b"], min: -1.5, none: null, on: true}` {
+		t.Errorf("printValue = %s", printValue(filter))
+	}
+
+	if fragment := doc.Fragment("Details"); fragment == nil || fragment.TypeCondition != "Character" {
+		t.Fatalf("fragment = %+v", fragment)
+	}
+	if anonymous := doc.Operations[1]; anonymous.Type != Query || anonymous.Name != "" || anonymous.Loc != (Location{14, 3}) {
+		t.Fatalf("shorthand query = %+v", anonymous)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		message string
+		loc     Location
+	}{
+		{"Empty", "  ", "Syntax Error: unexpected <EOF>, expected an operation or fragment", Location{1, 3}},
+		{"EmptySelectionSet", "{\n}", "Syntax Error: selection set must not be empty", Location{2, 1}},
+		{"UnclosedSelectionSet", "{ hero", "Syntax Error: expected a name, found <EOF>", Location{1, 7}},
+		{"EmptyArguments", "{ hero() }", "Syntax Error: argument list must not be empty", Location{1, 8}},
+		{"MissingColon", "{ hero(id 1) }", `Syntax Error: expected ":", found "1"`, Location{1, 11}},
+		{"UnknownDefinition", "subscribe { x }", `Syntax Error: unexpected "subscribe"`, Location{1, 1}},
+		{"FragmentNamedOn", "fragment on on T { x }", `Syntax Error: fragment cannot be named "on"`, Location{1, 1}},
+		{"FragmentWithoutCondition", "fragment F { x }", `Syntax Error: expected "on", found "{"`, Location{1, 12}},
+		{"VariableInDefault", "query ($a: Int = $b) { x }", "Syntax Error: unexpected variable in constant value", Location{1, 18}},
+		{"UnclosedList", "{ x(a: [1, 2 }", `Syntax Error: unexpected "}"`, Location{1, 14}},
+		{"LexerError", "{ x(a: \"\\q\") }", `Syntax Error: invalid escape sequence \q`, Location{1, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			var gqlErr *Error
+			if !errors.As(err, &gqlErr) {
+				t.Fatalf("Parse error = %v, want a GraphQL error", err)
+			}
+			if gqlErr.Message != tt.message {
+				t.Errorf("message = %q, want %q", gqlErr.Message, tt.message)
+			}
+			if len(gqlErr.Locations) != 1 || gqlErr.Locations[0] != tt.loc {
+				t.Errorf("locations = %+v, want %+v", gqlErr.Locations, tt.loc)
+			}
+		})
+	}
+}
@@ -0,0 +1,578 @@
+package gql
+
+import "sort"
+
+// TypeKind matches the __TypeKind enumeration of the introspection schema.
+type TypeKind string
+
+const (
+	KindScalar      TypeKind = "SCALAR"
+	KindObject      TypeKind = "OBJECT"
+	KindInterface   TypeKind = "INTERFACE"
+	KindUnion       TypeKind = "UNION"
+	KindEnum        TypeKind = "ENUM"
+	KindInputObject TypeKind = "INPUT_OBJECT"
+	KindList        TypeKind = "LIST"
+	KindNonNull     TypeKind = "NON_NULL"
+)
+
+// Type is a named type of the schema or a list or non-null wrapper around
+// one.
+type Type struct {
+	Kind          TypeKind
+	Name          string
+	Description   string
+	Fields        []*FieldDef
+	Interfaces    []*Type
+	PossibleTypes []*Type
+	EnumValues    []*EnumValueDef
+	InputFields   []*InputValue
+	OfType        *Type
+	Directives    []*Directive
+
+	// Scalar customizes how a custom scalar is read and written. Built-in
+	// scalars ignore it.
+	Scalar *Scalar
+}
+
+// Scalar holds the coercion functions of a custom scalar. Nil functions pass
+// values through unchanged.
+type Scalar struct {
+	ParseValue   func(value any) (any, error)
+	ParseLiteral func(value *Value) (any, error)
+	Serialize    func(value any) (any, error)
+}
+
+// FieldDef is a field of an object or interface type.
+type FieldDef struct {
+	Name              string
+	Description       string
+	Args              []*InputValue
+	Type              *Type
+	Directives        []*Directive
+	DeprecationReason *string
+}
+
+// InputValue is an argument or an input object field.
+type InputValue struct {
+	Name              string
+	Description       string
+	Type              *Type
+	DefaultValue      *Value
+	Directives        []*Directive
+	DeprecationReason *string
+}
+
+// EnumValueDef is a value of an enum type.
+type EnumValueDef struct {
+	Name              string
+	Description       string
+	Directives        []*Directive
+	DeprecationReason *string
+}
+
+// DirectiveDef declares a directive.
+type DirectiveDef struct {
+	Name         string
+	Description  string
+	Args         []*InputValue
+	Locations    []string
+	IsRepeatable bool
+}
+
+// Schema is a GraphQL schema built from its definition language.
+type Schema struct {
+	Types        map[string]*Type
+	Directives   map[string]*DirectiveDef
+	Query        *Type
+	Mutation     *Type
+	Subscription *Type
+
+	typename    *FieldDef
+	schemaField *FieldDef
+	typeField   *FieldDef
+}
+
+// Type returns the named type, or nil.
+func (s *Schema) Type(name string) *Type {
+	return s.Types[name]
+}
+
+// TypeNames returns the names of every named type, sorted.
+func (s *Schema) TypeNames() []string {
+	names := make([]string, 0, len(s.Types))
+	for name := range s.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RootType returns the root type of the given operation type, or nil when the
+// schema does not support it.
+func (s *Schema) RootType(op OperationType) *Type {
+	switch op {
+	case Mutation:
+		return s.Mutation
+	case Subscription:
+		return s.Subscription
+	default:
+		return s.Query
+	}
+}
+
+// Field returns the field with the given name, or nil.
+func (t *Type) Field(name string) *FieldDef {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// InputField returns the input object field with the given name, or nil.
+func (t *Type) InputField(name string) *InputValue {
+	for _, f := range t.InputFields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// EnumValue returns the enum value with the given name, or nil.
+func (t *Type) EnumValue(name string) *EnumValueDef {
+	for _, v := range t.EnumValues {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Named strips list and non-null wrappers.
+func (t *Type) Named() *Type {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+	return t
+}
+
+// Nullable strips a non-null wrapper.
+func (t *Type) Nullable() *Type {
+	if t.Kind == KindNonNull {
+		return t.OfType
+	}
+	return t
+}
+
+// IsLeaf reports whether values of the type are scalars or enums.
+func (t *Type) IsLeaf() bool {
+	k := t.Named().Kind
+	return k == KindScalar || k == KindEnum
+}
+
+// IsComposite reports whether the type selects sub-fields.
+func (t *Type) IsComposite() bool {
+	k := t.Named().Kind
+	return k == KindObject || k == KindInterface || k == KindUnion
+}
+
+// IsAbstract reports whether the type is an interface or union.
+func (t *Type) IsAbstract() bool {
+	return t.Kind == KindInterface || t.Kind == KindUnion
+}
+
+// IsInput reports whether the type may be used for arguments and variables.
+func (t *Type) IsInput() bool {
+	k := t.Named().Kind
+	return k == KindScalar || k == KindEnum || k == KindInputObject
+}
+
+// IsOutput reports whether the type may be used for fields.
+func (t *Type) IsOutput() bool {
+	return t.Named().Kind != KindInputObject
+}
+
+// Implements reports whether the object type t is a possible type of the
+// abstract type abstract.
+func (t *Type) Implements(abstract *Type) bool {
+	for _, possible := range abstract.PossibleTypes {
+		if possible == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Arg returns the argument with the given name, or nil.
+func (f *FieldDef) Arg(name string) *InputValue {
+	return findInputValue(f.Args, name)
+}
+
+// Arg returns the argument with the given name, or nil.
+func (d *DirectiveDef) Arg(name string) *InputValue {
+	return findInputValue(d.Args, name)
+}
+
+func findInputValue(values []*InputValue, name string) *InputValue {
+	for _, v := range values {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+func (t *Type) String() string {
+	switch t.Kind {
+	case KindList:
+		return "[" + t.OfType.String() + "]"
+	case KindNonNull:
+		return t.OfType.String() + "!"
+	default:
+		return t.Name
+	}
+}
+
+func listOf(t *Type) *Type    { return &Type{Kind: KindList, OfType: t} }
+func nonNullOf(t *Type) *Type { return &Type{Kind: KindNonNull, OfType: t} }
+
+// sameType reports whether a and b denote the same, possibly wrapped, type.
+func sameType(a, b *Type) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	if a.OfType != nil {
+		return sameType(a.OfType, b.OfType)
+	}
+	return a == b
+}
+
+const builtinDefinitions = `
+"The ` + "`Int`" + ` scalar type represents non-fractional signed whole numeric values."
+scalar Int
+"The ` + "`Float`" + ` scalar type represents signed double-precision fractional values."
+scalar Float
+"The ` + "`String`" + ` scalar type represents textual data."
+scalar String
+"The ` + "`Boolean`" + ` scalar type represents ` + "`true` or `false`" + `."
+scalar Boolean
+"The ` + "`ID`" + ` scalar type represents a unique identifier."
+scalar ID
+
+"Directs the executor to include this field or fragment only when the ` + "`if`" + ` argument is true."
+directive @include("Included when true." if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+"Directs the executor to skip this field or fragment when the ` + "`if`" + ` argument is true."
+directive @skip("Skipped when true." if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+"Marks an element of a GraphQL schema as no longer supported."
+directive @deprecated(reason: String = "No longer supported") on FIELD_DEFINITION | ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION | ENUM_VALUE
+"Exposes a URL that specifies the behavior of this scalar."
+directive @specifiedBy(url: String!) on SCALAR
+"Sets the cost of resolving a field, counted against the executor's cost limit."
+directive @cost(weight: Int!) on FIELD_DEFINITION
+"Sets the number of items a list field is assumed to return when estimating the cost of an operation."
+directive @listSize(assumedSize: Int!) on FIELD_DEFINITION
+` + introspectionDefinitions
+
+// ParseSchema builds a schema from its definition language. Built-in scalars,
+// directives and the introspection types are always available.
+func ParseSchema(src string) (*Schema, error) {
+	builtins, err := parseSchemaDocument(builtinDefinitions)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseSchemaDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	b := &schemaBuilder{
+		schema: &Schema{Types: make(map[string]*Type), Directives: make(map[string]*DirectiveDef)},
+	}
+	b.add(builtins, true)
+	b.add(doc, false)
+	if len(b.errs) > 0 {
+		return nil, b.errs
+	}
+	b.resolve()
+	if len(b.errs) > 0 {
+		return nil, b.errs
+	}
+	b.validate()
+	if len(b.errs) > 0 {
+		return nil, b.errs
+	}
+	b.addMetaFields()
+	return b.schema, nil
+}
+
+type schemaBuilder struct {
+	schema     *Schema
+	definition *schemaDefinition
+	types      []*typeDefinition
+	directives []*directiveDefinition
+	errs       Errors
+}
+
+func (b *schemaBuilder) errorf(loc Location, format string, args ...any) {
+	b.errs = append(b.errs, newError(loc, format, args...))
+}
+
+func (b *schemaBuilder) add(doc *schemaDocument, builtin bool) {
+	if doc.schema != nil {
+		b.definition = doc.schema
+	}
+	for _, def := range doc.types {
+		if _, exists := b.schema.Types[def.name]; exists {
+			if !builtin && def.kind == KindScalar && isBuiltinScalar(def.name) {
+				continue
+			}
+			b.errorf(def.loc, "There can be only one type named %q.", def.name)
+			continue
+		}
+		b.schema.Types[def.name] = &Type{Kind: def.kind, Name: def.name, Description: def.description, Directives: def.directives}
+		b.types = append(b.types, def)
+	}
+	for _, def := range doc.directives {
+		if _, exists := b.schema.Directives[def.name]; exists {
+			b.errorf(def.loc, "There can be only one directive named \"@%s\".", def.name)
+			continue
+		}
+		b.schema.Directives[def.name] = &DirectiveDef{
+			Name:         def.name,
+			Description:  def.description,
+			Locations:    def.locations,
+			IsRepeatable: def.repeatable,
+		}
+		b.directives = append(b.directives, def)
+	}
+}
+
+func isBuiltinScalar(name string) bool {
+	switch name {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+// resolve links type references once every named type is known.
+func (b *schemaBuilder) resolve() {
+	s := b.schema
+	for _, def := range b.types {
+		t := s.Types[def.name]
+		for _, name := range def.interfaces {
+			iface := s.Types[name]
+			if iface == nil || iface.Kind != KindInterface {
+				b.errorf(def.loc, "Type %q must only implement interface types, it cannot implement %q.", def.name, name)
+				continue
+			}
+			t.Interfaces = append(t.Interfaces, iface)
+			if t.Kind == KindObject {
+				iface.PossibleTypes = append(iface.PossibleTypes, t)
+			}
+		}
+		for _, name := range def.members {
+			member := s.Types[name]
+			if member == nil || member.Kind != KindObject {
+				b.errorf(def.loc, "Union type %q can only include object types, it cannot include %q.", def.name, name)
+				continue
+			}
+			t.PossibleTypes = append(t.PossibleTypes, member)
+		}
+		for _, f := range def.fields {
+			field := &FieldDef{
+				Name:              f.name,
+				Description:       f.description,
+				Type:              b.typeFromRef(f.typ),
+				Args:              b.inputValues(f.args),
+				Directives:        f.directives,
+				DeprecationReason: deprecationReason(f.directives),
+			}
+			t.Fields = append(t.Fields, field)
+		}
+		t.InputFields = b.inputValues(def.inputFields)
+		for _, v := range def.enumValues {
+			t.EnumValues = append(t.EnumValues, &EnumValueDef{
+				Name:              v.name,
+				Description:       v.description,
+				Directives:        v.directives,
+				DeprecationReason: deprecationReason(v.directives),
+			})
+		}
+	}
+	for _, def := range b.directives {
+		s.Directives[def.name].Args = b.inputValues(def.args)
+	}
+
+	roots := map[OperationType]string{Query: "Query", Mutation: "Mutation", Subscription: "Subscription"}
+	if b.definition != nil {
+		roots = b.definition.operations
+	}
+	for op, name := range roots {
+		t := s.Types[name]
+		if t == nil {
+			if b.definition != nil {
+				b.errorf(b.definition.loc, "Root %s type %q is not defined.", op, name)
+			}
+			continue
+		}
+		if t.Kind != KindObject {
+			b.errorf(Location{}, "Root %s type %q must be an object type.", op, name)
+			continue
+		}
+		switch op {
+		case Query:
+			s.Query = t
+		case Mutation:
+			s.Mutation = t
+		case Subscription:
+			s.Subscription = t
+		}
+	}
+	if s.Query == nil && len(b.errs) == 0 {
+		b.errorf(Location{}, "Query root type must be provided.")
+	}
+}
+
+func (b *schemaBuilder) inputValues(defs []*inputValueDefinition) []*InputValue {
+	var values []*InputValue
+	for _, def := range defs {
+		values = append(values, &InputValue{
+			Name:              def.name,
+			Description:       def.description,
+			Type:              b.typeFromRef(def.typ),
+			DefaultValue:      def.defaultValue,
+			Directives:        def.directives,
+			DeprecationReason: deprecationReason(def.directives),
+		})
+	}
+	return values
+}
+
+func (b *schemaBuilder) typeFromRef(ref *TypeRef) *Type {
+	t, err := typeFromRef(b.schema, ref)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return &Type{Kind: KindScalar, Name: ref.Name}
+	}
+	return t
+}
+
+// typeFromRef turns a type reference into a schema type.
+func typeFromRef(s *Schema, ref *TypeRef) (*Type, *Error) {
+	var t *Type
+	if ref.Elem != nil {
+		elem, err := typeFromRef(s, ref.Elem)
+		if err != nil {
+			return nil, err
+		}
+		t = listOf(elem)
+	} else if t = s.Types[ref.Name]; t == nil {
+		return nil, newError(ref.Loc, "Unknown type %q.", ref.Name)
+	}
+	if ref.NonNull {
+		t = nonNullOf(t)
+	}
+	return t, nil
+}
+
+func deprecationReason(directives []*Directive) *string {
+	for _, d := range directives {
+		if d.Name != "deprecated" {
+			continue
+		}
+		reason := "No longer supported"
+		for _, arg := range d.Arguments {
+			if arg.Name == "reason" && arg.Value.Kind == StringValue {
+				reason = arg.Value.Raw
+			}
+		}
+		return &reason
+	}
+	return nil
+}
+
+// validate checks the type system rules that cannot be enforced while
+// parsing.
+func (b *schemaBuilder) validate() {
+	for _, name := range b.schema.TypeNames() {
+		t := b.schema.Types[name]
+		switch t.Kind {
+		case KindObject, KindInterface:
+			if len(t.Fields) == 0 {
+				b.errorf(Location{}, "Type %q must define one or more fields.", t.Name)
+			}
+			for _, f := range t.Fields {
+				if !f.Type.IsOutput() {
+					b.errorf(Location{}, "The type of %s.%s must be an output type but got %q.", t.Name, f.Name, f.Type)
+				}
+				for _, arg := range f.Args {
+					if !arg.Type.IsInput() {
+						b.errorf(Location{}, "The type of %s.%s(%s:) must be an input type but got %q.", t.Name, f.Name, arg.Name, arg.Type)
+					}
+				}
+			}
+			for _, iface := range t.Interfaces {
+				b.validateImplementation(t, iface)
+			}
+		case KindUnion:
+			if len(t.PossibleTypes) == 0 {
+				b.errorf(Location{}, "Union type %q must define one or more member types.", t.Name)
+			}
+		case KindEnum:
+			if len(t.EnumValues) == 0 {
+				b.errorf(Location{}, "Enum type %q must define one or more values.", t.Name)
+			}
+		case KindInputObject:
+			if len(t.InputFields) == 0 {
+				b.errorf(Location{}, "Input Object type %q must define one or more fields.", t.Name)
+			}
+			for _, f := range t.InputFields {
+				if !f.Type.IsInput() {
+					b.errorf(Location{}, "The type of %s.%s must be an input type but got %q.", t.Name, f.Name, f.Type)
+				}
+			}
+		}
+	}
+}
+
+func (b *schemaBuilder) validateImplementation(t, iface *Type) {
+	for _, want := range iface.Fields {
+		got := t.Field(want.Name)
+		if got == nil {
+			b.errorf(Location{}, "Interface field %s.%s expected but %s does not provide it.", iface.Name, want.Name, t.Name)
+			continue
+		}
+		if !isSubtype(b.schema, got.Type, want.Type) {
+			b.errorf(Location{}, "Interface field %s.%s expects type %s but %s.%s is type %s.", iface.Name, want.Name, want.Type, t.Name, got.Name, got.Type)
+		}
+		for _, arg := range want.Args {
+			if other := got.Arg(arg.Name); other == nil || !sameType(other.Type, arg.Type) {
+				b.errorf(Location{}, "Interface field argument %s.%s(%s:) expected but %s.%s does not provide it with the same type.", iface.Name, want.Name, arg.Name, t.Name, got.Name)
+			}
+		}
+	}
+}
+
+// isSubtype reports whether values of type sub are valid where super is
+// expected, following the covariance rules for interface fields.
+func isSubtype(s *Schema, sub, super *Type) bool {
+	if sub == super {
+		return true
+	}
+	if super.Kind == KindNonNull {
+		return sub.Kind == KindNonNull && isSubtype(s, sub.OfType, super.OfType)
+	}
+	if sub.Kind == KindNonNull {
+		return isSubtype(s, sub.OfType, super)
+	}
+	if super.Kind == KindList {
+		return sub.Kind == KindList && isSubtype(s, sub.OfType, super.OfType)
+	}
+	if sub.Kind == KindList {
+		return false
+	}
+	return super.IsAbstract() && sub.Kind == KindObject && sub.Implements(super)
+}
@@ -0,0 +1,89 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+)
+
+// StreamResolver opens the source event stream of a subscription root field.
+// The stream ends when the channel is closed; the resolver must stop sending
+// and close it once p.Context is done.
+type StreamResolver func(p ResolveParams) (<-chan any, error)
+
+// Subscribe starts a subscription operation of an already validated
+// document. Every event of the source stream is executed against the
+// operation's selection set and delivered on the returned channel, which is
+// closed when the stream ends or ctx is done. When the stream cannot be
+// opened, Subscribe returns a response carrying the errors instead.
+func (e *Executor) Subscribe(ctx context.Context, doc *Document, operationName string, variables map[string]any) (<-chan *Response, *Response) {
+	x, errResp := e.prepare(ctx, doc, operationName, variables)
+	if errResp != nil {
+		return nil, errResp
+	}
+	if x.op.Type != Subscription {
+		return nil, ErrorResponse(newError(x.op.Loc, "Operation is not a subscription."))
+	}
+
+	fields := x.collectFields(x.root, x.op.SelectionSet)
+	if len(fields.keys) != 1 {
+		return nil, ErrorResponse(newError(x.op.Loc, "Subscription must select exactly one top level field."))
+	}
+	key := fields.keys[0]
+	selected := fields.fields[key]
+	def := x.fieldDef(x.root, selected[0].Name)
+	stream := e.cfg.Streams[selected[0].Name]
+	if def == nil || stream == nil {
+		return nil, ErrorResponse(newError(selected[0].Loc, "Subscription field %q has no event stream.", selected[0].Name))
+	}
+	path := []any{key}
+	args, err := coerceArguments(def.Args, selected[0].Arguments, x.vars)
+	if err == nil {
+		events, streamErr := openStream(stream, x.params(x.root, def, nil, selected, path, args))
+		if streamErr == nil {
+			return x.deliver(events), nil
+		}
+		err = streamErr
+	}
+	x.fieldError(err, selected, path)
+	return nil, &Response{Errors: x.errs}
+}
+
+func openStream(stream StreamResolver, p ResolveParams) (events <-chan any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			events, err = nil, fmt.Errorf("internal error opening stream %s: %v", p.Info.FieldName, r)
+		}
+	}()
+	return stream(p)
+}
+
+// deliver executes every event of the stream as its own execution.
+func (x *execution) deliver(events <-chan any) <-chan *Response {
+	out := make(chan *Response)
+	go func() {
+		defer close(out)
+		for {
+			var event any
+			var ok bool
+			select {
+			case <-x.ctx.Done():
+				return
+			case event, ok = <-events:
+				if !ok {
+					return
+				}
+			}
+			run := *x
+			if x.ex.cfg.Context != nil {
+				run.ctx = x.ex.cfg.Context(x.ctx)
+			}
+			resp := run.execute(event)
+			select {
+			case <-x.ctx.Done():
+				return
+			case out <- resp:
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,614 @@
+package gql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate checks an executable document against the schema and returns every
+// violation of the validation rules it enforces.
+func Validate(s *Schema, doc *Document) Errors {
+	v := &validator{schema: s, doc: doc, fragments: make(map[string]*FragmentDefinition)}
+	v.validateDefinitions()
+	for _, fragment := range doc.Fragments {
+		v.validateFragment(fragment)
+	}
+	for _, op := range doc.Operations {
+		v.validateOperation(op)
+	}
+	v.validateFragmentUsage()
+	return v.errs
+}
+
+type validator struct {
+	schema    *Schema
+	doc       *Document
+	fragments map[string]*FragmentDefinition
+	errs      Errors
+}
+
+// variableUsage records a variable used where a value of typ is expected.
+// hasDefault is true when that location provides a default value.
+type variableUsage struct {
+	value      *Value
+	typ        *Type
+	hasDefault bool
+}
+
+func (v *validator) errorf(loc Location, format string, args ...any) {
+	v.errs = append(v.errs, newError(loc, format, args...))
+}
+
+func (v *validator) validateDefinitions() {
+	names := make(map[string]bool)
+	for _, op := range v.doc.Operations {
+		if op.Name == "" {
+			if len(v.doc.Operations) > 1 {
+				v.errorf(op.Loc, "This anonymous operation must be the only defined operation.")
+			}
+			continue
+		}
+		if names[op.Name] {
+			v.errorf(op.Loc, "There can be only one operation named %q.", op.Name)
+		}
+		names[op.Name] = true
+	}
+	for _, fragment := range v.doc.Fragments {
+		if _, exists := v.fragments[fragment.Name]; exists {
+			v.errorf(fragment.Loc, "There can be only one fragment named %q.", fragment.Name)
+			continue
+		}
+		v.fragments[fragment.Name] = fragment
+	}
+	v.detectFragmentCycles()
+}
+
+func (v *validator) detectFragmentCycles() {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var visit func(fragment *FragmentDefinition, path []string)
+	visit = func(fragment *FragmentDefinition, path []string) {
+		state[fragment.Name] = visiting
+		for _, spread := range fragmentSpreads(fragment.SelectionSet) {
+			target := v.fragments[spread.Name]
+			if target == nil {
+				continue
+			}
+			switch state[target.Name] {
+			case visiting:
+				via := ""
+				if len(path) > 0 {
+					via = " via " + strings.Join(path, ", ")
+				}
+				v.errorf(spread.Loc, "Cannot spread fragment %q within itself%s.", target.Name, via)
+			case unvisited:
+				visit(target, append(path, target.Name))
+			}
+		}
+		state[fragment.Name] = done
+	}
+	for _, fragment := range v.doc.Fragments {
+		if state[fragment.Name] == unvisited {
+			visit(fragment, nil)
+		}
+	}
+}
+
+// fragmentSpreads lists the spreads of a selection set, including those
+// nested in fields and inline fragments.
+func fragmentSpreads(selections []Selection) []*FragmentSpread {
+	var spreads []*FragmentSpread
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *Field:
+			spreads = append(spreads, fragmentSpreads(sel.SelectionSet)...)
+		case *InlineFragment:
+			spreads = append(spreads, fragmentSpreads(sel.SelectionSet)...)
+		case *FragmentSpread:
+			spreads = append(spreads, sel)
+		}
+	}
+	return spreads
+}
+
+func (v *validator) validateFragmentUsage() {
+	used := make(map[string]bool)
+	var mark func(selections []Selection)
+	mark = func(selections []Selection) {
+		for _, spread := range fragmentSpreads(selections) {
+			if used[spread.Name] {
+				continue
+			}
+			used[spread.Name] = true
+			if fragment := v.fragments[spread.Name]; fragment != nil {
+				mark(fragment.SelectionSet)
+			}
+		}
+	}
+	for _, op := range v.doc.Operations {
+		mark(op.SelectionSet)
+	}
+	for _, fragment := range v.doc.Fragments {
+		if !used[fragment.Name] {
+			v.errorf(fragment.Loc, "Fragment %q is never used.", fragment.Name)
+		}
+	}
+}
+
+func (v *validator) validateFragment(fragment *FragmentDefinition) {
+	v.validateDirectives(fragment.Directives, "FRAGMENT_DEFINITION", nil)
+	t := v.schema.Type(fragment.TypeCondition)
+	if t == nil {
+		v.errorf(fragment.Loc, "Unknown type %q.", fragment.TypeCondition)
+		return
+	}
+	if !t.IsComposite() {
+		v.errorf(fragment.Loc, "Fragment %q cannot condition on non composite type %q.", fragment.Name, t.Name)
+		return
+	}
+	v.validateSelectionSet(t, fragment.SelectionSet, nil)
+}
+
+func (v *validator) validateOperation(op *Operation) {
+	root := v.schema.RootType(op.Type)
+	if root == nil {
+		v.errorf(op.Loc, "Schema is not configured for %ss.", op.Type)
+		return
+	}
+	location := strings.ToUpper(string(op.Type))
+	v.validateDirectives(op.Directives, location, nil)
+
+	defined := make(map[string]*VariableDefinition)
+	for _, def := range op.Variables {
+		if _, exists := defined[def.Name]; exists {
+			v.errorf(def.Loc, "There can be only one variable named \"$%s\".", def.Name)
+			continue
+		}
+		defined[def.Name] = def
+		v.validateDirectives(def.Directives, "VARIABLE_DEFINITION", nil)
+		t, err := typeFromRef(v.schema, def.Type)
+		if err != nil {
+			v.errs = append(v.errs, err)
+			continue
+		}
+		if !t.IsInput() {
+			v.errorf(def.Loc, "Variable \"$%s\" cannot be non-input type %q.", def.Name, def.Type)
+			continue
+		}
+		if def.Default != nil {
+			if _, err := coerceLiteral(def.Default, t, nil); err != nil {
+				v.errorf(def.Default.Loc, "Variable \"$%s\" has invalid default value: %s", def.Name, err)
+			}
+		}
+	}
+
+	var usages []variableUsage
+	v.validateSelectionSet(root, op.SelectionSet, &usages)
+	if op.Type == Subscription {
+		if fields := v.rootFields(root, op.SelectionSet, make(map[string]bool)); len(fields) != 1 {
+			name := "Anonymous Subscription"
+			if op.Name != "" {
+				name = fmt.Sprintf("Subscription %q", op.Name)
+			}
+			v.errorf(op.Loc, "%s must select only one top level field.", name)
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, usage := range usages {
+		name := usage.value.Raw
+		used[name] = true
+		def, ok := defined[name]
+		if !ok {
+			if op.Name != "" {
+				v.errorf(usage.value.Loc, "Variable \"$%s\" is not defined by operation %q.", name, op.Name)
+			} else {
+				v.errorf(usage.value.Loc, "Variable \"$%s\" is not defined.", name)
+			}
+			continue
+		}
+		varType, err := typeFromRef(v.schema, def.Type)
+		if err != nil || usage.typ == nil {
+			continue
+		}
+		if !variableAllowed(varType, def.Default, usage.typ, usage.hasDefault) {
+			v.errorf(usage.value.Loc, "Variable \"$%s\" of type %q used in position expecting type %q.", name, def.Type, usage.typ)
+		}
+	}
+	for _, def := range op.Variables {
+		if !used[def.Name] {
+			if op.Name != "" {
+				v.errorf(def.Loc, "Variable \"$%s\" is never used in operation %q.", def.Name, op.Name)
+			} else {
+				v.errorf(def.Loc, "Variable \"$%s\" is never used.", def.Name)
+			}
+		}
+	}
+}
+
+// variableAllowed implements the "All Variable Usages Are Allowed" rule.
+func variableAllowed(varType *Type, varDefault *Value, locationType *Type, locationDefault bool) bool {
+	if locationType.Kind == KindNonNull && varType.Kind != KindNonNull {
+		hasNonNullDefault := varDefault != nil && varDefault.Kind != NullValue
+		if !hasNonNullDefault && !locationDefault {
+			return false
+		}
+		return typeCompatible(varType, locationType.OfType)
+	}
+	return typeCompatible(varType, locationType)
+}
+
+func typeCompatible(varType, locationType *Type) bool {
+	if locationType.Kind == KindNonNull {
+		return varType.Kind == KindNonNull && typeCompatible(varType.OfType, locationType.OfType)
+	}
+	if varType.Kind == KindNonNull {
+		return typeCompatible(varType.OfType, locationType)
+	}
+	if locationType.Kind == KindList {
+		return varType.Kind == KindList && typeCompatible(varType.OfType, locationType.OfType)
+	}
+	if varType.Kind == KindList {
+		return false
+	}
+	return varType == locationType
+}
+
+// rootFields collects the response keys selected at the root, following
+// fragments, so a subscription can be checked for a single root field.
+func (v *validator) rootFields(t *Type, selections []Selection, seen map[string]bool) []string {
+	var keys []string
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *Field:
+			if !seen[sel.ResponseKey()] {
+				seen[sel.ResponseKey()] = true
+				keys = append(keys, sel.ResponseKey())
+			}
+		case *InlineFragment:
+			keys = append(keys, v.rootFields(t, sel.SelectionSet, seen)...)
+		case *FragmentSpread:
+			if fragment := v.fragments[sel.Name]; fragment != nil {
+				keys = append(keys, v.rootFields(t, fragment.SelectionSet, seen)...)
+			}
+		}
+	}
+	return keys
+}
+
+// validateSelectionSet checks the selections made on parent. When usages is
+// nil the set belongs to a fragment definition; otherwise the variables used
+// by the set, including through fragment spreads, are appended to it.
+func (v *validator) validateSelectionSet(parent *Type, selections []Selection, usages *[]variableUsage) {
+	v.validateFieldMerging(parent, selections)
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *Field:
+			v.validateField(parent, sel, usages)
+		case *InlineFragment:
+			v.validateDirectives(sel.Directives, "INLINE_FRAGMENT", usages)
+			t := parent
+			if sel.TypeCondition != "" {
+				if t = v.schema.Type(sel.TypeCondition); t == nil {
+					v.errorf(sel.Loc, "Unknown type %q.", sel.TypeCondition)
+					continue
+				}
+				if !t.IsComposite() {
+					v.errorf(sel.Loc, "Fragment cannot condition on non composite type %q.", t.Name)
+					continue
+				}
+				if !typesOverlap(parent, t) {
+					v.errorf(sel.Loc, "Fragment cannot be spread here as objects of type %q can never be of type %q.", parent.Name, t.Name)
+				}
+			}
+			v.validateSelectionSet(t, sel.SelectionSet, usages)
+		case *FragmentSpread:
+			v.validateDirectives(sel.Directives, "FRAGMENT_SPREAD", usages)
+			fragment := v.fragments[sel.Name]
+			if fragment == nil {
+				v.errorf(sel.Loc, "Unknown fragment %q.", sel.Name)
+				continue
+			}
+			if t := v.schema.Type(fragment.TypeCondition); t != nil && t.IsComposite() && !typesOverlap(parent, t) {
+				v.errorf(sel.Loc, "Fragment %q cannot be spread here as objects of type %q can never be of type %q.", sel.Name, parent.Name, t.Name)
+			}
+			if usages != nil {
+				v.collectFragmentUsages(fragment, usages, map[string]bool{fragment.Name: true})
+			}
+		}
+	}
+}
+
+// collectFragmentUsages gathers the variables used by a fragment for the
+// operation spreading it, without reporting the fragment's own errors again.
+func (v *validator) collectFragmentUsages(fragment *FragmentDefinition, usages *[]variableUsage, visited map[string]bool) {
+	t := v.schema.Type(fragment.TypeCondition)
+	if t == nil || !t.IsComposite() {
+		return
+	}
+	quiet := &validator{schema: v.schema, doc: v.doc, fragments: v.fragments}
+	quiet.collectUsages(t, fragment.SelectionSet, usages, visited)
+}
+
+func (v *validator) collectUsages(parent *Type, selections []Selection, usages *[]variableUsage, visited map[string]bool) {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *Field:
+			v.validateDirectives(sel.Directives, "FIELD", usages)
+			def := v.fieldDef(parent, sel.Name)
+			if def == nil {
+				continue
+			}
+			v.validateArguments(def.Args, sel.Arguments, sel.Loc, "", usages)
+			if def.Type.IsComposite() {
+				v.collectUsages(def.Type.Named(), sel.SelectionSet, usages, visited)
+			}
+		case *InlineFragment:
+			v.validateDirectives(sel.Directives, "INLINE_FRAGMENT", usages)
+			t := parent
+			if sel.TypeCondition != "" {
+				if t = v.schema.Type(sel.TypeCondition); t == nil || !t.IsComposite() {
+					continue
+				}
+			}
+			v.collectUsages(t, sel.SelectionSet, usages, visited)
+		case *FragmentSpread:
+			v.validateDirectives(sel.Directives, "FRAGMENT_SPREAD", usages)
+			fragment := v.fragments[sel.Name]
+			if fragment == nil || visited[sel.Name] {
+				continue
+			}
+			visited[sel.Name] = true
+			if t := v.schema.Type(fragment.TypeCondition); t != nil && t.IsComposite() {
+				v.collectUsages(t, fragment.SelectionSet, usages, visited)
+			}
+		}
+	}
+}
+
+func (v *validator) fieldDef(parent *Type, name string) *FieldDef {
+	if name == "__typename" {
+		return v.schema.typename
+	}
+	if def := metaFieldDef(v.schema, parent, name); def != nil {
+		return def
+	}
+	if parent.Kind == KindUnion {
+		return nil
+	}
+	return parent.Field(name)
+}
+
+func (v *validator) validateField(parent *Type, field *Field, usages *[]variableUsage) {
+	v.validateDirectives(field.Directives, "FIELD", usages)
+	def := v.fieldDef(parent, field.Name)
+	if def == nil {
+		v.errorf(field.Loc, "Cannot query field %q on type %q.", field.Name, parent.Name)
+		return
+	}
+	v.validateArguments(def.Args, field.Arguments, field.Loc, fmt.Sprintf("field %q", parent.Name+"."+field.Name), usages)
+
+	named := def.Type.Named()
+	switch {
+	case named.IsLeaf() && len(field.SelectionSet) > 0:
+		v.errorf(field.Loc, "Field %q must not have a selection since type %q has no subfields.", field.Name, def.Type)
+	case !named.IsLeaf() && len(field.SelectionSet) == 0:
+		v.errorf(field.Loc, "Field %q of type %q must have a selection of subfields.", field.Name, def.Type)
+	case !named.IsLeaf():
+		v.validateSelectionSet(named, field.SelectionSet, usages)
+	}
+}
+
+// validateArguments checks arguments against their definitions. An empty
+// owner only collects variable usages.
+func (v *validator) validateArguments(defs []*InputValue, args []*Argument, loc Location, owner string, usages *[]variableUsage) {
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		def := findInputValue(defs, arg.Name)
+		if owner != "" {
+			if seen[arg.Name] {
+				v.errorf(arg.Loc, "There can be only one argument named %q.", arg.Name)
+			}
+			seen[arg.Name] = true
+			if def == nil {
+				v.errorf(arg.Loc, "Unknown argument %q on %s.", arg.Name, owner)
+				continue
+			}
+			if _, err := coerceLiteral(arg.Value, def.Type, nil); err != nil {
+				v.errorf(arg.Value.Loc, "Argument %q has invalid value %s: %s", arg.Name, printValue(arg.Value), err)
+			}
+		}
+		if def != nil && usages != nil {
+			v.collectValueUsages(arg.Value, def.Type, def.DefaultValue != nil, usages)
+		}
+	}
+	if owner == "" {
+		return
+	}
+	for _, def := range defs {
+		if def.Type.Kind != KindNonNull || def.DefaultValue != nil || seen[def.Name] {
+			continue
+		}
+		v.errorf(loc, "Argument %q of required type %q on %s was not provided.", def.Name, def.Type, owner)
+	}
+}
+
+func (v *validator) collectValueUsages(value *Value, t *Type, hasDefault bool, usages *[]variableUsage) {
+	switch value.Kind {
+	case VariableValue:
+		*usages = append(*usages, variableUsage{value: value, typ: t, hasDefault: hasDefault})
+	case ListValue:
+		elem := t.Nullable()
+		if elem.Kind == KindList {
+			elem = elem.OfType
+		}
+		for _, item := range value.List {
+			v.collectValueUsages(item, elem, false, usages)
+		}
+	case ObjectValue:
+		object := t.Named()
+		for _, field := range value.Fields {
+			var fieldType *Type
+			fieldDefault := false
+			if object.Kind == KindInputObject {
+				if def := object.InputField(field.Name); def != nil {
+					fieldType, fieldDefault = def.Type, def.DefaultValue != nil
+				}
+			}
+			if fieldType == nil {
+				*usages = append(*usages, collectVariables(field.Value)...)
+				continue
+			}
+			v.collectValueUsages(field.Value, fieldType, fieldDefault, usages)
+		}
+	}
+}
+
+// collectVariables returns the variables of a value whose expected type is
+// unknown; they are only checked for being defined.
+func collectVariables(value *Value) []variableUsage {
+	switch value.Kind {
+	case VariableValue:
+		return []variableUsage{{value: value}}
+	case ListValue:
+		var usages []variableUsage
+		for _, item := range value.List {
+			usages = append(usages, collectVariables(item)...)
+		}
+		return usages
+	case ObjectValue:
+		var usages []variableUsage
+		for _, field := range value.Fields {
+			usages = append(usages, collectVariables(field.Value)...)
+		}
+		return usages
+	}
+	return nil
+}
+
+func (v *validator) validateDirectives(directives []*Directive, location string, usages *[]variableUsage) {
+	seen := make(map[string]bool)
+	for _, d := range directives {
+		def := v.schema.Directives[d.Name]
+		if def == nil {
+			v.errorf(d.Loc, "Unknown directive \"@%s\".", d.Name)
+			continue
+		}
+		allowed := false
+		for _, l := range def.Locations {
+			allowed = allowed || l == location
+		}
+		if !allowed {
+			v.errorf(d.Loc, "Directive \"@%s\" may not be used on %s.", d.Name, location)
+		}
+		if seen[d.Name] && !def.IsRepeatable {
+			v.errorf(d.Loc, "The directive \"@%s\" can only be used once at this location.", d.Name)
+		}
+		seen[d.Name] = true
+		v.validateArguments(def.Args, d.Arguments, d.Loc, fmt.Sprintf("directive \"@%s\"", d.Name), usages)
+	}
+}
+
+// validateFieldMerging reports fields sharing a response key that select
+// different fields or pass different arguments, since their results could
+// not be merged. Nested selections are checked when their own set is
+// validated.
+func (v *validator) validateFieldMerging(parent *Type, selections []Selection) {
+	type entry struct {
+		parent *Type
+		field  *Field
+	}
+	byKey := make(map[string][]entry)
+	var keys []string
+	var collect func(parent *Type, selections []Selection, visited map[string]bool)
+	collect = func(parent *Type, selections []Selection, visited map[string]bool) {
+		for _, selection := range selections {
+			switch sel := selection.(type) {
+			case *Field:
+				key := sel.ResponseKey()
+				if _, seen := byKey[key]; !seen {
+					keys = append(keys, key)
+				}
+				byKey[key] = append(byKey[key], entry{parent, sel})
+			case *InlineFragment:
+				t := parent
+				if sel.TypeCondition != "" {
+					t = v.schema.Type(sel.TypeCondition)
+				}
+				if t != nil {
+					collect(t, sel.SelectionSet, visited)
+				}
+			case *FragmentSpread:
+				fragment := v.fragments[sel.Name]
+				if fragment == nil || visited[sel.Name] {
+					continue
+				}
+				visited[sel.Name] = true
+				if t := v.schema.Type(fragment.TypeCondition); t != nil {
+					collect(t, fragment.SelectionSet, visited)
+				}
+			}
+		}
+	}
+	collect(parent, selections, make(map[string]bool))
+
+	for _, key := range keys {
+		entries := byKey[key]
+		first := entries[0]
+		for _, other := range entries[1:] {
+			// Fields on distinct object types never apply to the same object.
+			if first.parent != other.parent && first.parent.Kind == KindObject && other.parent.Kind == KindObject {
+				continue
+			}
+			if first.field.Name != other.field.Name {
+				v.errorf(other.field.Loc, "Fields %q conflict because %q and %q are different fields. Use different aliases on the fields to fetch both if this was intentional.", key, first.field.Name, other.field.Name)
+				break
+			}
+			if printArguments(first.field.Arguments) != printArguments(other.field.Arguments) {
+				v.errorf(other.field.Loc, "Fields %q conflict because they have differing arguments. Use different aliases on the fields to fetch both if this was intentional.", key)
+				break
+			}
+		}
+	}
+}
+
+func printArguments(args []*Argument) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		parts = append(parts, arg.Name+":"+printValue(arg.Value))
+	}
+	// Argument order does not matter.
+	for i := 1; i < len(parts); i++ {
+		for j := i; j > 0 && parts[j] < parts[j-1]; j-- {
+			parts[j], parts[j-1] = parts[j-1], parts[j]
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// typesOverlap reports whether some object type is possible for both a and
+// b.
+func typesOverlap(a, b *Type) bool {
+	if a == b {
+		return true
+	}
+	for _, x := range possibleTypes(a) {
+		for _, y := range possibleTypes(b) {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func possibleTypes(t *Type) []*Type {
+	if t.IsAbstract() {
+		return t.PossibleTypes
+	}
+	return []*Type{t}
+}
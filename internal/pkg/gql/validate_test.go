@@ -0,0 +1,187 @@
+package gql
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	schema, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "Valid",
+			query: `query ($id: ID!, $unit: Unit = FOOT) { human(id: $id) { ...H } } fragment H on Human { name height(unit: $unit) }`,
+		},
+		{
+			name:  "UnknownField",
+			query: `{ hero { name mass } }`,
+			want:  []string{`1:15 Cannot query field "mass" on type "Character".`},
+		},
+		{
+			name:  "FieldOnUnion",
+			query: `{ search(text: "") { name } }`,
+			want:  []string{`1:22 Cannot query field "name" on type "SearchResult".`},
+		},
+		{
+			name:  "LeafWithSelection",
+			query: `{ hero { name { length } } }`,
+			want:  []string{`1:10 Field "name" must not have a selection since type "String!" has no subfields.`},
+		},
+		{
+			name:  "ObjectWithoutSelection",
+			query: `{ hero }`,
+			want:  []string{`1:3 Field "hero" of type "Character" must have a selection of subfields.`},
+		},
+		{
+			name:  "UnknownArgument",
+			query: `{ hero(side: DARK) { name } }`,
+			want:  []string{`1:8 Unknown argument "side" on field "Query.hero".`},
+		},
+		{
+			name:  "MissingRequiredArgument",
+			query: `{ human { name } }`,
+			want:  []string{`1:3 Argument "id" of required type "ID!" on field "Query.human" was not provided.`},
+		},
+		{
+			name:  "InvalidLiteral",
+			query: `{ hero(episode: "JEDI") { name } }`,
+			want:  []string{`1:17 Argument "episode" has invalid value "JEDI": value "JEDI" does not exist in "Episode" enum`},
+		},
+		{
+			name:  "ConflictingAliases",
+			query: `{ hero { x: name x: id } }`,
+			want:  []string{`1:18 Fields "x" conflict because "name" and "id" are different fields. Use different aliases on the fields to fetch both if this was intentional.`},
+		},
+		{
+			name:  "UnknownFragment",
+			query: `{ hero { ...Missing } }`,
+			want:  []string{`1:10 Unknown fragment "Missing".`},
+		},
+		{
+			name:  "UnusedFragment",
+			query: `{ hero { name } } fragment F on Human { name }`,
+			want:  []string{`1:19 Fragment "F" is never used.`},
+		},
+		{
+			name:  "FragmentCycle",
+			query: `{ hero { ...A } } fragment A on Character { ...B } fragment B on Character { name ...C } fragment C on Character { ...A }`,
+			want:  []string{`1:116 Cannot spread fragment "A" within itself via B, C.`},
+		},
+		{
+			name:  "FragmentSpreadingItself",
+			query: `{ hero { ...A } } fragment A on Character { name ...A }`,
+			want:  []string{`1:50 Cannot spread fragment "A" within itself.`},
+		},
+		{
+			name:  "ImpossibleSpread",
+			query: `{ droid(id: "1") { ... on Human { name } } }`,
+			want:  []string{`1:20 Fragment cannot be spread here as objects of type "Droid" can never be of type "Human".`},
+		},
+		{
+			name:  "FragmentOnScalar",
+			query: `{ hero { ...F } } fragment F on String { length }`,
+			want:  []string{`1:19 Fragment "F" cannot condition on non composite type "String".`},
+		},
+		{
+			name:  "DuplicateOperations",
+			query: `query A { hero { name } } query A { hero { id } }`,
+			want:  []string{`1:27 There can be only one operation named "A".`},
+		},
+		{
+			name:  "AnonymousAmongOthers",
+			query: `{ hero { name } } query A { hero { id } }`,
+			want:  []string{`1:1 This anonymous operation must be the only defined operation.`},
+		},
+		{
+			name:  "UndefinedVariable",
+			query: `query Q { human(id: $id) { name } }`,
+			want:  []string{`1:21 Variable "$id" is not defined by operation "Q".`},
+		},
+		{
+			name:  "UndefinedVariableInFragment",
+			query: `query Q { hero { ...H } } fragment H on Character { ... on Human { height(unit: $unit) } }`,
+			want:  []string{`1:81 Variable "$unit" is not defined by operation "Q".`},
+		},
+		{
+			name:  "UnusedVariable",
+			query: `query ($id: ID) { hero { name } }`,
+			want:  []string{`1:8 Variable "$id" is never used.`},
+		},
+		{
+			name:  "NullableVariableInNonNullPosition",
+			query: `query ($id: ID) { human(id: $id) { name } }`,
+			want:  []string{`1:29 Variable "$id" of type "ID" used in position expecting type "ID!".`},
+		},
+		{
+			name:  "DefaultAllowsNonNullPosition",
+			query: `query ($id: ID = "1000") { human(id: $id) { name } }`,
+		},
+		{
+			name:  "WrongVariableType",
+			query: `query ($ids: [ID!]) { echo(count: $ids) }`,
+			want:  []string{`1:35 Variable "$ids" of type "[ID!]" used in position expecting type "Int".`},
+		},
+		{
+			name:  "VariableInsideInputObject",
+			query: `query ($stars: Int) { echo(review: {stars: $stars}) }`,
+			want:  []string{`1:44 Variable "$stars" of type "Int" used in position expecting type "Int!".`},
+		},
+		{
+			name:  "OutputTypeVariable",
+			query: `query ($hero: Character) { echo(flag: true) }`,
+			want:  []string{`1:8 Variable "$hero" cannot be non-input type "Character".`, `1:8 Variable "$hero" is never used.`},
+		},
+		{
+			name:  "InvalidVariableDefault",
+			query: `query ($count: Int = "many") { echo(count: $count) }`,
+			want:  []string{`1:22 Variable "$count" has invalid default value: Int cannot represent value: "many"`},
+		},
+		{
+			name:  "UnknownDirective",
+			query: `{ hero @cached { name } }`,
+			want:  []string{`1:8 Unknown directive "@cached".`},
+		},
+		{
+			name:  "MisplacedDirective",
+			query: `query @skip(if: true) { hero { name } }`,
+			want:  []string{`1:7 Directive "@skip" may not be used on QUERY.`},
+		},
+		{
+			name:  "RepeatedDirective",
+			query: `{ hero @skip(if: false) @skip(if: true) { name } }`,
+			want:  []string{`1:25 The directive "@skip" can only be used once at this location.`},
+		},
+		{
+			name:  "UnsupportedOperationType",
+			query: `subscription { hero { name } }`,
+			want:  []string{`1:1 Schema is not configured for subscriptions.`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			var got []string
+			for _, err := range Validate(schema, doc) {
+				loc := ""
+				if len(err.Locations) > 0 {
+					loc = fmt.Sprintf("%d:%d ", err.Locations[0].Line, err.Locations[0].Column)
+				}
+				got = append(got, loc+err.Message)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("errors\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,509 @@
+package gql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// coerceVariables coerces the raw JSON variables of a request according to
+// the variable definitions of op.
+func coerceVariables(s *Schema, op *Operation, raw map[string]any) (map[string]any, Errors) {
+	coerced := make(map[string]any, len(op.Variables))
+	var errs Errors
+	for _, def := range op.Variables {
+		t, typeErr := typeFromRef(s, def.Type)
+		if typeErr != nil {
+			errs = append(errs, typeErr)
+			continue
+		}
+		value, provided := raw[def.Name]
+		if !provided {
+			if def.Default != nil {
+				v, err := coerceLiteral(def.Default, t, nil)
+				if err != nil {
+					errs = append(errs, newError(def.Loc, "Variable \"$%s\" has an invalid default value: %s", def.Name, err))
+					continue
+				}
+				coerced[def.Name] = v
+			} else if t.Kind == KindNonNull {
+				errs = append(errs, newError(def.Loc, "Variable \"$%s\" of required type %q was not provided.", def.Name, t))
+			}
+			continue
+		}
+		v, err := coerceInput(value, t)
+		if err != nil {
+			errs = append(errs, newError(def.Loc, "Variable \"$%s\" got invalid value %s; %s", def.Name, jsonString(value), err))
+			continue
+		}
+		coerced[def.Name] = v
+	}
+	return coerced, errs
+}
+
+// coerceInput coerces an external (JSON) input value to type t.
+func coerceInput(value any, t *Type) (any, error) {
+	if t.Kind == KindNonNull {
+		if value == nil {
+			return nil, fmt.Errorf("expected non-nullable type %q not to be null", t)
+		}
+		return coerceInput(value, t.OfType)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch t.Kind {
+	case KindList:
+		items, ok := value.([]any)
+		if !ok {
+			item, err := coerceInput(value, t.OfType)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+		result := make([]any, len(items))
+		for i, item := range items {
+			v, err := coerceInput(item, t.OfType)
+			if err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+			result[i] = v
+		}
+		return result, nil
+	case KindInputObject:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected type %q to be an object", t.Name)
+		}
+		for name := range fields {
+			if t.InputField(name) == nil {
+				return nil, fmt.Errorf("field %q is not defined by type %q", name, t.Name)
+			}
+		}
+		result := make(map[string]any, len(t.InputFields))
+		for _, field := range t.InputFields {
+			raw, provided := fields[field.Name]
+			if !provided {
+				if field.DefaultValue != nil {
+					v, err := coerceLiteral(field.DefaultValue, field.Type, nil)
+					if err != nil {
+						return nil, err
+					}
+					result[field.Name] = v
+				} else if field.Type.Kind == KindNonNull {
+					return nil, fmt.Errorf("field %q of required type %q was not provided", field.Name, field.Type)
+				}
+				continue
+			}
+			v, err := coerceInput(raw, field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("at field %q: %w", field.Name, err)
+			}
+			result[field.Name] = v
+		}
+		return result, nil
+	case KindEnum:
+		name, ok := value.(string)
+		if !ok || t.EnumValue(name) == nil {
+			return nil, fmt.Errorf("value %s does not exist in %q enum", jsonString(value), t.Name)
+		}
+		return name, nil
+	default:
+		return parseScalarValue(t, value)
+	}
+}
+
+func parseScalarValue(t *Type, value any) (any, error) {
+	switch t.Name {
+	case "Int":
+		switch v := value.(type) {
+		case float64:
+			if v != math.Trunc(v) || v > math.MaxInt32 || v < math.MinInt32 {
+				return nil, fmt.Errorf("Int cannot represent non-integer or out of range value %v", v)
+			}
+			return int(v), nil
+		case json.Number:
+			i, err := strconv.ParseInt(string(v), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("Int cannot represent value %s", v)
+			}
+			return int(i), nil
+		case int:
+			if v > math.MaxInt32 || v < math.MinInt32 {
+				return nil, fmt.Errorf("Int cannot represent out of range value %d", v)
+			}
+			return v, nil
+		}
+		return nil, fmt.Errorf("Int cannot represent non-integer value %s", jsonString(value))
+	case "Float":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("Float cannot represent value %s", v)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("Float cannot represent non numeric value %s", jsonString(value))
+	case "String":
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("String cannot represent a non string value %s", jsonString(value))
+	case "Boolean":
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("Boolean cannot represent a non boolean value %s", jsonString(value))
+	case "ID":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) {
+				return strconv.FormatInt(int64(v), 10), nil
+			}
+		case int:
+			return strconv.Itoa(v), nil
+		case json.Number:
+			if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+				return string(v), nil
+			}
+		}
+		return nil, fmt.Errorf("ID cannot represent value %s", jsonString(value))
+	}
+	if t.Scalar != nil && t.Scalar.ParseValue != nil {
+		return t.Scalar.ParseValue(value)
+	}
+	return value, nil
+}
+
+// coerceLiteral coerces a literal of the document to type t. Variables are
+// looked up in vars; a nil vars accepts any variable, which is how validation
+// checks literals before variables are known. An absent variable yields
+// errAbsent so the caller can apply defaults.
+func coerceLiteral(v *Value, t *Type, vars map[string]any) (any, error) {
+	if v.Kind == VariableValue {
+		if vars == nil {
+			return nil, nil
+		}
+		value, ok := vars[v.Raw]
+		if !ok {
+			return nil, errAbsent
+		}
+		if value == nil && t.Kind == KindNonNull {
+			return nil, fmt.Errorf("expected non-nullable type %q not to be null", t)
+		}
+		return value, nil
+	}
+	if t.Kind == KindNonNull {
+		if v.Kind == NullValue {
+			return nil, fmt.Errorf("expected value of type %q, found null", t)
+		}
+		return coerceLiteral(v, t.OfType, vars)
+	}
+	if v.Kind == NullValue {
+		return nil, nil
+	}
+
+	switch t.Kind {
+	case KindList:
+		if v.Kind != ListValue {
+			item, err := coerceLiteral(v, t.OfType, vars)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+		result := make([]any, len(v.List))
+		for i, item := range v.List {
+			coerced, err := coerceLiteral(item, t.OfType, vars)
+			if err == errAbsent {
+				coerced, err = nil, nil
+				if t.OfType.Kind == KindNonNull {
+					err = fmt.Errorf("expected value of type %q, found an absent variable", t.OfType)
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			result[i] = coerced
+		}
+		return result, nil
+	case KindInputObject:
+		if v.Kind != ObjectValue {
+			return nil, fmt.Errorf("expected value of type %q, found %s", t.Name, printValue(v))
+		}
+		for _, field := range v.Fields {
+			if t.InputField(field.Name) == nil {
+				return nil, fmt.Errorf("field %q is not defined by type %q", field.Name, t.Name)
+			}
+		}
+		result := make(map[string]any, len(t.InputFields))
+		for _, def := range t.InputFields {
+			var literal *Value
+			for _, field := range v.Fields {
+				if field.Name == def.Name {
+					literal = field.Value
+				}
+			}
+			var value any
+			err := errAbsent
+			if literal != nil {
+				value, err = coerceLiteral(literal, def.Type, vars)
+			}
+			if err == errAbsent {
+				switch {
+				case def.DefaultValue != nil:
+					value, err = coerceLiteral(def.DefaultValue, def.Type, nil)
+				case def.Type.Kind == KindNonNull:
+					err = fmt.Errorf("field %s.%s of required type %q was not provided", t.Name, def.Name, def.Type)
+				default:
+					continue
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			result[def.Name] = value
+		}
+		return result, nil
+	case KindEnum:
+		if v.Kind != EnumValue || t.EnumValue(v.Raw) == nil {
+			return nil, fmt.Errorf("value %s does not exist in %q enum", printValue(v), t.Name)
+		}
+		return v.Raw, nil
+	default:
+		return parseScalarLiteral(t, v)
+	}
+}
+
+func parseScalarLiteral(t *Type, v *Value) (any, error) {
+	switch t.Name {
+	case "Int":
+		if v.Kind == IntValue {
+			if i, err := strconv.ParseInt(v.Raw, 10, 32); err == nil {
+				return int(i), nil
+			}
+			return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %s", v.Raw)
+		}
+	case "Float":
+		if v.Kind == IntValue || v.Kind == FloatValue {
+			f, err := strconv.ParseFloat(v.Raw, 64)
+			if err == nil {
+				return f, nil
+			}
+		}
+	case "String":
+		if v.Kind == StringValue {
+			return v.Raw, nil
+		}
+	case "Boolean":
+		if v.Kind == BooleanValue {
+			return v.Raw == "true", nil
+		}
+	case "ID":
+		if v.Kind == StringValue || v.Kind == IntValue {
+			return v.Raw, nil
+		}
+	default:
+		if t.Scalar != nil && t.Scalar.ParseLiteral != nil {
+			return t.Scalar.ParseLiteral(v)
+		}
+		return valueFromLiteral(v), nil
+	}
+	return nil, fmt.Errorf("%s cannot represent value: %s", t.Name, printValue(v))
+}
+
+// valueFromLiteral converts a constant literal to a plain Go value.
+func valueFromLiteral(v *Value) any {
+	switch v.Kind {
+	case IntValue:
+		i, _ := strconv.Atoi(v.Raw)
+		return i
+	case FloatValue:
+		f, _ := strconv.ParseFloat(v.Raw, 64)
+		return f
+	case BooleanValue:
+		return v.Raw == "true"
+	case NullValue:
+		return nil
+	case ListValue:
+		items := make([]any, len(v.List))
+		for i, item := range v.List {
+			items[i] = valueFromLiteral(item)
+		}
+		return items
+	case ObjectValue:
+		fields := make(map[string]any, len(v.Fields))
+		for _, field := range v.Fields {
+			fields[field.Name] = valueFromLiteral(field.Value)
+		}
+		return fields
+	default:
+		return v.Raw
+	}
+}
+
+type absentError struct{}
+
+func (absentError) Error() string { return "absent value" }
+
+var errAbsent error = absentError{}
+
+// coerceArguments computes the argument values of a field or directive.
+func coerceArguments(defs []*InputValue, args []*Argument, vars map[string]any) (map[string]any, error) {
+	values := make(map[string]any, len(defs))
+	for _, def := range defs {
+		var (
+			value any
+			err   = errAbsent
+		)
+		for _, arg := range args {
+			if arg.Name == def.Name {
+				value, err = coerceLiteral(arg.Value, def.Type, vars)
+			}
+		}
+		if err == errAbsent {
+			switch {
+			case def.DefaultValue != nil:
+				value, err = coerceLiteral(def.DefaultValue, def.Type, nil)
+			case def.Type.Kind == KindNonNull:
+				err = fmt.Errorf("argument %q of required type %q was not provided", def.Name, def.Type)
+			default:
+				continue
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("argument %q has invalid value: %w", def.Name, err)
+		}
+		values[def.Name] = value
+	}
+	return values, nil
+}
+
+// serializeLeaf converts a resolved Go value into the JSON representation of
+// the scalar or enum type t.
+func serializeLeaf(t *Type, value any) (any, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	switch {
+	case t.Kind == KindEnum:
+		if rv.Kind() == reflect.String && t.EnumValue(rv.String()) != nil {
+			return rv.String(), nil
+		}
+		return nil, fmt.Errorf("Enum %q cannot represent value: %v", t.Name, rv.Interface())
+	case t.Name == "Int":
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i := rv.Int(); i >= math.MinInt32 && i <= math.MaxInt32 {
+				return int(i), nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if u := rv.Uint(); u <= math.MaxInt32 {
+				return int(u), nil
+			}
+		case reflect.Float32, reflect.Float64:
+			if f := rv.Float(); f == math.Trunc(f) && f >= math.MinInt32 && f <= math.MaxInt32 {
+				return int(f), nil
+			}
+		case reflect.Bool:
+			if rv.Bool() {
+				return 1, nil
+			}
+			return 0, nil
+		}
+		return nil, fmt.Errorf("Int cannot represent value: %v", rv.Interface())
+	case t.Name == "Float":
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			return rv.Float(), nil
+		}
+		return nil, fmt.Errorf("Float cannot represent value: %v", rv.Interface())
+	case t.Name == "String" || t.Name == "ID":
+		switch rv.Kind() {
+		case reflect.String:
+			return rv.String(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(rv.Int(), 10), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.FormatUint(rv.Uint(), 10), nil
+		case reflect.Bool:
+			if t.Name == "String" {
+				return strconv.FormatBool(rv.Bool()), nil
+			}
+		}
+		if s, ok := rv.Interface().(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+		return nil, fmt.Errorf("%s cannot represent value: %v", t.Name, rv.Interface())
+	case t.Name == "Boolean":
+		if rv.Kind() == reflect.Bool {
+			return rv.Bool(), nil
+		}
+		return nil, fmt.Errorf("Boolean cannot represent a non boolean value: %v", rv.Interface())
+	}
+	if t.Scalar != nil && t.Scalar.Serialize != nil {
+		return t.Scalar.Serialize(rv.Interface())
+	}
+	return rv.Interface(), nil
+}
+
+// printValue renders a literal the way it appears in a document.
+func printValue(v *Value) string {
+	switch v.Kind {
+	case VariableValue:
+		return "$" + v.Raw
+	case StringValue:
+		return strconv.Quote(v.Raw)
+	case ListValue:
+		s := "["
+		for i, item := range v.List {
+			if i > 0 {
+				s += ", "
+			}
+			s += printValue(item)
+		}
+		return s + "]"
+	case ObjectValue:
+		s := "{"
+		for i, field := range v.Fields {
+			if i > 0 {
+				s += ", "
+			}
+			s += field.Name + ": " + printValue(field.Value)
+		}
+		return s + "}"
+	default:
+		return v.Raw
+	}
+}
+
+func jsonString(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,183 @@
+package gql
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestCoerceVariables(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		want      string
+	}{
+		{
+			name:      "Scalars",
+			query:     `query ($count: Int, $ratio: Float, $flag: Boolean, $unit: Unit) { echo(count: $count, ratio: $ratio, flag: $flag, unit: $unit) }`,
+			variables: map[string]any{"count": float64(3), "ratio": float64(2), "flag": true, "unit": "FOOT"},
+			want:      `{"data":{"echo":"{\"count\":3,\"flag\":true,\"ratio\":2,\"unit\":\"FOOT\"}"}}`,
+		},
+		{
+			name:      "IDsFromNumbers",
+			query:     `query ($ids: [ID!]) { echo(ids: $ids) }`,
+			variables: map[string]any{"ids": []any{"a", float64(7)}},
+			want:      `{"data":{"echo":"{\"ids\":[\"a\",\"7\"]}"}}`,
+		},
+		{
+			name:      "SingleValueAsList",
+			query:     `query ($ids: [ID!]) { echo(ids: $ids) }`,
+			variables: map[string]any{"ids": "a"},
+			want:      `{"data":{"echo":"{\"ids\":[\"a\"]}"}}`,
+		},
+		{
+			name:      "InputObjectDefaults",
+			query:     `query ($review: ReviewInput) { echo(review: $review) }`,
+			variables: map[string]any{"review": map[string]any{"stars": float64(4), "commentary": nil}},
+			want:      `{"data":{"echo":"{\"review\":{\"commentary\":null,\"episode\":\"NEWHOPE\",\"stars\":4,\"tags\":[]}}"}}`,
+		},
+		{
+			name:  "VariableDefault",
+			query: `query ($count: Int = 2) { echo(count: $count) }`,
+			want:  `{"data":{"echo":"{\"count\":2}"}}`,
+		},
+		{
+			name:  "AbsentVariableLeavesArgumentOut",
+			query: `query ($count: Int) { echo(count: $count) }`,
+			want:  `{"data":{"echo":"{}"}}`,
+		},
+		{
+			name:      "ExplicitNull",
+			query:     `query ($count: Int) { echo(count: $count) }`,
+			variables: map[string]any{"count": nil},
+			want:      `{"data":{"echo":"{\"count\":null}"}}`,
+		},
+		{
+			name:      "LiteralsWithVariablesInside",
+			query:     `query ($stars: Int!) { echo(review: {stars: $stars, tags: "solo"}, ids: [1, "b"]) }`,
+			variables: map[string]any{"stars": float64(1)},
+			want:      `{"data":{"echo":"{\"ids\":[\"1\",\"b\"],\"review\":{\"episode\":\"NEWHOPE\",\"stars\":1,\"tags\":[\"solo\"]}}"}}`,
+		},
+		{
+			name:  "MissingRequiredVariable",
+			query: `query ($id: ID!) { human(id: $id) { name } }`,
+			want:  `{"errors":[{"message":"Variable \"$id\" of required type \"ID!\" was not provided.","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:      "NullForRequiredVariable",
+			query:     `query ($id: ID!) { human(id: $id) { name } }`,
+			variables: map[string]any{"id": nil},
+			want:      `{"errors":[{"message":"Variable \"$id\" got invalid value null; expected non-nullable type \"ID!\" not to be null","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:      "FractionalInt",
+			query:     `query ($count: Int) { echo(count: $count) }`,
+			variables: map[string]any{"count": 1.5},
+			want:      `{"errors":[{"message":"Variable \"$count\" got invalid value 1.5; Int cannot represent non-integer or out of range value 1.5","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:      "UnknownEnumValue",
+			query:     `query ($unit: Unit) { echo(unit: $unit) }`,
+			variables: map[string]any{"unit": "INCH"},
+			want:      `{"errors":[{"message":"Variable \"$unit\" got invalid value \"INCH\"; value \"INCH\" does not exist in \"Unit\" enum","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:      "UnknownInputField",
+			query:     `query ($review: ReviewInput) { echo(review: $review) }`,
+			variables: map[string]any{"review": map[string]any{"stars": float64(1), "rating": float64(2)}},
+			want:      `{"errors":[{"message":"Variable \"$review\" got invalid value {\"rating\":2,\"stars\":1}; field \"rating\" is not defined by type \"ReviewInput\"","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:      "MissingRequiredInputField",
+			query:     `query ($review: ReviewInput) { echo(review: $review) }`,
+			variables: map[string]any{"review": map[string]any{}},
+			want:      `{"errors":[{"message":"Variable \"$review\" got invalid value {}; field \"stars\" of required type \"Int!\" was not provided","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:      "InvalidListItem",
+			query:     `query ($ids: [ID!]) { echo(ids: $ids) }`,
+			variables: map[string]any{"ids": []any{"a", nil}},
+			want:      `{"errors":[{"message":"Variable \"$ids\" got invalid value [\"a\",null]; at index 1: expected non-nullable type \"ID!\" not to be null","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:      "StringForBoolean",
+			query:     `query ($flag: Boolean) { echo(flag: $flag) }`,
+			variables: map[string]any{"flag": "true"},
+			want:      `{"errors":[{"message":"Variable \"$flag\" got invalid value \"true\"; Boolean cannot represent a non boolean value \"true\"","locations":[{"line":1,"column":8}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := run(t, newTestExecutor(t, Limits{}), Request{Query: tt.query, Variables: tt.variables})
+			if got != tt.want {
+				t.Errorf("response\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScalarValue(t *testing.T) {
+	schema, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	tests := []struct {
+		typ     string
+		value   any
+		want    any
+		wantErr bool
+	}{
+		{typ: "Int", value: float64(42), want: 42},
+		{typ: "Int", value: json.Number("-7"), want: -7},
+		{typ: "Int", value: float64(math.MaxInt32 + 1), wantErr: true},
+		{typ: "Int", value: "1", wantErr: true},
+		{typ: "Float", value: 7, want: float64(7)},
+		{typ: "Float", value: json.Number("2.5"), want: 2.5},
+		{typ: "Float", value: true, wantErr: true},
+		{typ: "String", value: "text", want: "text"},
+		{typ: "String", value: float64(1), wantErr: true},
+		{typ: "ID", value: json.Number("12"), want: "12"},
+		{typ: "ID", value: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseScalarValue(schema.Type(tt.typ), tt.value)
+		if (err != nil) != tt.wantErr || !tt.wantErr && got != tt.want {
+			t.Errorf("parseScalarValue(%s, %#v) = %#v, %v; want %#v, error %t", tt.typ, tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSerializeLeaf(t *testing.T) {
+	schema, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	type unit string
+	three := 3
+	tests := []struct {
+		typ     string
+		value   any
+		want    any
+		wantErr bool
+	}{
+		{typ: "Int", value: &three, want: 3},
+		{typ: "Int", value: uint8(9), want: 9},
+		{typ: "Int", value: 2.0, want: 2},
+		{typ: "Int", value: int64(math.MaxInt32) + 1, wantErr: true},
+		{typ: "Int", value: 2.5, wantErr: true},
+		{typ: "Float", value: int32(4), want: float64(4)},
+		{typ: "String", value: false, want: "false"},
+		{typ: "ID", value: int64(12), want: "12"},
+		{typ: "ID", value: true, wantErr: true},
+		{typ: "Boolean", value: 1, wantErr: true},
+		{typ: "Unit", value: unit("FOOT"), want: "FOOT"},
+		{typ: "Unit", value: "INCH", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := serializeLeaf(schema.Type(tt.typ), tt.value)
+		if (err != nil) != tt.wantErr || !tt.wantErr && got != tt.want {
+			t.Errorf("serializeLeaf(%s, %#v) = %#v, %v; want %#v, error %t", tt.typ, tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package lru
+
+import "testing"
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	// Reading a makes b the least recently used entry.
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v, want 1, true", v, ok)
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b was kept, want it evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Fatalf("Get(%s) = %d, %v, want %d, true", key, v, ok, want)
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+}
+
+func TestCacheAddUpdatesExistingEntry(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	// Replacing a makes it the most recently used entry without growing the
+	// cache.
+	c.Add("a", 10)
+	if n := c.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b was kept, want it evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Fatalf("Get(a) = %d, %v, want 10, true", v, ok)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		c := New[string, int](capacity)
+		c.Add("a", 1)
+		if _, ok := c.Get("a"); ok || c.Len() != 0 {
+			t.Fatalf("cache of capacity %d kept an entry", capacity)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey and testAccept are the handshake example of RFC 6455.
+const (
+	testKey    = "dGhlIHNhbXBsZSBub25jZQ=="
+	testAccept = "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+)
+
+func handshakeRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/graphql", nil)
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", testKey)
+	return r
+}
+
+func TestUpgradeRejectsInvalidHandshakes(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(r *http.Request)
+		status int
+	}{
+		{"post", func(r *http.Request) { r.Method = http.MethodPost }, http.StatusBadRequest},
+		{"no upgrade", func(r *http.Request) { r.Header.Del("Upgrade") }, http.StatusBadRequest},
+		{"old version", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }, http.StatusUpgradeRequired},
+		{"missing key", func(r *http.Request) { r.Header.Del("Sec-WebSocket-Key") }, http.StatusBadRequest},
+		{"key not base64", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "not a key!") }, http.StatusBadRequest},
+		{"short key", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "c2hvcnQ=") }, http.StatusBadRequest},
+		{"foreign origin", func(r *http.Request) { r.Header.Set("Origin", "https://evil.example.org") }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := handshakeRequest()
+			tt.modify(r)
+			rec := httptest.NewRecorder()
+			if _, err := Upgrade(rec, r, Options{}); err == nil {
+				t.Fatal("Upgrade succeeded, want an error")
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusUpgradeRequired && rec.Header().Get("Sec-WebSocket-Version") != "13" {
+				t.Fatalf("Sec-WebSocket-Version = %q, want 13", rec.Header().Get("Sec-WebSocket-Version"))
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{"", nil, true},
+		{"http://example.com", nil, true},
+		{"http://EXAMPLE.com", nil, true},
+		{"http://example.com.evil.org", nil, false},
+		{"https://app.example.org", []string{"https://app.example.org/"}, true},
+		{"https://other.example.org", []string{"https://app.example.org"}, false},
+		{"https://other.example.org", []string{"*"}, true},
+		{"null", nil, false},
+	}
+	for _, tt := range tests {
+		r := handshakeRequest()
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := CheckOrigin(r, tt.allowed); got != tt.want {
+			t.Errorf("CheckOrigin(%q, %q) = %v, want %v", tt.origin, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+// testClient is the client end of a connection to a server started by
+// serve. It writes raw frames so that tests control masking and
+// fragmentation.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+	// errs receives the error that ended the server's read loop.
+	errs chan error
+}
+
+// serve starts a server echoing the messages of each connection with the
+// given options, and opens a connection to it.
+func serve(t *testing.T, opts Options, protocols string, setup func(*Conn)) *testClient {
+	t.Helper()
+	errs := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, opts)
+		if err != nil {
+			errs <- err
+			return
+		}
+		if setup != nil {
+			setup(conn)
+		}
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET / HTTP/1.1\r\nHost: " + server.Listener.Addr().String() +
+		"\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: " + testKey + "\r\n"
+	if protocols != "" {
+		request += "Sec-WebSocket-Protocol: " + protocols + "\r\n"
+	}
+	if _, err := conn.Write([]byte(request + "\r\n")); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != testAccept {
+		t.Fatalf("Sec-WebSocket-Accept = %q, want %q", accept, testAccept)
+	}
+	return &testClient{t: t, conn: conn, br: br, errs: errs}
+}
+
+// writeFrame sends one frame, masked unless unmasked is set.
+func (c *testClient) writeFrame(fin bool, opcode int, payload []byte, unmasked bool) {
+	c.t.Helper()
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first, 0}
+	switch {
+	case len(payload) <= 125:
+		frame[1] = byte(len(payload))
+	case len(payload) <= 0xffff:
+		frame[1] = 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame[1] = 127
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	if unmasked {
+		frame = append(frame, payload...)
+	} else {
+		frame[1] |= 0x80
+		mask := []byte{0x12, 0x34, 0x56, 0x78}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("write frame: %v", err)
+	}
+}
+
+// readFrame reads one unfragmented frame sent by the server, which must not
+// be masked.
+func (c *testClient) readFrame() (opcode int, payload []byte) {
+	c.t.Helper()
+	var header [2]byte
+	c.readFull(header[:])
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		c.t.Fatalf("frame header %08b %08b, want a final unmasked frame", header[0], header[1])
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		c.readFull(ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload = make([]byte, length)
+	c.readFull(payload)
+	return int(header[0] & 0x0f), payload
+}
+
+func (c *testClient) readFull(p []byte) {
+	c.t.Helper()
+	if _, err := io.ReadFull(c.br, p); err != nil {
+		c.t.Fatalf("read frame: %v", err)
+	}
+}
+
+// expectClose reads a close frame, checks it carries code, and returns the
+// error that ended the server's read loop.
+func (c *testClient) expectClose(code int) *CloseError {
+	c.t.Helper()
+	opcode, payload := c.readFrame()
+	if opcode != CloseMessage || len(payload) < 2 {
+		c.t.Fatalf("received frame %d %q, want a close frame", opcode, payload)
+	}
+	if got := int(binary.BigEndian.Uint16(payload)); got != code {
+		c.t.Fatalf("close code = %d (%q), want %d", got, payload[2:], code)
+	}
+	var closeErr *CloseError
+	if err := <-c.errs; !errors.As(err, &closeErr) {
+		c.t.Fatalf("ReadMessage returned %v, want a *CloseError", err)
+	}
+	return closeErr
+}
+
+func closePayload(code int, reason string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+func TestUpgradeNegotiatesSubprotocol(t *testing.T) {
+	negotiated := make(chan string, 1)
+	serve(t, Options{Protocols: []string{"graphql-transport-ws", "graphql-ws"}}, "chat, graphql-ws, graphql-transport-ws", func(conn *Conn) {
+		negotiated <- conn.Subprotocol()
+	})
+	if got := <-negotiated; got != "graphql-ws" {
+		t.Fatalf("Subprotocol = %q, want the first requested one the server speaks", got)
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	c := serve(t, Options{}, "", nil)
+
+	c.writeFrame(true, TextMessage, []byte("hello"), false)
+	if opcode, payload := c.readFrame(); opcode != TextMessage || string(payload) != "hello" {
+		t.Fatalf("echo = %d %q, want the text message", opcode, payload)
+	}
+
+	// A fragmented message, interleaved with control frames.
+	c.writeFrame(false, BinaryMessage, []byte{1, 2}, false)
+	c.writeFrame(true, PingMessage, []byte("are you there"), false)
+	c.writeFrame(false, 0, []byte{3}, false)
+	c.writeFrame(true, PongMessage, nil, false)
+	c.writeFrame(true, 0, []byte{4, 5}, false)
+	if opcode, payload := c.readFrame(); opcode != PongMessage || string(payload) != "are you there" {
+		t.Fatalf("received %d %q, want the pong answering the ping", opcode, payload)
+	}
+	if opcode, payload := c.readFrame(); opcode != BinaryMessage || string(payload) != "\x01\x02\x03\x04\x05" {
+		t.Fatalf("echo = %d %v, want the reassembled binary message", opcode, payload)
+	}
+
+	// Lengths with 16-bit extensions.
+	long := strings.Repeat("x", 300)
+	c.writeFrame(true, TextMessage, []byte(long), false)
+	if opcode, payload := c.readFrame(); opcode != TextMessage || string(payload) != long {
+		t.Fatalf("echo of a %d-byte message = %d, %d bytes", len(long), opcode, len(payload))
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	c := serve(t, Options{}, "", nil)
+	c.writeFrame(true, CloseMessage, closePayload(CloseGoingAway, "bye"), false)
+	closeErr := c.expectClose(CloseGoingAway)
+	if closeErr.Code != CloseGoingAway || closeErr.Reason != "bye" {
+		t.Fatalf("CloseError = %+v, want the code and reason of the client", closeErr)
+	}
+
+	// A close frame without a status is answered with a normal closure.
+	c = serve(t, Options{}, "", nil)
+	c.writeFrame(true, CloseMessage, nil, false)
+	if closeErr := c.expectClose(CloseNormal); closeErr.Code != CloseNoStatus {
+		t.Fatalf("CloseError code = %d, want %d", closeErr.Code, CloseNoStatus)
+	}
+}
+
+func TestReadMessageFailsProtocolViolations(t *testing.T) {
+	tests := []struct {
+		name  string
+		send  func(c *testClient)
+		code  int
+		limit int64
+	}{
+		{"unmasked frame", func(c *testClient) { c.writeFrame(true, TextMessage, []byte("hi"), true) }, CloseProtocolError, 0},
+		{"reserved bits", func(c *testClient) { c.writeFrame(true, TextMessage|0x40, []byte("hi"), false) }, CloseProtocolError, 0},
+		{"unknown opcode", func(c *testClient) { c.writeFrame(true, 3, nil, false) }, CloseProtocolError, 0},
+		{"fragmented ping", func(c *testClient) { c.writeFrame(false, PingMessage, nil, false) }, CloseProtocolError, 0},
+		{"long ping", func(c *testClient) { c.writeFrame(true, PingMessage, make([]byte, 126), false) }, CloseProtocolError, 0},
+		{"one-byte close", func(c *testClient) { c.writeFrame(true, CloseMessage, []byte{3}, false) }, CloseProtocolError, 0},
+		{"continuation first", func(c *testClient) { c.writeFrame(true, 0, []byte("hi"), false) }, CloseProtocolError, 0},
+		{"interleaved message", func(c *testClient) {
+			c.writeFrame(false, TextMessage, []byte("a"), false)
+			c.writeFrame(true, TextMessage, []byte("b"), false)
+		}, CloseProtocolError, 0},
+		{"invalid UTF-8", func(c *testClient) { c.writeFrame(true, TextMessage, []byte{0xff, 0xfe}, false) }, CloseInvalidPayload, 0},
+		{"frame too big", func(c *testClient) { c.writeFrame(true, TextMessage, make([]byte, 9), false) }, CloseMessageTooBig, 8},
+		{"message too big", func(c *testClient) {
+			c.writeFrame(false, TextMessage, make([]byte, 5), false)
+			c.writeFrame(true, 0, make([]byte, 5), false)
+		}, CloseMessageTooBig, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var setup func(*Conn)
+			if tt.limit > 0 {
+				setup = func(conn *Conn) { conn.SetReadLimit(tt.limit) }
+			}
+			c := serve(t, Options{}, "", setup)
+			tt.send(c)
+			if closeErr := c.expectClose(tt.code); closeErr.Code != tt.code {
+				t.Fatalf("CloseError code = %d, want %d", closeErr.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	written := make(chan error, 1)
+	c := serve(t, Options{}, "", func(conn *Conn) {
+		if err := conn.WriteClose(CloseNormal, "done"); err != nil {
+			written <- err
+			return
+		}
+		written <- conn.WriteMessage(TextMessage, []byte("late"))
+	})
+	if err := <-written; !errors.Is(err, ErrClosed) {
+		t.Fatalf("WriteMessage after WriteClose = %v, want ErrClosed", err)
+	}
+	if opcode, payload := c.readFrame(); opcode != CloseMessage || string(payload) != string(closePayload(CloseNormal, "done")) {
+		t.Fatalf("received %d %q, want the close frame", opcode, payload)
+	}
+}
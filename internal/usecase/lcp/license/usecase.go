@@ -6,38 +6,60 @@ import (
 
 	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
 	lcplicense "github.com/Mehrbod2002/lcp/internal/lcp/license"
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
 	"github.com/Mehrbod2002/lcp/internal/pkg/id"
 )
 
 type LicenseUsecase interface {
 	Create(ctx context.Context, input *lcp.LicenseInput) (*lcp.License, error)
+	GetByID(ctx context.Context, id string) (*lcp.License, error)
 	GetByPublication(ctx context.Context, publicationID *string) ([]*lcp.License, error)
-	Revoke(ctx context.Context, id string) error
+	GetByPublications(ctx context.Context, publicationIDs []string) ([]*lcp.License, error)
+	Revoke(ctx context.Context, id, reason string) error
+	LicensedPublication(ctx context.Context, id string) (*lcp.Publication, error)
+	UpdateLicense(ctx context.Context, update *lcp.LicenseUpdate) error
 }
 
 type licenseUsecase struct {
-	repo    lcp.LicenseRepository
-	lcp     *lcplicense.Service
-	baseURL string
+	repo         lcp.LicenseRepository
+	outbox       lcp.Outbox
+	publications lcp.PublicationRepository
+	tx           lcp.Transactor
+	lcp          *lcplicense.Service
+	events       lcp.EventPublisher
+	baseURL      string
+	renewal      lcp.RenewalPolicy
 }
 
-func NewLicenseUsecase(repo lcp.LicenseRepository, lcp *lcplicense.Service, baseURL string) LicenseUsecase {
-	return &licenseUsecase{repo: repo, lcp: lcp, baseURL: baseURL}
+// NewLicenseUsecase returns the license use case. New licenses are issued
+// under the renewal policy, and issued and revoked licenses are queued in
+// outbox for the status service.
+func NewLicenseUsecase(repo lcp.LicenseRepository, outbox lcp.Outbox, publications lcp.PublicationRepository, tx lcp.Transactor, lcp *lcplicense.Service, events lcp.EventPublisher, baseURL string, renewal lcp.RenewalPolicy) LicenseUsecase {
+	return &licenseUsecase{repo: repo, outbox: outbox, publications: publications, tx: tx, lcp: lcp, events: events, baseURL: baseURL, renewal: renewal}
 }
 
 func (u *licenseUsecase) Create(ctx context.Context, input *lcp.LicenseInput) (*lcp.License, error) {
+	licenseID := id.New()
 	license := &lcp.License{
-		ID:             id.New(),
+		ID:             licenseID,
+		Provider:       input.Provider,
 		PublicationID:  input.PublicationID,
 		UserID:         input.UserID,
 		Passphrase:     input.Passphrase,
 		Hint:           input.Hint,
-		PublicationURL: u.baseURL + "/publications/" + input.PublicationID + "/content",
+		PublicationURL: u.baseURL + "/licenses/" + licenseID + "/publication",
 		RightPrint:     input.RightPrint,
 		RightCopy:      input.RightCopy,
 		StartDate:      input.StartDate,
 		EndDate:        input.EndDate,
 		CreatedAt:      time.Now(),
+		Status:         status.StatusReady,
+		Renewal:        u.renewal,
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Generate LCP license using lcpserver
@@ -46,19 +68,108 @@ func (u *licenseUsecase) Create(ctx context.Context, input *lcp.LicenseInput) (*
 		return nil, err
 	}
 
-	// Save license to database
-	err = u.repo.Save(ctx, license)
+	// Check the publication, save the license and queue its notification to
+	// the status service as one unit of work, so no license goes without a
+	// status
+	err = u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		pub, err := u.publications.FindByID(ctx, license.PublicationID)
+		if err != nil {
+			return err
+		}
+		if pub.CurrentStatus() != status.StatusActive {
+			return apperrors.Conflict("publication %s is inactive", pub.ID)
+		}
+		if err := u.repo.Save(ctx, license); err != nil {
+			return err
+		}
+		return u.outbox.Queue(ctx, lcp.NotificationLicenseIssued, license.ID)
+	})
 	if err != nil {
 		return nil, err
 	}
+	u.outbox.Wake()
 
+	u.events.Publish(ctx, lcp.Event{Type: lcp.EventLicenseCreated, License: license, OccurredAt: time.Now()})
 	return license, nil
 }
 
+func (u *licenseUsecase) GetByID(ctx context.Context, id string) (*lcp.License, error) {
+	return u.repo.FindByID(ctx, id)
+}
+
 func (u *licenseUsecase) GetByPublication(ctx context.Context, publicationID *string) ([]*lcp.License, error) {
 	return u.repo.FindByPublication(ctx, publicationID)
 }
 
-func (u *licenseUsecase) Revoke(ctx context.Context, id string) error {
-	return u.lcp.RevokeLicense(id)
+func (u *licenseUsecase) GetByPublications(ctx context.Context, publicationIDs []string) ([]*lcp.License, error) {
+	return u.repo.FindByPublications(ctx, publicationIDs)
+}
+
+// Revoke withdraws a license that still grants access. The revocation is
+// stored with its reason and queued for the status service, which records it
+// in the status document, and the publication can no longer be downloaded
+// through the license.
+func (u *licenseUsecase) Revoke(ctx context.Context, licenseID, reason string) error {
+	var license *lcp.License
+	err := u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if license, err = u.repo.FindByID(ctx, licenseID); err != nil {
+			return err
+		}
+		now := time.Now()
+		if !license.ActiveAt(now) {
+			return apperrors.Conflict("license %s is %s and cannot be revoked", licenseID, license.StatusAt(now))
+		}
+		if err := u.lcp.RevokeLicense(licenseID); err != nil {
+			return err
+		}
+		license.Status = status.StatusRevoked
+		license.StatusUpdatedAt = now
+		license.RevokedAt = &now
+		license.RevocationReason = reason
+		if err := u.repo.Save(ctx, license); err != nil {
+			return err
+		}
+		return u.outbox.Queue(ctx, lcp.NotificationLicenseRevoked, license.ID)
+	})
+	if err != nil {
+		return err
+	}
+	u.outbox.Wake()
+
+	u.events.Publish(ctx, lcp.Event{Type: lcp.EventLicenseRevoked, License: license, OccurredAt: time.Now()})
+	return nil
+}
+
+// LicensedPublication returns the publication of a license, provided the
+// license still grants access to it.
+func (u *licenseUsecase) LicensedPublication(ctx context.Context, id string) (*lcp.Publication, error) {
+	license, err := u.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !license.ActiveAt(now) {
+		return nil, apperrors.Forbidden("license %s is %s", id, license.StatusAt(now))
+	}
+	return u.publications.FindByID(ctx, license.PublicationID)
+}
+
+// UpdateLicense applies the dates changed by the status service. Updates
+// that are not newer than the license, such as one already applied, are
+// ignored.
+func (u *licenseUsecase) UpdateLicense(ctx context.Context, update *lcp.LicenseUpdate) error {
+	return u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		license, err := u.repo.FindByID(ctx, update.LicenseID)
+		if err != nil {
+			return err
+		}
+		if !update.UpdatedAt.After(license.UpdatedAt) {
+			return nil
+		}
+		license.StartDate = update.StartDate
+		license.EndDate = update.EndDate
+		license.UpdatedAt = update.UpdatedAt
+		return u.repo.Save(ctx, license)
+	})
 }
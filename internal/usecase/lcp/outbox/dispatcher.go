@@ -0,0 +1,277 @@
+// Package outbox delivers the notifications the license and status services
+// queue for each other. They are queued by the unit of work that produces
+// them and delivered once it commits, so no notifier runs within a
+// transaction and no notification is lost when the other service is down.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+	"github.com/Mehrbod2002/lcp/internal/pkg/id"
+)
+
+// Defaults applied to the zero fields of Options.
+const (
+	DefaultRetryInterval    = 5 * time.Second
+	DefaultMaxRetryInterval = 10 * time.Minute
+)
+
+// Options configures a Dispatcher.
+type Options struct {
+	// RetryInterval is the delay before the first retry of a failed
+	// notification, doubled on each further failure. It is also the time
+	// between deliveries when nothing wakes the dispatcher. Zero means
+	// DefaultRetryInterval.
+	RetryInterval time.Duration
+	// MaxRetryInterval bounds the delay between retries. Zero means
+	// DefaultMaxRetryInterval, and it is never below RetryInterval.
+	MaxRetryInterval time.Duration
+}
+
+// DeliveryRun reports one pass over the outbox. Retrying counts the
+// notifications left in the outbox after failing at least once, this pass
+// or an earlier one. Rejected counts the notifications the other service
+// refused for good, which were dropped; Rejection describes the last one.
+type DeliveryRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Delivered  int       `json:"delivered"`
+	Failed     int       `json:"failed"`
+	Retrying   int       `json:"retrying"`
+	Rejected   int       `json:"rejected"`
+	Rejection  string    `json:"rejection,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Dispatcher is the lcp.Outbox of a process. It delivers the queued
+// notifications when woken and on a schedule, in the order they were queued
+// for each license, and retries failed ones with exponential backoff.
+// Deliveries may repeat after a crash, which notifications tolerate.
+type Dispatcher struct {
+	notifications lcp.NotificationRepository
+	licenses      lcp.LicenseRepository
+	statuses      lcp.StatusNotifier
+	updates       lcp.LicenseUpdater
+	opts          Options
+
+	running sync.Mutex
+	mu      sync.Mutex
+	lastRun *DeliveryRun
+
+	wake chan struct{}
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewDispatcher returns a dispatcher delivering the notifications kept in
+// notifications. Issued and revoked licenses, read from licenses, go to
+// statuses, and the dates of updated ones go to updates.
+func NewDispatcher(notifications lcp.NotificationRepository, licenses lcp.LicenseRepository, statuses lcp.StatusNotifier, updates lcp.LicenseUpdater, opts Options) *Dispatcher {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultRetryInterval
+	}
+	if opts.MaxRetryInterval <= 0 {
+		opts.MaxRetryInterval = DefaultMaxRetryInterval
+	}
+	opts.MaxRetryInterval = max(opts.MaxRetryInterval, opts.RetryInterval)
+	return &Dispatcher{
+		notifications: notifications,
+		licenses:      licenses,
+		statuses:      statuses,
+		updates:       updates,
+		opts:          opts,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Queue saves a notification for the license, within the transaction
+// carried by ctx if any.
+func (d *Dispatcher) Queue(ctx context.Context, kind lcp.NotificationKind, licenseID string) error {
+	now := time.Now()
+	return d.notifications.Save(ctx, &lcp.Notification{
+		ID:            id.New(),
+		Kind:          kind,
+		LicenseID:     licenseID,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	})
+}
+
+// Wake asks the running dispatcher for a delivery. It never blocks.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start delivers notifications in the background until Stop is called.
+func (d *Dispatcher) Start() {
+	if d.stop != nil {
+		return
+	}
+	d.stop = make(chan struct{})
+	d.done.Add(1)
+	go d.loop()
+}
+
+// Stop ends the deliveries and waits for a running one to finish. Queued
+// notifications stay in the outbox for the next start.
+func (d *Dispatcher) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	d.done.Wait()
+	d.stop = nil
+}
+
+func (d *Dispatcher) loop() {
+	defer d.done.Done()
+	ticker := time.NewTicker(d.opts.RetryInterval)
+	defer ticker.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-d.stop
+		cancel()
+	}()
+	// Notifications left by a previous run go out first.
+	d.Deliver(ctx)
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-ctx.Done():
+			return
+		}
+		// Failures are reported by LastRun and retried on a later pass.
+		d.Deliver(ctx)
+	}
+}
+
+// Deliver sends the notifications that are due, and records the run. A
+// notification waiting for a retry holds back the later ones of its license,
+// so that a revocation never overtakes the issue of its license. Transport
+// failures and server errors are retried; a notification the other service
+// rejects as invalid, conflicting or about an unknown license would be
+// rejected again, so it is dropped. A delivery already in progress is waited
+// for rather than repeated.
+func (d *Dispatcher) Deliver(ctx context.Context) DeliveryRun {
+	d.running.Lock()
+	defer d.running.Unlock()
+
+	run := DeliveryRun{StartedAt: time.Now()}
+	pending, err := d.notifications.FindPending(ctx)
+	if err != nil {
+		run.Error = err.Error()
+	}
+	held := make(map[string]bool)
+	for _, notification := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		if held[notification.LicenseID] || notification.NextAttemptAt.After(run.StartedAt) {
+			held[notification.LicenseID] = true
+			if notification.Attempts > 0 {
+				run.Retrying++
+			}
+			continue
+		}
+		deliveryErr := d.deliver(ctx, notification)
+		if deliveryErr != nil && !rejected(deliveryErr) {
+			held[notification.LicenseID] = true
+			run.Failed++
+			run.Retrying++
+			if err := d.retryLater(ctx, notification, deliveryErr); err != nil {
+				run.Error = err.Error()
+			}
+			continue
+		}
+		if err := d.notifications.Delete(ctx, notification.ID); err != nil {
+			run.Error = err.Error()
+			continue
+		}
+		if deliveryErr != nil {
+			run.Rejected++
+			run.Rejection = fmt.Sprintf("%s of license %s: %v", notification.Kind, notification.LicenseID, deliveryErr)
+			continue
+		}
+		run.Delivered++
+	}
+	run.FinishedAt = time.Now()
+
+	d.mu.Lock()
+	d.lastRun = &run
+	d.mu.Unlock()
+	return run
+}
+
+// deliver sends the current state of the license. A license deleted since
+// the notification was queued has nothing left to tell.
+func (d *Dispatcher) deliver(ctx context.Context, notification *lcp.Notification) error {
+	license, err := d.licenses.FindByID(ctx, notification.LicenseID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch notification.Kind {
+	case lcp.NotificationLicenseIssued:
+		return d.statuses.LicenseIssued(ctx, license)
+	case lcp.NotificationLicenseRevoked:
+		return d.statuses.LicenseRevoked(ctx, license)
+	case lcp.NotificationLicenseUpdated:
+		return d.updates.UpdateLicense(ctx, &lcp.LicenseUpdate{
+			LicenseID: license.ID,
+			StartDate: license.StartDate,
+			EndDate:   license.EndDate,
+			UpdatedAt: license.UpdatedAt,
+		})
+	default:
+		return fmt.Errorf("outbox: unknown notification kind %q", notification.Kind)
+	}
+}
+
+// rejected reports whether err is a refusal that retrying cannot change.
+func rejected(err error) bool {
+	switch apperrors.KindOf(err) {
+	case apperrors.KindNotFound, apperrors.KindValidation, apperrors.KindConflict:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryLater records the failure of a delivery and schedules the next one.
+func (d *Dispatcher) retryLater(ctx context.Context, notification *lcp.Notification, cause error) error {
+	notification.Attempts++
+	notification.LastError = cause.Error()
+	notification.NextAttemptAt = time.Now().Add(d.backoff(notification.Attempts))
+	return d.notifications.Save(ctx, notification)
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.RetryInterval
+	for i := 1; i < attempts && delay < d.opts.MaxRetryInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, d.opts.MaxRetryInterval)
+}
+
+// LastRun returns the most recent delivery, if any ran.
+func (d *Dispatcher) LastRun() (DeliveryRun, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.lastRun == nil {
+		return DeliveryRun{}, false
+	}
+	return *d.lastRun, true
+}
@@ -0,0 +1,170 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/adapter/notify"
+	"github.com/Mehrbod2002/lcp/internal/adapter/repository/lcp"
+	domain "github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/outbox"
+)
+
+// recorder is a StatusNotifier and LicenseUpdater that fails while down.
+type recorder struct {
+	down      bool
+	delivered []string
+}
+
+func (r *recorder) record(kind, licenseID string) error {
+	if r.down {
+		return errors.New("unreachable")
+	}
+	r.delivered = append(r.delivered, kind+" "+licenseID)
+	return nil
+}
+
+func (r *recorder) LicenseIssued(_ context.Context, license *domain.License) error {
+	return r.record("issued", license.ID)
+}
+
+func (r *recorder) LicenseRevoked(_ context.Context, license *domain.License) error {
+	return r.record("revoked", license.ID)
+}
+
+func (r *recorder) UpdateLicense(_ context.Context, update *domain.LicenseUpdate) error {
+	return r.record("updated", update.LicenseID)
+}
+
+func TestDeliverRetriesInOrder(t *testing.T) {
+	ctx := context.Background()
+	store := lcp.NewStore()
+	for _, id := range []string{"l1", "l2"} {
+		if err := store.Licenses.Save(ctx, &domain.License{ID: id, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Save license %s: %v", id, err)
+		}
+	}
+	peer := &recorder{down: true}
+	dispatcher := outbox.NewDispatcher(store.Notifications, store.Licenses, peer, peer, outbox.Options{RetryInterval: time.Hour})
+
+	queue := func(kind domain.NotificationKind, licenseID string) {
+		t.Helper()
+		if err := dispatcher.Queue(ctx, kind, licenseID); err != nil {
+			t.Fatalf("Queue: %v", err)
+		}
+	}
+	queue(domain.NotificationLicenseIssued, "l1")
+	queue(domain.NotificationLicenseIssued, "l2")
+	// The license is gone by the time its notification is delivered.
+	queue(domain.NotificationLicenseIssued, "deleted")
+
+	run := dispatcher.Deliver(ctx)
+	if run.Delivered != 1 || run.Failed != 2 || run.Retrying != 2 {
+		t.Fatalf("first run delivered %d, failed %d and left %d to retry, want 1, 2 and 2", run.Delivered, run.Failed, run.Retrying)
+	}
+	pending, err := store.Notifications.FindPending(ctx)
+	if err != nil {
+		t.Fatalf("FindPending: %v", err)
+	}
+	for _, notification := range pending {
+		if notification.Attempts != 1 || notification.LastError != "unreachable" || time.Until(notification.NextAttemptAt) < 59*time.Minute {
+			t.Fatalf("failed notification = %+v, want one attempt and a retry in an hour", notification)
+		}
+	}
+
+	// Held back by the failed issue of l1, even once the peer is back.
+	queue(domain.NotificationLicenseRevoked, "l1")
+	peer.down = false
+	if run := dispatcher.Deliver(ctx); run.Delivered != 0 || run.Failed != 0 || run.Retrying != 2 {
+		t.Fatalf("run before the retry delivered %d, failed %d and left %d to retry, want 0, 0 and 2", run.Delivered, run.Failed, run.Retrying)
+	}
+
+	pending, err = store.Notifications.FindPending(ctx)
+	if err != nil {
+		t.Fatalf("FindPending: %v", err)
+	}
+	for _, notification := range pending {
+		notification.NextAttemptAt = time.Now()
+		if err := store.Notifications.Save(ctx, notification); err != nil {
+			t.Fatalf("Save notification: %v", err)
+		}
+	}
+	if run := dispatcher.Deliver(ctx); run.Delivered != 3 || run.Failed != 0 || run.Retrying != 0 {
+		t.Fatalf("retry delivered %d, failed %d and left %d to retry, want 3, 0 and 0", run.Delivered, run.Failed, run.Retrying)
+	}
+	if want := []string{"issued l1", "issued l2", "revoked l1"}; !slices.Equal(peer.delivered, want) {
+		t.Fatalf("delivered %v, want %v", peer.delivered, want)
+	}
+	if pending, _ := store.Notifications.FindPending(ctx); len(pending) != 0 {
+		t.Fatalf("%d notifications left in the outbox", len(pending))
+	}
+}
+
+func TestDeliverDropsRejectedNotifications(t *testing.T) {
+	ctx := context.Background()
+	store := lcp.NewStore()
+	// The status server answers with the status named by the license ID.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var license domain.License
+		if err := json.NewDecoder(r.Body).Decode(&license); err != nil {
+			t.Errorf("decode notification: %v", err)
+		}
+		code, _ := strconv.Atoi(license.ID[:3])
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `{"error": "license %s answered %d"}`, license.ID, code)
+	}))
+	defer server.Close()
+	statuses := notify.NewHTTPStatusNotifier(server.URL, notify.Credentials{Username: "lcp", Password: "secret"}, nil)
+	dispatcher := outbox.NewDispatcher(store.Notifications, store.Licenses, statuses, nil, outbox.Options{RetryInterval: time.Hour})
+
+	licenses := []string{"200-ok", "400-invalid", "404-unknown", "409-conflict", "500-error", "503-unavailable"}
+	for _, id := range licenses {
+		if err := store.Licenses.Save(ctx, &domain.License{ID: id, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Save license %s: %v", id, err)
+		}
+		if err := dispatcher.Queue(ctx, domain.NotificationLicenseIssued, id); err != nil {
+			t.Fatalf("Queue: %v", err)
+		}
+	}
+	// Rejected notifications do not hold back the later ones of their license.
+	if err := dispatcher.Queue(ctx, domain.NotificationLicenseRevoked, "409-conflict"); err != nil {
+		t.Fatalf("Queue: %v", err)
+	}
+
+	run := dispatcher.Deliver(ctx)
+	if run.Delivered != 1 || run.Rejected != 4 || run.Failed != 2 || run.Retrying != 2 || run.Error != "" {
+		t.Fatalf("run = %+v, want 1 delivered, 4 rejected and 2 left to retry", run)
+	}
+	if want := "license_revoked of license 409-conflict: license 409-conflict answered 409"; run.Rejection != want {
+		t.Fatalf("Rejection = %q, want %q", run.Rejection, want)
+	}
+	pending, err := store.Notifications.FindPending(ctx)
+	if err != nil {
+		t.Fatalf("FindPending: %v", err)
+	}
+	var retried []string
+	for _, notification := range pending {
+		retried = append(retried, notification.LicenseID)
+	}
+	if want := []string{"500-error", "503-unavailable"}; !slices.Equal(retried, want) {
+		t.Fatalf("outbox holds %v, want %v", retried, want)
+	}
+
+	// Transport failures are retried too.
+	server.Close()
+	if err := dispatcher.Queue(ctx, domain.NotificationLicenseIssued, "200-ok"); err != nil {
+		t.Fatalf("Queue: %v", err)
+	}
+	if run := dispatcher.Deliver(ctx); run.Failed != 1 || run.Retrying != 3 || run.Rejected != 0 {
+		t.Fatalf("run with the server down = %+v, want 1 failed and 3 left to retry", run)
+	}
+}
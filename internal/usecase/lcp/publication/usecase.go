@@ -2,62 +2,96 @@ package publication
 
 import (
 	"context"
+	"errors"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
 	"github.com/Mehrbod2002/lcp/internal/lcp/encrypt"
+	"github.com/Mehrbod2002/lcp/internal/lcp/status"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
 	"github.com/Mehrbod2002/lcp/internal/pkg/id"
 )
 
 type PublicationUsecase interface {
 	UploadAndEncrypt(ctx context.Context, title string, file io.Reader) (*lcp.Publication, error)
 	GetAll(ctx context.Context) ([]*lcp.Publication, error)
+	Search(ctx context.Context, query *lcp.PublicationQuery) ([]*lcp.Publication, error)
 	GetByID(ctx context.Context, id string) (*lcp.Publication, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*lcp.Publication, error)
+	Update(ctx context.Context, id string, update *lcp.PublicationUpdate) (*lcp.Publication, error)
+	Activate(ctx context.Context, id string) (*lcp.Publication, error)
+	Deactivate(ctx context.Context, id string) (*lcp.Publication, error)
+	Delete(ctx context.Context, id string) error
 }
 
 type publicationUsecase struct {
-	repo lcp.PublicationRepository
-	enc  encrypt.Encrypter
+	repo          lcp.PublicationRepository
+	licenses      lcp.LicenseRepository
+	licenseEvents lcp.LicenseEventRepository
+	tx            lcp.Transactor
+	enc           encrypt.Encrypter
+	events        lcp.EventPublisher
 }
 
-func NewPublicationUsecase(repo lcp.PublicationRepository, enc encrypt.Encrypter) PublicationUsecase {
-	return &publicationUsecase{repo, enc}
+func NewPublicationUsecase(repo lcp.PublicationRepository, licenses lcp.LicenseRepository, licenseEvents lcp.LicenseEventRepository, tx lcp.Transactor, enc encrypt.Encrypter, events lcp.EventPublisher) PublicationUsecase {
+	return &publicationUsecase{repo: repo, licenses: licenses, licenseEvents: licenseEvents, tx: tx, enc: enc, events: events}
 }
 
 func (u *publicationUsecase) UploadAndEncrypt(ctx context.Context, title string, file io.Reader) (*lcp.Publication, error) {
-	// Save file temporarily
-	tempPath := "/tmp/" + title + ".tmp"
-	out, err := os.Create(tempPath)
+	if title == "" {
+		return nil, apperrors.Validation("publication title is required")
+	}
+
+	// Spool the upload to a temporary file of its own, removed once it is
+	// encrypted
+	pubID := id.New()
+	out, err := os.CreateTemp("", pubID+"-*.tmp")
 	if err != nil {
 		return nil, err
 	}
+	tempPath := out.Name()
+	defer os.Remove(tempPath)
 	defer out.Close()
 	_, err = io.Copy(out, file)
 	if err != nil {
 		return nil, err
 	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+
+	pub := &lcp.Publication{
+		ID:        pubID,
+		Title:     title,
+		FilePath:  tempPath,
+		Status:    status.StatusActive,
+		CreatedAt: time.Now(),
+	}
+
+	// Stop before the expensive steps when the caller gave up
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Encrypt using lcpencrypt
 	encryptedPath, err := u.enc.Encrypt(tempPath, title)
 	if err != nil {
+		u.publish(ctx, lcp.EventPublicationFailed, pub, err)
 		return nil, err
 	}
+	pub.EncryptedPath = encryptedPath
 
 	// Store publication metadata
-	pub := &lcp.Publication{
-		ID:            id.New(),
-		Title:         title,
-		FilePath:      tempPath,
-		EncryptedPath: encryptedPath,
-		CreatedAt:     time.Now(),
-	}
 	err = u.repo.Save(ctx, pub)
 	if err != nil {
+		u.publish(ctx, lcp.EventPublicationFailed, pub, err)
 		return nil, err
 	}
 
+	u.publish(ctx, lcp.EventPublicationEncrypted, pub, nil)
 	return pub, nil
 }
 
@@ -65,6 +99,134 @@ func (u *publicationUsecase) GetAll(ctx context.Context) ([]*lcp.Publication, er
 	return u.repo.FindAll(ctx)
 }
 
+func (u *publicationUsecase) Search(ctx context.Context, query *lcp.PublicationQuery) ([]*lcp.Publication, error) {
+	if query.Status == "" && query.Title == "" {
+		return u.repo.List(ctx, query.Page)
+	}
+	all, err := u.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	title := strings.ToLower(query.Title)
+	matches := make([]*lcp.Publication, 0, len(all))
+	for _, pub := range all {
+		if query.Status != "" && pub.CurrentStatus() != query.Status {
+			continue
+		}
+		if title != "" && !strings.Contains(strings.ToLower(pub.Title), title) {
+			continue
+		}
+		matches = append(matches, pub)
+	}
+	start := min(max(query.Page.Offset, 0), len(matches))
+	end := len(matches)
+	if query.Page.Limit > 0 {
+		end = min(start+query.Page.Limit, end)
+	}
+	return matches[start:end], nil
+}
+
 func (u *publicationUsecase) GetByID(ctx context.Context, id string) (*lcp.Publication, error) {
 	return u.repo.FindByID(ctx, id)
 }
+
+func (u *publicationUsecase) GetByIDs(ctx context.Context, ids []string) ([]*lcp.Publication, error) {
+	return u.repo.FindByIDs(ctx, ids)
+}
+
+func (u *publicationUsecase) Update(ctx context.Context, id string, update *lcp.PublicationUpdate) (*lcp.Publication, error) {
+	if update.Title != nil && *update.Title == "" {
+		return nil, apperrors.Validation("publication title is required")
+	}
+	return u.modify(ctx, id, func(pub *lcp.Publication) {
+		if update.Title != nil {
+			pub.Title = *update.Title
+		}
+	})
+}
+
+func (u *publicationUsecase) Activate(ctx context.Context, id string) (*lcp.Publication, error) {
+	return u.modify(ctx, id, func(pub *lcp.Publication) { pub.Status = status.StatusActive })
+}
+
+func (u *publicationUsecase) Deactivate(ctx context.Context, id string) (*lcp.Publication, error) {
+	return u.modify(ctx, id, func(pub *lcp.Publication) { pub.Status = status.StatusInactive })
+}
+
+// Delete removes a publication and its stored files. Publications that
+// still have active licenses are kept, since their readers could no longer
+// download the content; the other licenses, and their status events, are
+// removed in the same transaction so that none outlives its publication.
+func (u *publicationUsecase) Delete(ctx context.Context, id string) error {
+	var pub *lcp.Publication
+	err := u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if pub, err = u.repo.FindByID(ctx, id); err != nil {
+			return err
+		}
+		licenses, err := u.licenses.FindByPublication(ctx, &id)
+		if err != nil {
+			return err
+		}
+		active := 0
+		now := time.Now()
+		for _, license := range licenses {
+			if license.ActiveAt(now) {
+				active++
+			}
+		}
+		if active > 0 {
+			return apperrors.Conflict("publication %s cannot be deleted while %d of its licenses are active", id, active)
+		}
+		for _, license := range licenses {
+			if err := u.licenseEvents.DeleteByLicense(ctx, license.ID); err != nil {
+				return err
+			}
+			if err := u.licenses.Delete(ctx, license.ID); err != nil {
+				return err
+			}
+		}
+		return u.repo.Delete(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Remove the stored files once the record is gone
+	return errors.Join(removeFile(pub.EncryptedPath), removeFile(pub.FilePath))
+}
+
+// modify applies change to a publication and saves it as one unit of work.
+func (u *publicationUsecase) modify(ctx context.Context, id string, change func(pub *lcp.Publication)) (*lcp.Publication, error) {
+	var pub *lcp.Publication
+	err := u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if pub, err = u.repo.FindByID(ctx, id); err != nil {
+			return err
+		}
+		change(pub)
+		return u.repo.Save(ctx, pub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+func removeFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (u *publicationUsecase) publish(ctx context.Context, eventType lcp.EventType, pub *lcp.Publication, cause error) {
+	event := lcp.Event{Type: eventType, Publication: pub, OccurredAt: time.Now()}
+	if cause != nil {
+		event.Error = cause.Error()
+	}
+	u.events.Publish(ctx, event)
+}
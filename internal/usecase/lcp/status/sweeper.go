@@ -0,0 +1,137 @@
+package status
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBatchSize is the number of licenses a sweep expires per batch when
+// SweeperOptions leaves it unset.
+const DefaultBatchSize = 100
+
+// SweeperOptions configures a Sweeper.
+type SweeperOptions struct {
+	// Interval is the time between sweeps. Zero disables the schedule;
+	// Sweep can still be called directly.
+	Interval time.Duration
+	// BatchSize bounds the licenses expired per batch. Zero means
+	// DefaultBatchSize.
+	BatchSize int
+}
+
+// SweepRun reports one sweep.
+type SweepRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Expired    int       `json:"expired"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Sweeper moves licenses past their end date to expired on a schedule, so
+// that stored statuses, events and subscribers catch up with status
+// documents. Sweeps on one replica never overlap, and ExpireBatch checks
+// each license again before expiring it.
+type Sweeper struct {
+	statuses StatusUsecase
+	opts     SweeperOptions
+
+	running sync.Mutex
+	mu      sync.Mutex
+	lastRun *SweepRun
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+func NewSweeper(statuses StatusUsecase, opts SweeperOptions) *Sweeper {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	return &Sweeper{statuses: statuses, opts: opts}
+}
+
+// Start runs a sweep every interval until Stop is called. It does nothing
+// when no interval is configured.
+func (s *Sweeper) Start() {
+	if s.opts.Interval <= 0 || s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done.Add(1)
+	go s.loop()
+}
+
+// Stop ends the schedule and waits for a running sweep to finish.
+func (s *Sweeper) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.done.Wait()
+	s.stop = nil
+}
+
+func (s *Sweeper) loop() {
+	defer s.done.Done()
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			// A failed sweep is reported by LastRun and retried on the next tick.
+			s.Sweep(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sweep expires licenses batch by batch until a batch comes back short, and
+// records the run. A full batch that expired nothing stops the sweep too,
+// since the next one would find the same licenses. A sweep already in
+// progress is waited for rather than repeated.
+func (s *Sweeper) Sweep(ctx context.Context) SweepRun {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	run := SweepRun{StartedAt: time.Now()}
+	for {
+		found, expired, err := s.statuses.ExpireBatch(ctx, run.StartedAt, s.opts.BatchSize)
+		run.Expired += expired
+		if err != nil {
+			run.Error = err.Error()
+			break
+		}
+		if found < s.opts.BatchSize || expired == 0 {
+			break
+		}
+	}
+	run.FinishedAt = time.Now()
+
+	s.mu.Lock()
+	s.lastRun = &run
+	s.mu.Unlock()
+	return run
+}
+
+// LastRun returns the most recent sweep, if any ran.
+func (s *Sweeper) LastRun() (SweepRun, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastRun == nil {
+		return SweepRun{}, false
+	}
+	return *s.lastRun, true
+}
+
+// Interval returns the time between scheduled sweeps.
+func (s *Sweeper) Interval() time.Duration {
+	return s.opts.Interval
+}
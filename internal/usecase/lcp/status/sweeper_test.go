@@ -0,0 +1,224 @@
+package status_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	domain "github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	lcpstatus "github.com/Mehrbod2002/lcp/internal/lcp/status"
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/status"
+)
+
+// saveEnded stores n ready licenses whose end dates have passed, the first
+// one ending first.
+func (f *fixture) saveEnded(prefix string, n int) {
+	f.t.Helper()
+	for i := 0; i < n; i++ {
+		end := time.Now().Add(-time.Hour + time.Duration(i)*time.Minute)
+		f.save(&domain.License{ID: fmt.Sprintf("%s%d", prefix, i), EndDate: &end})
+	}
+}
+
+func TestExpireBatch(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	f.saveEnded("ended", 3)
+	future := time.Now().Add(time.Hour)
+	f.save(&domain.License{ID: "future", EndDate: &future})
+	f.save(&domain.License{ID: "open", Status: lcpstatus.StatusActive})
+	past := time.Now().Add(-2 * time.Hour)
+	f.save(&domain.License{ID: "revoked", Status: lcpstatus.StatusRevoked, EndDate: &past})
+
+	// Batches take the licenses that ended first.
+	found, expired, err := f.statuses.ExpireBatch(f.ctx, time.Now(), 2)
+	if err != nil || found != 2 || expired != 2 {
+		t.Fatalf("first batch = %d, %d, %v, want 2, 2", found, expired, err)
+	}
+	for _, id := range []string{"ended0", "ended1"} {
+		license, events := f.stored(id)
+		if license.Status != lcpstatus.StatusExpired || license.StatusUpdatedAt.IsZero() {
+			t.Fatalf("license %s is %s, want expired", id, license.Status)
+		}
+		if len(events) != 1 || events[0].Type != domain.LicenseEventExpire {
+			t.Fatalf("events of %s = %+v, want the expiry", id, events)
+		}
+	}
+	if license, _ := f.stored("ended2"); license.Status != "" {
+		t.Fatalf("license ended2 is %s, want it left for the next batch", license.Status)
+	}
+
+	found, expired, err = f.statuses.ExpireBatch(f.ctx, time.Now(), 2)
+	if err != nil || found != 1 || expired != 1 {
+		t.Fatalf("second batch = %d, %d, %v, want 1, 1", found, expired, err)
+	}
+	found, expired, err = f.statuses.ExpireBatch(f.ctx, time.Now(), 2)
+	if err != nil || found != 0 || expired != 0 {
+		t.Fatalf("third batch = %d, %d, %v, want 0, 0", found, expired, err)
+	}
+
+	for _, id := range []string{"future", "open", "revoked"} {
+		if license, events := f.stored(id); license.Status == lcpstatus.StatusExpired || len(events) != 0 {
+			t.Fatalf("license %s was expired", id)
+		}
+	}
+	if len(f.recorder.published) != 3 {
+		t.Fatalf("published %d status changes, want 3", len(f.recorder.published))
+	}
+	// Expiring a license changes no date.
+	if len(f.recorder.queued) != 0 {
+		t.Fatalf("queued %v, want nothing", f.recorder.queued)
+	}
+}
+
+// changingLicenses changes licenses between the lookup of ExpireBatch and
+// the transactions that expire them, as a concurrent request would.
+type changingLicenses struct {
+	domain.LicenseRepository
+	change func(ctx context.Context)
+}
+
+func (r *changingLicenses) FindExpired(ctx context.Context, now time.Time, limit int) ([]*domain.License, error) {
+	found, err := r.LicenseRepository.FindExpired(ctx, now, limit)
+	if err == nil && r.change != nil {
+		r.change(ctx)
+		r.change = nil
+	}
+	return found, err
+}
+
+func TestExpireBatchChecksLicensesAgain(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	f.saveEnded("l", 3)
+	licenses := &changingLicenses{LicenseRepository: f.store.Licenses}
+	statuses := status.NewStatusUsecase(licenses, f.store.LicenseEvents, f.store, f.recorder, f.recorder, status.Options{})
+
+	licenses.change = func(ctx context.Context) {
+		// l0 is renewed and l1 expired by another sweep.
+		renewed, _ := f.stored("l0")
+		end := time.Now().Add(time.Hour)
+		renewed.EndDate = &end
+		expired, _ := f.stored("l1")
+		expired.Status = lcpstatus.StatusExpired
+		for _, license := range []*domain.License{renewed, expired} {
+			if err := f.store.Licenses.Save(ctx, license); err != nil {
+				t.Fatalf("Save %s: %v", license.ID, err)
+			}
+		}
+	}
+	found, expired, err := statuses.ExpireBatch(f.ctx, time.Now(), 10)
+	if err != nil || found != 3 || expired != 1 {
+		t.Fatalf("ExpireBatch = %d, %d, %v, want 3 found and 1 expired", found, expired, err)
+	}
+	for id, want := range map[string]lcpstatus.Status{"l0": "", "l1": lcpstatus.StatusExpired, "l2": lcpstatus.StatusExpired} {
+		license, events := f.stored(id)
+		if license.Status != want {
+			t.Fatalf("license %s is %q, want %q", id, license.Status, want)
+		}
+		if wantEvents := map[string]int{"l2": 1}[id]; len(events) != wantEvents {
+			t.Fatalf("license %s has %d events, want %d", id, len(events), wantEvents)
+		}
+	}
+}
+
+// countingStatuses counts the batches of a sweep and can replace their
+// results.
+type countingStatuses struct {
+	status.StatusUsecase
+	batches int
+	batch   func(size int) (found, expired int, err error)
+}
+
+func (s *countingStatuses) ExpireBatch(ctx context.Context, now time.Time, size int) (int, int, error) {
+	s.batches++
+	if s.batch != nil {
+		return s.batch(size)
+	}
+	return s.StatusUsecase.ExpireBatch(ctx, now, size)
+}
+
+func TestSweep(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	f.saveEnded("l", 5)
+	statuses := &countingStatuses{StatusUsecase: f.statuses}
+	sweeper := status.NewSweeper(statuses, status.SweeperOptions{BatchSize: 2})
+
+	if _, ok := sweeper.LastRun(); ok {
+		t.Fatal("LastRun reported a sweep before any ran")
+	}
+	// Two full batches, then a short one ends the sweep.
+	run := sweeper.Sweep(f.ctx)
+	if run.Expired != 5 || run.Error != "" || statuses.batches != 3 {
+		t.Fatalf("sweep expired %d in %d batches (%q), want 5 in 3", run.Expired, statuses.batches, run.Error)
+	}
+	if last, ok := sweeper.LastRun(); !ok || last != run {
+		t.Fatalf("LastRun = %+v, want %+v", last, run)
+	}
+
+	// Nothing is left to expire.
+	statuses.batches = 0
+	if run := sweeper.Sweep(f.ctx); run.Expired != 0 || statuses.batches != 1 {
+		t.Fatalf("second sweep expired %d in %d batches, want 0 in 1", run.Expired, statuses.batches)
+	}
+}
+
+func TestSweepStops(t *testing.T) {
+	tests := []struct {
+		name  string
+		batch func(size int) (int, int, error)
+		run   status.SweepRun
+	}{
+		{
+			// The next batch would find the same licenses.
+			name:  "full batch expiring nothing",
+			batch: func(size int) (int, int, error) { return size, 0, nil },
+		},
+		{
+			name:  "error",
+			batch: func(size int) (int, int, error) { return size, 1, errors.New("store unavailable") },
+			run:   status.SweepRun{Expired: 1, Error: "store unavailable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := &countingStatuses{batch: tt.batch}
+			run := status.NewSweeper(statuses, status.SweeperOptions{}).Sweep(context.Background())
+			if statuses.batches != 1 || run.Expired != tt.run.Expired || run.Error != tt.run.Error {
+				t.Fatalf("sweep = %+v after %d batches, want %+v after 1", run, statuses.batches, tt.run)
+			}
+		})
+	}
+}
+
+func TestSweepLeavesLicensesEndingDuringIt(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	f.saveEnded("ended", 1)
+	end := time.Now().Add(50 * time.Millisecond)
+	f.save(&domain.License{ID: "ending", EndDate: &end})
+
+	// Licenses are expired as of the start of the sweep, so one ending while
+	// it runs is left for the next.
+	sweeper := status.NewSweeper(&slowStatuses{StatusUsecase: f.statuses, delay: time.Until(end) + 10*time.Millisecond}, status.SweeperOptions{BatchSize: 1})
+	run := sweeper.Sweep(f.ctx)
+	if run.Expired != 1 || run.Error != "" {
+		t.Fatalf("sweep = %+v, want the license that ended before it", run)
+	}
+	if license, _ := f.stored("ending"); license.Status != "" {
+		t.Fatalf("license ending during the sweep is %s, want it left", license.Status)
+	}
+	if run := sweeper.Sweep(f.ctx); run.Expired != 1 {
+		t.Fatalf("next sweep = %+v, want the license that ended during the first", run)
+	}
+}
+
+// slowStatuses delays each batch.
+type slowStatuses struct {
+	status.StatusUsecase
+	delay time.Duration
+}
+
+func (s *slowStatuses) ExpireBatch(ctx context.Context, now time.Time, size int) (int, int, error) {
+	time.Sleep(s.delay)
+	return s.StatusUsecase.ExpireBatch(ctx, now, size)
+}
@@ -0,0 +1,294 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	lcpstatus "github.com/Mehrbod2002/lcp/internal/lcp/status"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+	"github.com/Mehrbod2002/lcp/internal/pkg/id"
+)
+
+// StatusUsecase serves the License Status Documents of licenses and the
+// interactions of reading systems with them.
+type StatusUsecase interface {
+	Get(ctx context.Context, licenseID string) (*lcp.LicenseStatus, error)
+	Register(ctx context.Context, licenseID string, device lcp.Device) (*lcp.LicenseStatus, error)
+	Return(ctx context.Context, licenseID string, device lcp.Device) (*lcp.LicenseStatus, error)
+	Renew(ctx context.Context, licenseID string, device lcp.Device, end *time.Time) (*lcp.LicenseStatus, error)
+	ExpireBatch(ctx context.Context, now time.Time, size int) (found, expired int, err error)
+	LicenseIssued(ctx context.Context, license *lcp.License) error
+	LicenseRevoked(ctx context.Context, license *lcp.License) error
+}
+
+// Options configures the policy applied to device interactions.
+type Options struct {
+	// MaxDevices bounds the devices a license can be registered on. Zero
+	// allows any number.
+	MaxDevices int
+}
+
+type statusUsecase struct {
+	licenses  lcp.LicenseRepository
+	events    lcp.LicenseEventRepository
+	tx        lcp.Transactor
+	publisher lcp.EventPublisher
+	outbox    lcp.Outbox
+	opts      Options
+}
+
+// NewStatusUsecase returns the status use case. The dates changed by
+// renewals and returns are queued in outbox for the license service.
+func NewStatusUsecase(licenses lcp.LicenseRepository, events lcp.LicenseEventRepository, tx lcp.Transactor, publisher lcp.EventPublisher, outbox lcp.Outbox, opts Options) StatusUsecase {
+	return &statusUsecase{licenses: licenses, events: events, tx: tx, publisher: publisher, outbox: outbox, opts: opts}
+}
+
+// Get returns the status of a license along with its events. Licenses past
+// their end date are reported as expired.
+func (u *statusUsecase) Get(ctx context.Context, licenseID string) (*lcp.LicenseStatus, error) {
+	license, err := u.licenses.FindByID(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := u.events.FindByLicense(ctx, licenseID)
+	if err != nil {
+		return nil, err
+	}
+	return &lcp.LicenseStatus{License: license, Status: license.StatusAt(time.Now()), Events: events}, nil
+}
+
+// Register records a device using the license, which activates a ready
+// license. Registering a device again changes nothing.
+func (u *statusUsecase) Register(ctx context.Context, licenseID string, device lcp.Device) (*lcp.LicenseStatus, error) {
+	if err := validateDevice(device); err != nil {
+		return nil, err
+	}
+	return u.update(ctx, licenseID, func(license *lcp.License, events []*lcp.LicenseEvent, now time.Time) (*lcp.LicenseEvent, error) {
+		if err := requireUsable(license, now); err != nil {
+			return nil, err
+		}
+		devices := registeredDevices(events)
+		if devices[device.ID] {
+			return nil, nil
+		}
+		if u.opts.MaxDevices > 0 && len(devices) >= u.opts.MaxDevices {
+			return nil, apperrors.Conflict("license %s is already registered on the maximum of %d devices", license.ID, u.opts.MaxDevices)
+		}
+		license.Status = lcpstatus.StatusActive
+		return &lcp.LicenseEvent{Type: lcp.LicenseEventRegister, DeviceID: device.ID, DeviceName: device.Name}, nil
+	})
+}
+
+// Return ends an active loan early: the license ends now and is returned,
+// so fresh copies of it carry the shortened rights.
+func (u *statusUsecase) Return(ctx context.Context, licenseID string, device lcp.Device) (*lcp.LicenseStatus, error) {
+	if err := validateDevice(device); err != nil {
+		return nil, err
+	}
+	return u.update(ctx, licenseID, func(license *lcp.License, events []*lcp.LicenseEvent, now time.Time) (*lcp.LicenseEvent, error) {
+		if current := license.StatusAt(now); current != lcpstatus.StatusActive {
+			return nil, apperrors.Conflict("license %s is %s, only active licenses can be returned", license.ID, current)
+		}
+		end := now
+		license.EndDate = &end
+		license.UpdatedAt = now
+		license.Status = lcpstatus.StatusReturned
+		return &lcp.LicenseEvent{Type: lcp.LicenseEventReturn, DeviceID: device.ID, DeviceName: device.Name}, nil
+	})
+}
+
+// Renew extends an active loan to end, or by the default extension of its
+// renewal policy when end is nil, within the limits of that policy.
+func (u *statusUsecase) Renew(ctx context.Context, licenseID string, device lcp.Device, end *time.Time) (*lcp.LicenseStatus, error) {
+	if err := validateDevice(device); err != nil {
+		return nil, err
+	}
+	return u.update(ctx, licenseID, func(license *lcp.License, events []*lcp.LicenseEvent, now time.Time) (*lcp.LicenseEvent, error) {
+		if current := license.StatusAt(now); current != lcpstatus.StatusActive {
+			return nil, apperrors.Conflict("license %s is %s, only active licenses can be renewed", license.ID, current)
+		}
+		if license.EndDate == nil {
+			return nil, apperrors.Conflict("license %s has no end date to renew", license.ID)
+		}
+		policy := license.Renewal
+		if policy.MaxRenewals > 0 && countEvents(events, lcp.LicenseEventRenew) >= policy.MaxRenewals {
+			return nil, apperrors.Conflict("license %s has already been renewed the maximum of %d times", license.ID, policy.MaxRenewals)
+		}
+		newEnd := end
+		if newEnd == nil {
+			if policy.DefaultExtension <= 0 {
+				return nil, apperrors.Validation("an end date is required to renew license %s", license.ID)
+			}
+			extended := license.EndDate.Add(policy.DefaultExtension)
+			newEnd = &extended
+		}
+		if !newEnd.After(*license.EndDate) {
+			return nil, apperrors.Validation("the new end date must be after the current end date %s", license.EndDate.Format(time.RFC3339))
+		}
+		if potential := license.PotentialEnd(); potential != nil && newEnd.After(*potential) {
+			return nil, apperrors.Conflict("license %s cannot be renewed past %s", license.ID, potential.Format(time.RFC3339))
+		}
+		license.EndDate = newEnd
+		license.UpdatedAt = now
+		return &lcp.LicenseEvent{Type: lcp.LicenseEventRenew, DeviceID: device.ID, DeviceName: device.Name}, nil
+	})
+}
+
+// ExpireBatch looks up to size licenses whose end date has passed at now and
+// moves them to expired, each in its own transaction. It returns how many it
+// found and how many it expired. Every license is checked again within its
+// transaction, which the serializable Transactor makes safe against a
+// concurrent sweep or renewal: one that changed first is left alone.
+func (u *statusUsecase) ExpireBatch(ctx context.Context, now time.Time, size int) (found, expired int, err error) {
+	candidates, err := u.licenses.FindExpired(ctx, now, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return len(candidates), expired, err
+		}
+		_, err := u.update(ctx, candidate.ID, func(license *lcp.License, _ []*lcp.LicenseEvent, _ time.Time) (*lcp.LicenseEvent, error) {
+			if license.CurrentStatus() == lcpstatus.StatusExpired || license.StatusAt(now) != lcpstatus.StatusExpired {
+				return nil, nil
+			}
+			license.Status = lcpstatus.StatusExpired
+			expired++
+			return &lcp.LicenseEvent{Type: lcp.LicenseEventExpire}, nil
+		})
+		if err != nil {
+			return len(candidates), expired, err
+		}
+	}
+	return len(candidates), expired, nil
+}
+
+// LicenseIssued starts tracking a license issued by the license service. A
+// license already known is left as it is.
+func (u *statusUsecase) LicenseIssued(ctx context.Context, license *lcp.License) error {
+	if license.ID == "" {
+		return apperrors.Validation("license id is required")
+	}
+	return u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := u.licenses.FindByID(ctx, license.ID)
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			return err
+		}
+		return u.licenses.Save(ctx, license)
+	})
+}
+
+// LicenseRevoked records the revocation of a license by the license service
+// in its status document. A revocation already recorded changes nothing.
+func (u *statusUsecase) LicenseRevoked(ctx context.Context, revoked *lcp.License) error {
+	if revoked.CurrentStatus() != lcpstatus.StatusRevoked {
+		return apperrors.Validation("license %s is %s, not revoked", revoked.ID, revoked.CurrentStatus())
+	}
+	_, err := u.update(ctx, revoked.ID, func(license *lcp.License, events []*lcp.LicenseEvent, _ time.Time) (*lcp.LicenseEvent, error) {
+		if countEvents(events, lcp.LicenseEventRevoke) > 0 {
+			return nil, nil
+		}
+		license.Status = lcpstatus.StatusRevoked
+		license.RevokedAt = revoked.RevokedAt
+		license.RevocationReason = revoked.RevocationReason
+		return &lcp.LicenseEvent{Type: lcp.LicenseEventRevoke}, nil
+	})
+	return err
+}
+
+// update applies change to a license as one unit of work. The event returned
+// by change, if any, is recorded and the license saved with it; a nil event
+// leaves everything untouched. Changed dates are queued for the license
+// service within the unit of work, and delivered and announced once
+// committed.
+func (u *statusUsecase) update(ctx context.Context, licenseID string, change func(license *lcp.License, events []*lcp.LicenseEvent, now time.Time) (*lcp.LicenseEvent, error)) (*lcp.LicenseStatus, error) {
+	var result *lcp.LicenseStatus
+	changed, dated := false, false
+	err := u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		license, err := u.licenses.FindByID(ctx, licenseID)
+		if err != nil {
+			return err
+		}
+		events, err := u.events.FindByLicense(ctx, licenseID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		previous, updated := license.CurrentStatus(), license.UpdatedAt
+		event, err := change(license, events, now)
+		if err != nil {
+			return err
+		}
+		if event != nil {
+			if dated = !license.UpdatedAt.Equal(updated); dated {
+				if err := u.outbox.Queue(ctx, lcp.NotificationLicenseUpdated, license.ID); err != nil {
+					return err
+				}
+			}
+			if changed = license.CurrentStatus() != previous; changed {
+				license.StatusUpdatedAt = now
+			}
+			event.ID = id.New()
+			event.LicenseID = license.ID
+			event.Timestamp = now
+			if err := u.events.Save(ctx, event); err != nil {
+				return err
+			}
+			if err := u.licenses.Save(ctx, license); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		result = &lcp.LicenseStatus{License: license, Status: license.StatusAt(now), Events: events}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dated {
+		u.outbox.Wake()
+	}
+	if changed {
+		u.publisher.Publish(ctx, lcp.Event{Type: lcp.EventLicenseStatusChanged, License: result.License, OccurredAt: time.Now()})
+	}
+	return result, nil
+}
+
+func validateDevice(device lcp.Device) error {
+	if device.ID == "" || device.Name == "" {
+		return apperrors.Validation("device id and name are required")
+	}
+	return nil
+}
+
+// requireUsable rejects device interactions with licenses that no longer
+// grant access.
+func requireUsable(license *lcp.License, now time.Time) error {
+	if !license.ActiveAt(now) {
+		return apperrors.Conflict("license %s is %s", license.ID, license.StatusAt(now))
+	}
+	return nil
+}
+
+func countEvents(events []*lcp.LicenseEvent, eventType lcp.LicenseEventType) int {
+	count := 0
+	for _, event := range events {
+		if event.Type == eventType {
+			count++
+		}
+	}
+	return count
+}
+
+// registeredDevices returns the IDs of the devices registered on a license.
+func registeredDevices(events []*lcp.LicenseEvent) map[string]bool {
+	devices := make(map[string]bool)
+	for _, event := range events {
+		if event.Type == lcp.LicenseEventRegister {
+			devices[event.DeviceID] = true
+		}
+	}
+	return devices
+}
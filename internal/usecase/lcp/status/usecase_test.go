@@ -0,0 +1,331 @@
+package status_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Mehrbod2002/lcp/internal/adapter/repository/lcp"
+	domain "github.com/Mehrbod2002/lcp/internal/domain/lcp"
+	lcpstatus "github.com/Mehrbod2002/lcp/internal/lcp/status"
+	apperrors "github.com/Mehrbod2002/lcp/internal/pkg/errors"
+	"github.com/Mehrbod2002/lcp/internal/usecase/lcp/status"
+)
+
+// recorder is the Outbox and EventPublisher of the use case under test.
+type recorder struct {
+	queued    []string
+	wakes     int
+	published []domain.Event
+}
+
+func (r *recorder) Queue(_ context.Context, kind domain.NotificationKind, licenseID string) error {
+	r.queued = append(r.queued, string(kind)+" "+licenseID)
+	return nil
+}
+
+func (r *recorder) Wake() { r.wakes++ }
+
+func (r *recorder) Publish(_ context.Context, event domain.Event) {
+	r.published = append(r.published, event)
+}
+
+// fixture is a status use case over a volatile store.
+type fixture struct {
+	t        *testing.T
+	ctx      context.Context
+	store    *lcp.Store
+	recorder *recorder
+	statuses status.StatusUsecase
+}
+
+func newFixture(t *testing.T, opts status.Options) *fixture {
+	store := lcp.NewStore()
+	r := &recorder{}
+	return &fixture{
+		t:        t,
+		ctx:      context.Background(),
+		store:    store,
+		recorder: r,
+		statuses: status.NewStatusUsecase(store.Licenses, store.LicenseEvents, store, r, r, opts),
+	}
+}
+
+// save stores a license.
+func (f *fixture) save(license *domain.License) {
+	f.t.Helper()
+	if license.CreatedAt.IsZero() {
+		license.CreatedAt = time.Now().Add(-time.Hour)
+	}
+	if err := f.store.Licenses.Save(f.ctx, license); err != nil {
+		f.t.Fatalf("Save license %s: %v", license.ID, err)
+	}
+}
+
+// stored returns the license and events kept in the store.
+func (f *fixture) stored(licenseID string) (*domain.License, []*domain.LicenseEvent) {
+	f.t.Helper()
+	license, err := f.store.Licenses.FindByID(f.ctx, licenseID)
+	if err != nil {
+		f.t.Fatalf("FindByID %s: %v", licenseID, err)
+	}
+	events, err := f.store.LicenseEvents.FindByLicense(f.ctx, licenseID)
+	if err != nil {
+		f.t.Fatalf("FindByLicense %s: %v", licenseID, err)
+	}
+	return license, events
+}
+
+// expectKind checks err is of kind.
+func expectKind(t *testing.T, err error, kind apperrors.Kind) {
+	t.Helper()
+	if err == nil || apperrors.KindOf(err) != kind {
+		t.Fatalf("error = %v, want %s", err, kind)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+var (
+	reader = domain.Device{ID: "d1", Name: "Reader"}
+	phone  = domain.Device{ID: "d2", Name: "Phone"}
+	tablet = domain.Device{ID: "d3", Name: "Tablet"}
+)
+
+func TestRegister(t *testing.T) {
+	f := newFixture(t, status.Options{MaxDevices: 2})
+	f.save(&domain.License{ID: "l1"})
+
+	// The first device activates the license.
+	result, err := f.statuses.Register(f.ctx, "l1", reader)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if result.Status != lcpstatus.StatusActive || len(result.Events) != 1 {
+		t.Fatalf("Register = %s with %d events, want active with one", result.Status, len(result.Events))
+	}
+	license, events := f.stored("l1")
+	if license.Status != lcpstatus.StatusActive || license.StatusUpdatedAt.IsZero() {
+		t.Fatalf("stored license is %s, updated at %v, want a recorded activation", license.Status, license.StatusUpdatedAt)
+	}
+	if len(events) != 1 || events[0].Type != domain.LicenseEventRegister || events[0].DeviceID != reader.ID || events[0].DeviceName != reader.Name {
+		t.Fatalf("stored events = %+v, want the registration of %s", events, reader.ID)
+	}
+	if len(f.recorder.published) != 1 || f.recorder.published[0].Type != domain.EventLicenseStatusChanged {
+		t.Fatalf("published %+v, want the status change", f.recorder.published)
+	}
+	// Registrations change no date, so the license service is not told.
+	if len(f.recorder.queued) != 0 || f.recorder.wakes != 0 {
+		t.Fatalf("queued %v, want nothing", f.recorder.queued)
+	}
+
+	// Registering the same device again changes nothing.
+	if _, err := f.statuses.Register(f.ctx, "l1", reader); err != nil {
+		t.Fatalf("Register again: %v", err)
+	}
+	if _, events := f.stored("l1"); len(events) != 1 {
+		t.Fatalf("%d events after registering the same device, want 1", len(events))
+	}
+
+	// A second device is recorded without changing the status.
+	if _, err := f.statuses.Register(f.ctx, "l1", phone); err != nil {
+		t.Fatalf("Register second device: %v", err)
+	}
+	if _, events := f.stored("l1"); len(events) != 2 || len(f.recorder.published) != 1 {
+		t.Fatalf("%d events and %d published after a second device, want 2 and 1", len(events), len(f.recorder.published))
+	}
+
+	// A third device exceeds MaxDevices, while known ones still register.
+	_, err = f.statuses.Register(f.ctx, "l1", tablet)
+	expectKind(t, err, apperrors.KindConflict)
+	if _, err := f.statuses.Register(f.ctx, "l1", phone); err != nil {
+		t.Fatalf("Register a known device at the limit: %v", err)
+	}
+	if _, events := f.stored("l1"); len(events) != 2 {
+		t.Fatalf("%d events after the refused device, want 2", len(events))
+	}
+}
+
+func TestRegisterRefusals(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	f.save(&domain.License{ID: "revoked", Status: lcpstatus.StatusRevoked})
+	f.save(&domain.License{ID: "ended", EndDate: timePtr(time.Now().Add(-time.Minute))})
+	f.save(&domain.License{ID: "ready"})
+
+	_, err := f.statuses.Register(f.ctx, "ready", domain.Device{ID: "d1"})
+	expectKind(t, err, apperrors.KindValidation)
+	_, err = f.statuses.Register(f.ctx, "missing", reader)
+	expectKind(t, err, apperrors.KindNotFound)
+	for _, id := range []string{"revoked", "ended"} {
+		_, err := f.statuses.Register(f.ctx, id, reader)
+		expectKind(t, err, apperrors.KindConflict)
+		if _, events := f.stored(id); len(events) != 0 {
+			t.Fatalf("license %s recorded %d events, want none", id, len(events))
+		}
+	}
+}
+
+func TestReturn(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	end := time.Now().Add(7 * 24 * time.Hour)
+	f.save(&domain.License{ID: "l1", EndDate: timePtr(end)})
+	if _, err := f.statuses.Register(f.ctx, "l1", reader); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	f.recorder.published = nil
+
+	before := time.Now()
+	result, err := f.statuses.Return(f.ctx, "l1", reader)
+	if err != nil {
+		t.Fatalf("Return: %v", err)
+	}
+	if result.Status != lcpstatus.StatusReturned {
+		t.Fatalf("Return = %s, want returned", result.Status)
+	}
+
+	// The loan ends now, and the license service learns the new end date.
+	license, events := f.stored("l1")
+	if license.Status != lcpstatus.StatusReturned || license.EndDate == nil || license.EndDate.Before(before) || !license.EndDate.Before(end) {
+		t.Fatalf("stored license is %s ending %v, want returned and ending now", license.Status, license.EndDate)
+	}
+	if !license.UpdatedAt.Equal(*license.EndDate) || !license.StatusUpdatedAt.Equal(*license.EndDate) {
+		t.Fatalf("stored license updated at %v, status at %v, want the return time %v", license.UpdatedAt, license.StatusUpdatedAt, license.EndDate)
+	}
+	if len(events) != 2 || events[1].Type != domain.LicenseEventReturn || events[1].DeviceID != reader.ID {
+		t.Fatalf("stored events = %+v, want the registration and the return", events)
+	}
+	if want := string(domain.NotificationLicenseUpdated) + " l1"; len(f.recorder.queued) != 1 || f.recorder.queued[0] != want || f.recorder.wakes != 1 {
+		t.Fatalf("queued %v with %d wakes, want %q and one wake", f.recorder.queued, f.recorder.wakes, want)
+	}
+	if len(f.recorder.published) != 1 || f.recorder.published[0].License.Status != lcpstatus.StatusReturned {
+		t.Fatalf("published %+v, want the return", f.recorder.published)
+	}
+
+	// A returned license cannot be returned again.
+	_, err = f.statuses.Return(f.ctx, "l1", reader)
+	expectKind(t, err, apperrors.KindConflict)
+}
+
+func TestReturnRequiresActiveLicense(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	f.save(&domain.License{ID: "ready"})
+	f.save(&domain.License{ID: "revoked", Status: lcpstatus.StatusRevoked})
+	f.save(&domain.License{ID: "cancelled", Status: lcpstatus.StatusCancelled})
+	f.save(&domain.License{ID: "ended", Status: lcpstatus.StatusActive, EndDate: timePtr(time.Now().Add(-time.Minute))})
+
+	for _, id := range []string{"ready", "revoked", "cancelled", "ended"} {
+		before, _ := f.stored(id)
+		_, err := f.statuses.Return(f.ctx, id, reader)
+		expectKind(t, err, apperrors.KindConflict)
+		license, events := f.stored(id)
+		if license.Status != before.Status || !equalTimes(license.EndDate, before.EndDate) || len(events) != 0 {
+			t.Fatalf("refused return changed license %s to %s ending %v with %d events", id, license.Status, license.EndDate, len(events))
+		}
+	}
+	if len(f.recorder.queued) != 0 || len(f.recorder.published) != 0 {
+		t.Fatalf("refused returns queued %v and published %v", f.recorder.queued, f.recorder.published)
+	}
+	_, err := f.statuses.Return(f.ctx, "missing", reader)
+	expectKind(t, err, apperrors.KindNotFound)
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestRenew(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	day := 24 * time.Hour
+	start := time.Now().Add(-day).Truncate(time.Second)
+	end := start.Add(7 * day)
+	f.save(&domain.License{
+		ID:        "l1",
+		Status:    lcpstatus.StatusActive,
+		StartDate: timePtr(start),
+		EndDate:   timePtr(end),
+		Renewal:   domain.RenewalPolicy{MaxRenewals: 2, MaxLoanLength: 21 * day, DefaultExtension: 7 * day},
+	})
+
+	// Without an end date, the loan is extended by DefaultExtension.
+	result, err := f.statuses.Renew(f.ctx, "l1", reader, nil)
+	if err != nil {
+		t.Fatalf("Renew: %v", err)
+	}
+	if result.Status != lcpstatus.StatusActive {
+		t.Fatalf("Renew = %s, want active", result.Status)
+	}
+	license, events := f.stored("l1")
+	if want := end.Add(7 * day); license.EndDate == nil || !license.EndDate.Equal(want) || license.UpdatedAt.IsZero() {
+		t.Fatalf("stored license ends %v, updated at %v, want %v", license.EndDate, license.UpdatedAt, want)
+	}
+	if len(events) != 1 || events[0].Type != domain.LicenseEventRenew || events[0].DeviceID != reader.ID {
+		t.Fatalf("stored events = %+v, want the renewal", events)
+	}
+	if want := string(domain.NotificationLicenseUpdated) + " l1"; len(f.recorder.queued) != 1 || f.recorder.queued[0] != want || f.recorder.wakes != 1 {
+		t.Fatalf("queued %v with %d wakes, want %q and one wake", f.recorder.queued, f.recorder.wakes, want)
+	}
+	// The status did not change.
+	if len(f.recorder.published) != 0 {
+		t.Fatalf("published %+v, want nothing", f.recorder.published)
+	}
+
+	// The requested end is capped by MaxLoanLength, through PotentialEnd.
+	potential := start.Add(21 * day)
+	_, err = f.statuses.Renew(f.ctx, "l1", reader, timePtr(potential.Add(time.Second)))
+	expectKind(t, err, apperrors.KindConflict)
+	if _, err := f.statuses.Renew(f.ctx, "l1", reader, timePtr(potential)); err != nil {
+		t.Fatalf("Renew to the potential end: %v", err)
+	}
+	if license, _ := f.stored("l1"); !license.EndDate.Equal(potential) {
+		t.Fatalf("stored license ends %v, want the potential end %v", license.EndDate, potential)
+	}
+
+	// MaxRenewals is reached.
+	_, err = f.statuses.Renew(f.ctx, "l1", reader, nil)
+	expectKind(t, err, apperrors.KindConflict)
+	if _, events := f.stored("l1"); len(events) != 2 {
+		t.Fatalf("%d renewals recorded, want 2", len(events))
+	}
+}
+
+func TestRenewRefusals(t *testing.T) {
+	f := newFixture(t, status.Options{})
+	end := time.Now().Add(24 * time.Hour)
+	f.save(&domain.License{ID: "active", Status: lcpstatus.StatusActive, EndDate: timePtr(end)})
+	f.save(&domain.License{ID: "open", Status: lcpstatus.StatusActive})
+	f.save(&domain.License{ID: "ready", EndDate: timePtr(end)})
+	f.save(&domain.License{ID: "returned", Status: lcpstatus.StatusReturned, EndDate: timePtr(end)})
+	f.save(&domain.License{ID: "ended", Status: lcpstatus.StatusActive, EndDate: timePtr(time.Now().Add(-time.Minute))})
+
+	tests := []struct {
+		name      string
+		licenseID string
+		end       *time.Time
+		kind      apperrors.Kind
+	}{
+		{"end in the past", "active", timePtr(time.Now().Add(-time.Hour)), apperrors.KindValidation},
+		{"end before the current one", "active", timePtr(end.Add(-time.Minute)), apperrors.KindValidation},
+		{"current end", "active", timePtr(end), apperrors.KindValidation},
+		{"no end without a default extension", "active", nil, apperrors.KindValidation},
+		{"no end date to renew", "open", timePtr(end), apperrors.KindConflict},
+		{"ready", "ready", timePtr(end.Add(time.Hour)), apperrors.KindConflict},
+		{"returned", "returned", timePtr(end.Add(time.Hour)), apperrors.KindConflict},
+		{"expired", "ended", timePtr(end.Add(time.Hour)), apperrors.KindConflict},
+		{"missing", "missing", timePtr(end.Add(time.Hour)), apperrors.KindNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.statuses.Renew(f.ctx, tt.licenseID, reader, tt.end)
+			expectKind(t, err, tt.kind)
+		})
+	}
+	license, events := f.stored("active")
+	if !license.EndDate.Equal(end) || len(events) != 0 || len(f.recorder.queued) != 0 {
+		t.Fatalf("refused renewals left the license ending %v with %d events and queued %v", license.EndDate, len(events), f.recorder.queued)
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE publications ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active';
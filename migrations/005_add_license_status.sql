@@ -0,0 +1,14 @@
+-- Dates left NULL map to the zero time: the license was never updated, its
+-- status never changed, or it was never revoked.
+ALTER TABLE licenses ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE licenses ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE licenses ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'ready';
+ALTER TABLE licenses ADD COLUMN status_updated_at TIMESTAMP;
+ALTER TABLE licenses ADD COLUMN revoked_at TIMESTAMP;
+ALTER TABLE licenses ADD COLUMN revocation_reason TEXT NOT NULL DEFAULT '';
+-- The renewal policy the license was issued under, as the JSON encoding of
+-- lcp.RenewalPolicy.
+ALTER TABLE licenses ADD COLUMN renewal TEXT NOT NULL DEFAULT '{}';
+
+CREATE INDEX licenses_publication_id ON licenses (publication_id);
+CREATE INDEX licenses_user_id ON licenses (user_id);
@@ -0,0 +1,11 @@
+CREATE TABLE license_events (
+    id VARCHAR(36) PRIMARY KEY,
+    license_id VARCHAR(36) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    device_id TEXT NOT NULL DEFAULT '',
+    device_name TEXT NOT NULL DEFAULT '',
+    "timestamp" TIMESTAMP NOT NULL,
+    FOREIGN KEY (license_id) REFERENCES licenses(id)
+);
+
+CREATE INDEX license_events_license_id ON license_events (license_id);
@@ -0,0 +1,3 @@
+-- The expiry sweeper looks licenses up by end date, among those that still
+-- grant access.
+CREATE INDEX licenses_status_end_date ON licenses (status, end_date);
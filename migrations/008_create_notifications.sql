@@ -0,0 +1,13 @@
+-- The outbox of notifications waiting for the other service, in the order
+-- they were queued.
+CREATE TABLE notifications (
+    id VARCHAR(36) PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    license_id VARCHAR(36) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX notifications_created_at ON notifications (created_at);